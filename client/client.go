@@ -0,0 +1,278 @@
+// Package client is a typed client of the boltkit api.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// apiVersion is the path prefix of the api version the client calls.
+	apiVersion = "/v1"
+
+	// DefaultRetries is the number of times an idempotent call is retried.
+	DefaultRetries = 3
+	// DefaultBackoff is the delay ahead of the first retry, doubled for
+	// every retry after.
+	DefaultBackoff = 200 * time.Millisecond
+)
+
+// Client calls the api of a boltkit service. A client signed in with
+// CreateSession attaches its session token to every call and signs in again
+// when the session expires.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Retries    int
+	Backoff    time.Duration
+
+	mtx      sync.RWMutex
+	token    string
+	email    string
+	password string
+}
+
+// New creates a client of the service served at the provided base url.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Retries:    DefaultRetries,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// Token returns the session token of the client.
+func (client *Client) Token() string {
+	client.mtx.RLock()
+	defer client.mtx.RUnlock()
+	return client.token
+}
+
+// SetToken sets the session token of the client. A client given a token
+// rather than signed in cannot refresh its session.
+func (client *Client) SetToken(token string) {
+	client.mtx.Lock()
+	client.token = token
+	client.mtx.Unlock()
+}
+
+// Error is an error response of the api, described by its problem details.
+type Error struct {
+	Status  int
+	Code    string
+	Detail  string
+	Details map[string]interface{}
+	Fields  []FieldError
+}
+
+// Errors of the api, matched by code with errors.Is.
+var (
+	ErrUnauthorized         = &Error{Status: http.StatusUnauthorized, Code: "unauthorized"}
+	ErrExpiredSession       = &Error{Status: http.StatusUnauthorized, Code: "expired_session"}
+	ErrMissingAuthorization = &Error{Status: http.StatusUnauthorized, Code: "missing_authorization"}
+	ErrForbidden            = &Error{Status: http.StatusForbidden, Code: "forbidden"}
+	ErrNotFound             = &Error{Status: http.StatusNotFound, Code: "not_found"}
+)
+
+// Error returns the error detail.
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s)", err.Detail, err.Status, err.Code)
+}
+
+// Is matches errors by code, so errors.Is(err, ErrForbidden) holds for a
+// forbidden response.
+func (err *Error) Is(target error) bool {
+	typed, ok := target.(*Error)
+	return ok && typed.Code == err.Code
+}
+
+// HasCode asserts an error is an api error with the provided code.
+func HasCode(err error, code string) bool {
+	typed, ok := err.(*Error)
+	return ok && typed.Code == code
+}
+
+// call describes a single api call.
+type call struct {
+	method     string
+	path       string
+	payload    interface{}
+	result     interface{}
+	idempotent bool
+	public     bool
+	key        string
+}
+
+// do makes an api call, decoding the response into the result of the call.
+// A call rejected for an expired session is made again after signing in.
+func (client *Client) do(ctx context.Context, c call) error {
+	err := client.retry(ctx, c)
+	if c.public || !isSessionError(err) {
+		return err
+	}
+
+	client.mtx.RLock()
+	email, password := client.email, client.password
+	client.mtx.RUnlock()
+	if email == "" {
+		return err
+	}
+
+	_, err = client.CreateSession(ctx, email, password)
+	if err != nil {
+		return err
+	}
+
+	return client.retry(ctx, c)
+}
+
+// retry makes an api call, retrying idempotent calls that fail with a
+// transient error with exponential backoff. Creates are retried with an
+// idempotency key so a retry does not create twice.
+func (client *Client) retry(ctx context.Context, c call) error {
+	var body []byte
+	if c.payload != nil {
+		var err error
+		body, err = json.Marshal(c.payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Creates are made idempotent by a key identifying their retries.
+	if c.method == http.MethodPost && !c.idempotent {
+		c.key = ksuid.New().String()
+		c.idempotent = true
+	}
+
+	backoff := client.Backoff
+	for attempt := 0; ; attempt++ {
+		err := client.send(ctx, c, body)
+		if err == nil || !c.idempotent || attempt >= client.Retries || !isTransient(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// send makes a single attempt at an api call.
+func (client *Client) send(ctx context.Context, c call, body []byte) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(c.method, client.BaseURL+apiVersion+c.path, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.key != "" {
+		req.Header.Set("Idempotency-Key", c.key)
+	}
+
+	token := client.Token()
+	if token != "" && !c.public {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp.StatusCode, respBody)
+	}
+
+	if c.result == nil || len(respBody) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(respBody, c.result)
+}
+
+// responseError reads the problem details of an error response.
+func responseError(status int, body []byte) error {
+	described := problem{}
+	err := json.Unmarshal(body, &described)
+	if err != nil || described.Code == "" {
+		return &Error{
+			Status: status,
+			Code:   "unexpected_response",
+			Detail: http.StatusText(status),
+		}
+	}
+
+	return &Error{
+		Status:  status,
+		Code:    described.Code,
+		Detail:  described.Detail,
+		Details: described.Details,
+		Fields:  described.Fields,
+	}
+}
+
+// isSessionError asserts an error rejects the session a call was made with.
+func isSessionError(err error) bool {
+	typed, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+
+	switch typed.Code {
+	case ErrExpiredSession.Code, ErrUnauthorized.Code, ErrMissingAuthorization.Code:
+		return true
+	}
+	return false
+}
+
+// isTransient asserts a failed call may succeed when made again.
+func isTransient(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	typed, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+
+	switch typed.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetInvite fetches the invite associated with the provided id.
+func (client *Client) GetInvite(ctx context.Context, id string) (*Invite, error) {
+	invite := new(Invite)
+	err := client.do(ctx, call{
+		method:     http.MethodGet,
+		path:       "/invites/" + url.PathEscape(id),
+		result:     invite,
+		idempotent: true,
+	})
+	return invite, err
+}
+
+// CreateInvite invites the provided email to sign up with the provided role.
+func (client *Client) CreateInvite(ctx context.Context, email string, role string, invitedBy string) (*Invite, error) {
+	payload := map[string]interface{}{
+		"email":     email,
+		"role":      role,
+		"invitedBy": invitedBy,
+	}
+
+	invite := new(Invite)
+	err := client.do(ctx, call{
+		method:  http.MethodPost,
+		path:    "/invites",
+		payload: payload,
+		result:  invite,
+	})
+	return invite, err
+}
+
+// UpdateInviteStatus updates the status of the invite associated with the
+// provided id.
+func (client *Client) UpdateInviteStatus(ctx context.Context, id string, status string) (*Invite, error) {
+	invite := new(Invite)
+	err := client.do(ctx, call{
+		method:     http.MethodPut,
+		path:       "/invites/" + url.PathEscape(id),
+		payload:    map[string]interface{}{"status": status},
+		result:     invite,
+		idempotent: true,
+	})
+	return invite, err
+}
+
+// DeleteInvite soft deletes, or restores, the invite associated with the
+// provided id.
+func (client *Client) DeleteInvite(ctx context.Context, id string, deleted bool) error {
+	return client.do(ctx, call{
+		method:     http.MethodDelete,
+		path:       "/invites/" + url.PathEscape(id),
+		payload:    map[string]interface{}{"deleted": deleted},
+		idempotent: true,
+	})
+}
+
+// ListInvites fetches the page of invites matching the provided term at the
+// provided cursor.
+func (client *Client) ListInvites(ctx context.Context, term string, cursor uint32) ([]Invite, Page, error) {
+	response := struct {
+		Meta    Page     `json:"meta"`
+		Results []Invite `json:"results"`
+	}{}
+	err := client.do(ctx, call{
+		method:     http.MethodPost,
+		path:       "/invites/list",
+		payload:    listPayload{term, cursor},
+		result:     &response,
+		idempotent: true,
+	})
+	return response.Results, response.Meta, err
+}
@@ -0,0 +1,24 @@
+package client
+
+// listPayload is the payload of a paginated list query.
+type listPayload struct {
+	Term   string `json:"term"`
+	Offset uint32 `json:"offset"`
+}
+
+// Page describes a page of a list response. Pages are addressed by a cursor,
+// the offset of the page.
+type Page struct {
+	Count    int    `json:"count"`
+	Offset   uint32 `json:"offset"`
+	PageSize uint32 `json:"pagesize"`
+}
+
+// Next returns the cursor of the following page, false when the page is
+// the last.
+func (page Page) Next() (uint32, bool) {
+	if page.PageSize == 0 || uint32(page.Count) < page.PageSize {
+		return 0, false
+	}
+	return page.Offset + 1, true
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateSession signs in, the session token is attached to every call made
+// after. The credentials are kept to sign in again once the session expires.
+func (client *Client) CreateSession(ctx context.Context, email string, password string) (*Session, error) {
+	payload := map[string]interface{}{
+		"email":    email,
+		"password": password,
+	}
+
+	session := new(Session)
+	err := client.do(ctx, call{
+		method:  http.MethodPost,
+		path:    "/sessions",
+		payload: payload,
+		result:  session,
+		public:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client.mtx.Lock()
+	client.token = session.Token
+	client.email = email
+	client.password = password
+	client.mtx.Unlock()
+	return session, nil
+}
+
+// GetSession fetches the session associated with the provided token.
+func (client *Client) GetSession(ctx context.Context, token string) (*Session, error) {
+	session := new(Session)
+	err := client.do(ctx, call{
+		method:     http.MethodGet,
+		path:       "/sessions/" + url.PathEscape(token),
+		result:     session,
+		idempotent: true,
+		public:     true,
+	})
+	return session, err
+}
@@ -0,0 +1,56 @@
+package client
+
+// Session is a signed in session, its token authorizes calls.
+type Session struct {
+	User      string `json:"user"`
+	Token     string `json:"token"`
+	Access    string `json:"access"`
+	CreatedOn int64  `json:"createdOn"`
+	Expiry    int64  `json:"expiry"`
+}
+
+// User is a user of the service.
+type User struct {
+	Uuid         string `json:"uuid"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	LastLogin    int64  `json:"lastLogin"`
+	LastModified int64  `json:"lastModified"`
+	ModifiedBy   string `json:"modifiedBy"`
+	CreatedOn    int64  `json:"createdOn"`
+	Deleted      bool   `json:"deleted"`
+	DeletedOn    int64  `json:"deletedOn"`
+	Invite       string `json:"invite"`
+}
+
+// Invite is an invitation to sign up with a role.
+type Invite struct {
+	Uuid         string `json:"uuid"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	Status       string `json:"status"`
+	LastModified int64  `json:"lastModified"`
+	ModifiedBy   string `json:"modifiedBy"`
+	CreatedOn    int64  `json:"createdOn"`
+	Expiry       int64  `json:"expiry"`
+	InvitedBy    string `json:"invitedBy"`
+	Deleted      bool   `json:"deleted"`
+	DeletedOn    int64  `json:"deletedOn"`
+}
+
+// FieldError describes an invalid field of a rejected payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// problem is the body of an error response, RFC 7807 problem details.
+type problem struct {
+	Status  int                    `json:"status"`
+	Detail  string                 `json:"detail"`
+	Code    string                 `json:"code"`
+	Details map[string]interface{} `json:"details"`
+	Fields  []FieldError           `json:"fields"`
+}
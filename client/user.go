@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateUserRequest is the payload of a user sign up from an invite.
+type CreateUserRequest struct {
+	Invite    string `json:"invite"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Password  string `json:"password"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+}
+
+// GetUser fetches the user associated with the provided id.
+func (client *Client) GetUser(ctx context.Context, id string) (*User, error) {
+	user := new(User)
+	err := client.do(ctx, call{
+		method:     http.MethodGet,
+		path:       "/users/" + url.PathEscape(id),
+		result:     user,
+		idempotent: true,
+	})
+	return user, err
+}
+
+// CreateUser signs up a user with the invite they received.
+func (client *Client) CreateUser(ctx context.Context, payload CreateUserRequest) (*User, error) {
+	user := new(User)
+	err := client.do(ctx, call{
+		method:  http.MethodPost,
+		path:    "/users",
+		payload: payload,
+		result:  user,
+		public:  true,
+	})
+	return user, err
+}
+
+// UpdateUserRole updates the role of the user associated with the provided id.
+func (client *Client) UpdateUserRole(ctx context.Context, id string, role string) (*User, error) {
+	user := new(User)
+	err := client.do(ctx, call{
+		method:     http.MethodPut,
+		path:       "/users/" + url.PathEscape(id) + "/role",
+		payload:    map[string]interface{}{"role": role},
+		result:     user,
+		idempotent: true,
+	})
+	return user, err
+}
+
+// DeleteUser soft deletes, or restores, the user associated with the
+// provided id.
+func (client *Client) DeleteUser(ctx context.Context, id string, deleted bool) error {
+	return client.do(ctx, call{
+		method:     http.MethodDelete,
+		path:       "/users/" + url.PathEscape(id),
+		payload:    map[string]interface{}{"deleted": deleted},
+		idempotent: true,
+	})
+}
+
+// ListUsers fetches the page of users matching the provided term at the
+// provided cursor.
+func (client *Client) ListUsers(ctx context.Context, term string, cursor uint32) ([]User, Page, error) {
+	response := struct {
+		Meta    Page   `json:"meta"`
+		Results []User `json:"results"`
+	}{}
+	err := client.do(ctx, call{
+		method:     http.MethodPost,
+		path:       "/users/list",
+		payload:    listPayload{term, cursor},
+		result:     &response,
+		idempotent: true,
+	})
+	return response.Results, response.Meta, err
+}
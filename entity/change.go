@@ -0,0 +1,261 @@
+package entity
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"einheit/boltkit/util"
+)
+
+// Change operations.
+var (
+	PutOperation    = "put"
+	DeleteOperation = "delete"
+)
+
+var (
+	changeMtx    sync.Mutex
+	changeSignal = make(chan struct{})
+)
+
+// Change describes a single committed write to an entity. Changes are
+// sequenced in the order they were committed, the data held is the state
+// of the entity after the write and is empty for deletes.
+type Change struct {
+	Sequence  uint64          `json:"sequence"`
+	Entity    string          `json:"entity"`
+	EntityId  string          `json:"entityId"`
+	Operation string          `json:"operation"`
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// putChange appends a change to the change feed. It is expected to be called
+// in the same transaction as the write it records so the change is only
+// visible if the write commits.
+func putChange(tx *bolt.Tx, entityBucket []byte, id []byte, operation string, data []byte) error {
+	bucket := tx.Bucket(util.ChangeBucket)
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	change := Change{
+		Sequence:  seq,
+		Entity:    string(entityBucket),
+		EntityId:  string(id),
+		Operation: operation,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	}
+
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		log.Error(util.ErrStorage)
+		return util.ErrStorage
+	}
+
+	tx.OnCommit(notifyChange)
+	return bucket.Put(util.EncodeSequence(seq), changeBytes)
+}
+
+// notifyChange wakes all consumers waiting on the change feed.
+func notifyChange() {
+	changeMtx.Lock()
+	close(changeSignal)
+	changeSignal = make(chan struct{})
+	changeMtx.Unlock()
+}
+
+// ChangeSignal returns a channel that is closed when the next change is
+// committed.
+func ChangeSignal() <-chan struct{} {
+	changeMtx.Lock()
+	defer changeMtx.Unlock()
+	return changeSignal
+}
+
+// ListChanges returns up to limit changes committed after the provided
+// sequence number, oldest first. Resuming from before the compacted changes
+// fails, the changes in between are no longer kept.
+func ListChanges(db Store, after uint64, limit uint32) (*[]Change, error) {
+	changeList := []Change{}
+	err := db.View(func(tx *bolt.Tx) error {
+		if after < compactedSequence(tx) {
+			return util.ErrChangesCompacted
+		}
+
+		bucket := tx.Bucket(util.ChangeBucket)
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(util.EncodeSequence(after + 1)); k != nil; k, v = cursor.Next() {
+			change := Change{}
+			err := json.Unmarshal(v, &change)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			changeList = append(changeList, change)
+			// Stop iterating when data target has been met.
+			if uint32(len(changeList)) == limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	return &changeList, err
+}
+
+// HeadSequence returns the sequence number of the latest committed change.
+func HeadSequence(db Store) (uint64, error) {
+	var seq uint64
+	err := db.View(func(tx *bolt.Tx) error {
+		seq = tx.Bucket(util.ChangeBucket).Sequence()
+		return nil
+	})
+	return seq, err
+}
+
+// CompactedSequence returns the sequence number of the last change removed
+// from the change feed by CompactChanges, 0 when none has been removed.
+func CompactedSequence(db Store) (uint64, error) {
+	var seq uint64
+	err := db.View(func(tx *bolt.Tx) error {
+		seq = compactedSequence(tx)
+		return nil
+	})
+	return seq, err
+}
+
+// compactedSequence reads the sequence number of the last compacted change.
+func compactedSequence(tx *bolt.Tx) uint64 {
+	v := tx.Bucket(util.CacheBucket).Get(util.CompactedKey)
+	if v == nil {
+		return 0
+	}
+	return util.DecodeSequence(v)
+}
+
+// CompactChanges removes the changes committed before the provided unix time
+// from the change feed and records the sequence of the last one removed.
+// Changes are sequenced in commit order so removal stops at the first change
+// that is kept.
+func CompactChanges(db Store, before int64) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.ChangeBucket)
+		cursor := bucket.Cursor()
+		expired := [][]byte{}
+		change := Change{}
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			err := json.Unmarshal(v, &change)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			if change.Timestamp >= before {
+				break
+			}
+			expired = append(expired, append([]byte{}, k...))
+		}
+
+		if len(expired) == 0 {
+			return nil
+		}
+
+		// Delete after iterating, bolt does not support deleting keys
+		// while iterating with a cursor.
+		for _, key := range expired {
+			err := bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(util.CacheBucket).Put(util.CompactedKey, expired[len(expired)-1])
+	})
+	return err
+}
+
+// ApplyChanges replays changes committed elsewhere, as done by a replica
+// following a primary. The changes are written to the change feed under
+// their original sequence numbers and the last applied sequence is recorded,
+// all in a single transaction.
+func ApplyChanges(db Store, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		changeBucket := tx.Bucket(util.ChangeBucket)
+		for _, change := range changes {
+			_, err := tx.CreateBucketIfNotExists([]byte(change.Entity))
+			if err != nil {
+				return err
+			}
+
+			switch change.Operation {
+			case PutOperation:
+				err = putEntity(tx, []byte(change.Entity), []byte(change.EntityId), change.Data)
+			case DeleteOperation:
+				err = deleteEntity(tx, []byte(change.Entity), []byte(change.EntityId))
+			default:
+				err = util.ErrInvalidParameterOption("operation", change.Operation,
+					[]string{PutOperation, DeleteOperation})
+			}
+			if err != nil {
+				return err
+			}
+
+			changeBytes, err := json.Marshal(change)
+			if err != nil {
+				logger(db).Error(util.ErrStorage)
+				return util.ErrStorage
+			}
+
+			err = changeBucket.Put(util.EncodeSequence(change.Sequence), changeBytes)
+			if err != nil {
+				return err
+			}
+
+			err = changeBucket.SetSequence(change.Sequence)
+			if err != nil {
+				return err
+			}
+		}
+
+		last := changes[len(changes)-1].Sequence
+		err := tx.Bucket(util.CacheBucket).Put(util.ReplicationKey, util.EncodeSequence(last))
+		if err != nil {
+			return err
+		}
+
+		tx.OnCommit(notifyChange)
+		return nil
+	})
+	return err
+}
+
+// AppliedSequence returns the sequence number of the last change applied by
+// a replica.
+func AppliedSequence(db Store) (uint64, error) {
+	var seq uint64
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(util.CacheBucket).Get(util.ReplicationKey)
+		if v == nil {
+			return util.ErrKeyNotFound(string(util.ReplicationKey))
+		}
+
+		seq = util.DecodeSequence(v)
+		return nil
+	})
+	return seq, err
+}
+
+// Sanitize prepares the change to be sent a request response.
+// This removes all sensitive details from the recorded entity state.
+func (change *Change) Sanitize() {
+	change.Data = sanitizeState(change.Data)
+}
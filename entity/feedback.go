@@ -4,7 +4,6 @@ import (
 	"einheit/boltkit/util"
 	"encoding/json"
 	"reflect"
-	"strings"
 
 	"github.com/boltdb/bolt"
 )
@@ -24,7 +23,7 @@ type Feedback struct {
 }
 
 // GetFeedback fetches the feedback associated with the provided id.
-func GetFeedback(id []byte, db *bolt.DB) (*Feedback, error) {
+func GetFeedback(id []byte, db Store) (*Feedback, error) {
 	feedback := new(Feedback)
 	err := db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.FeedbackBucket)
@@ -34,81 +33,61 @@ func GetFeedback(id []byte, db *bolt.DB) (*Feedback, error) {
 		}
 
 		err := json.Unmarshal(v, feedback)
-		return err
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
 	})
 	return feedback, err
 }
 
 // Update stores the most updated state of the feedback entity.
-func (feedback *Feedback) Update(db *bolt.DB) error {
+func (feedback *Feedback) Update(db Store) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.FeedbackBucket)
 		feedbackBytes, err := json.Marshal(feedback)
 		if err != nil {
-			log.Error(util.ErrMalformedJSON)
-			return util.ErrMalformedJSON
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		err = putEntity(tx, util.FeedbackBucket, []byte(feedback.Uuid), feedbackBytes)
+		if err != nil {
+			return err
 		}
 
-		err = bucket.Put([]byte(feedback.Uuid), feedbackBytes)
+		err = putChange(tx, util.FeedbackBucket, []byte(feedback.Uuid), PutOperation, feedbackBytes)
 		return err
 	})
 	return err
 }
 
 // Delete not applicable for feedback.
-func (feedback *Feedback) Delete(state bool, db *bolt.DB) error {
+func (feedback *Feedback) Delete(state bool, db Store) error {
 	return util.ErrNotApplicable(reflect.TypeOf(feedback).Name())
 }
 
-// ListFeedback returns a set of feedback that match the query criteria.
-func ListFeedback(db *bolt.DB, pageLimit uint32, term string, offset uint32) (*[]Feedback, error) {
-	var target uint32
-	feedbackList := []Feedback{}
-	currFeedback := new(Feedback)
-	err := db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.FeedbackBucket)
-		cursor := bucket.Cursor()
-		// Adjust data size targets based on offset and page limit
-		if offset > 0 {
-			target = pageLimit * (offset + 1)
-		}
-
-		if offset == 0 {
-			target = pageLimit
-		}
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			err := json.Unmarshal(v, currFeedback)
-			if err != nil {
-				return util.ErrMalformedJSON
-			}
-
-			if term != "" {
-				if strings.Contains(strings.ToLower(currFeedback.User), strings.ToLower(term)) {
-					feedbackList = append(feedbackList, *currFeedback)
-					// Stop iterating when data target has been met.
-					if uint32(len(feedbackList)) == target {
-						break
-					}
-				}
-			}
-
-			if term == "" {
-				feedbackList = append(feedbackList, *currFeedback)
-				// Stop iterating when data target has been met.
-				if uint32(len(feedbackList)) == target {
-					break
-				}
-			}
-		}
-
-		// Slice the relevant data according to the page limit and offset.
-		if offset > 0 {
-			feedbackList = feedbackList[(pageLimit * offset):]
-		}
+// FeedbackQuery describes the filters and sort fields of feedback list
+// queries.
+var FeedbackQuery = &util.QuerySchema{
+	Fields: []util.QueryField{
+		{Name: "user", Type: util.StringField, Sortable: true},
+		{Name: "resolved", Type: util.BoolField},
+		{Name: "createdOn", Param: "created", Type: util.TimeField, Sortable: true},
+		{Name: "lastModified", Param: "modified", Type: util.TimeField, Sortable: true},
+	},
+	Search: []string{"user"},
+}
 
-		return nil
+// QueryFeedback returns the page of feedback matching the query.
+func QueryFeedback(db Store, pageLimit uint32, query *util.Query) (*[]Feedback, error) {
+	feedbackList := []Feedback{}
+	err := queryBucket(db, util.FeedbackBucket, pageLimit, query, &feedbackList, func(entity interface{}) bool {
+		return true
 	})
-
 	return &feedbackList, err
 }
+
+// ListFeedback returns a set of feedback that match the query criteria.
+func ListFeedback(db Store, pageLimit uint32, term string, offset uint32) (*[]Feedback, error) {
+	return QueryFeedback(db, pageLimit, FeedbackQuery.NewQuery(term, offset))
+}
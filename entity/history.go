@@ -0,0 +1,208 @@
+package entity
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"einheit/boltkit/util"
+)
+
+// Revision describes a single write made to an entity. It holds the state of
+// the entity before the write, a nil previous state indicates the write
+// created the entity.
+type Revision struct {
+	Id        uint64          `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityId  string          `json:"entityId"`
+	Actor     string          `json:"actor"`
+	Timestamp int64           `json:"timestamp"`
+	Previous  json.RawMessage `json:"previous"`
+}
+
+// putRevision appends a revision to the history of the entity associated with
+// the provided id. It is expected to be called in the same transaction as the
+// write it records, before the write is made.
+func putRevision(tx *bolt.Tx, entityBucket []byte, id []byte, actor string) error {
+	historyBucket := tx.Bucket(util.HistoryBucket)
+	typeBucket, err := historyBucket.CreateBucketIfNotExists(entityBucket)
+	if err != nil {
+		return err
+	}
+
+	idBucket, err := typeBucket.CreateBucketIfNotExists(id)
+	if err != nil {
+		return err
+	}
+
+	seq, err := idBucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	revision := Revision{
+		Id:        seq,
+		Entity:    string(entityBucket),
+		EntityId:  string(id),
+		Actor:     actor,
+		Timestamp: time.Now().Unix(),
+	}
+
+	// Copy the previous state, values returned by bolt are only valid for the
+	// life of the transaction.
+	if v := tx.Bucket(entityBucket).Get(id); v != nil {
+		revision.Previous = append(json.RawMessage{}, v...)
+	}
+
+	revisionBytes, err := json.Marshal(revision)
+	if err != nil {
+		log.Error(util.ErrStorage)
+		return util.ErrStorage
+	}
+
+	return idBucket.Put(util.EncodeSequence(seq), revisionBytes)
+}
+
+// revisionBucket fetches the history bucket of the entity associated with
+// the provided id.
+func revisionBucket(tx *bolt.Tx, entityBucket []byte, id []byte) *bolt.Bucket {
+	typeBucket := tx.Bucket(util.HistoryBucket).Bucket(entityBucket)
+	if typeBucket == nil {
+		return nil
+	}
+
+	return typeBucket.Bucket(id)
+}
+
+// GetRevision fetches the specified revision of the entity associated with
+// the provided id.
+func GetRevision(entityBucket []byte, id []byte, revisionId uint64, db Store) (*Revision, error) {
+	revision := new(Revision)
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := revisionBucket(tx, entityBucket, id)
+		if bucket == nil {
+			return util.ErrKeyNotFound(string(id))
+		}
+
+		v := bucket.Get(util.EncodeSequence(revisionId))
+		if v == nil {
+			return util.ErrKeyNotFound(strconv.FormatUint(revisionId, 10))
+		}
+
+		return json.Unmarshal(v, revision)
+	})
+	return revision, err
+}
+
+// ListRevisions returns the revisions of the entity associated with the
+// provided id, oldest first.
+func ListRevisions(entityBucket []byte, id []byte, db Store) (*[]Revision, error) {
+	revisionList := []Revision{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := revisionBucket(tx, entityBucket, id)
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			revision := Revision{}
+			err := json.Unmarshal(v, &revision)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			revisionList = append(revisionList, revision)
+		}
+
+		return nil
+	})
+	return &revisionList, err
+}
+
+// getStateAt fetches the stored state of the entity associated with the
+// provided id as it was at the provided unix time. The state at a point in
+// time is the previous state recorded by the first revision made after it, or
+// the current state if there has been no write since.
+func getStateAt(entityBucket []byte, id []byte, timestamp int64, db Store) ([]byte, error) {
+	var state []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		current := tx.Bucket(entityBucket).Get(id)
+		bucket := revisionBucket(tx, entityBucket, id)
+		if bucket != nil {
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				revision := Revision{}
+				err := json.Unmarshal(v, &revision)
+				if err != nil {
+					return util.ErrStorage
+				}
+
+				if revision.Timestamp > timestamp {
+					current = revision.Previous
+					break
+				}
+			}
+		}
+
+		if current == nil || string(current) == "null" {
+			return util.ErrKeyNotFound(string(id))
+		}
+
+		state = append([]byte{}, current...)
+		return nil
+	})
+	return state, err
+}
+
+// purge permanently removes the entity associated with the provided id along
+// with its history.
+func purge(tx *bolt.Tx, entityBucket []byte, id []byte) error {
+	err := deleteEntity(tx, entityBucket, id)
+	if err != nil {
+		return err
+	}
+
+	err = putChange(tx, entityBucket, id, DeleteOperation, nil)
+	if err != nil {
+		return err
+	}
+
+	typeBucket := tx.Bucket(util.HistoryBucket).Bucket(entityBucket)
+	if typeBucket == nil || typeBucket.Bucket(id) == nil {
+		return nil
+	}
+
+	return typeBucket.DeleteBucket(id)
+}
+
+// Sanitize prepares the revision to be sent a request response.
+// This removes all sensitive details from the recorded entity state.
+func (revision *Revision) Sanitize() {
+	revision.Previous = sanitizeState(revision.Previous)
+}
+
+// sanitizeState removes all sensitive details from a stored entity state.
+func sanitizeState(state json.RawMessage) json.RawMessage {
+	if len(state) == 0 || string(state) == "null" {
+		return state
+	}
+
+	fields := map[string]interface{}{}
+	err := json.Unmarshal(state, &fields)
+	if err != nil {
+		return state
+	}
+
+	delete(fields, "password")
+	delete(fields, "resetURL")
+	delete(fields, "secret")
+	sanitized, err := json.Marshal(fields)
+	if err != nil {
+		return state
+	}
+
+	return sanitized
+}
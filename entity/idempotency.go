@@ -0,0 +1,112 @@
+package entity
+
+import (
+	"encoding/json"
+
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// IdempotencyRecord holds the response to a request made with an idempotency
+// key, replayed for retries of the request. A record without a response is
+// held by a request still being processed.
+type IdempotencyRecord struct {
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+	CreatedOn   int64  `json:"createdOn"`
+	Expiry      int64  `json:"expiry"`
+}
+
+// ReserveIdempotencyKey stores the record of a request made with an
+// idempotency key unless an unexpired record of the key exists, in which
+// case the existing record is returned.
+func ReserveIdempotencyKey(record *IdempotencyRecord, db Store) (*IdempotencyRecord, error) {
+	var existing *IdempotencyRecord
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.IdempotencyBucket)
+		v := bucket.Get([]byte(record.Key))
+		if v != nil {
+			current := new(IdempotencyRecord)
+			err := json.Unmarshal(v, current)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			if current.Expiry > record.CreatedOn {
+				existing = current
+				return nil
+			}
+		}
+
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return util.ErrStorage
+		}
+
+		return bucket.Put([]byte(record.Key), recordBytes)
+	})
+	return existing, err
+}
+
+// Update stores the most updated state of the idempotency record.
+func (record *IdempotencyRecord) Update(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.IdempotencyBucket)
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		return bucket.Put([]byte(record.Key), recordBytes)
+	})
+	return err
+}
+
+// Purge permanently removes the idempotency record from storage.
+func (record *IdempotencyRecord) Purge(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(util.IdempotencyBucket).Delete([]byte(record.Key))
+	})
+	return err
+}
+
+// PurgeExpiredIdempotencyRecords removes the idempotency records expired at
+// the provided unix time.
+func PurgeExpiredIdempotencyRecords(now int64, db Store) error {
+	expired := [][]byte{}
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.IdempotencyBucket)
+		record := new(IdempotencyRecord)
+		err := bucket.ForEach(func(k, v []byte) error {
+			err := json.Unmarshal(v, record)
+			if err != nil {
+				return err
+			}
+
+			if record.Expiry <= now {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Delete after iterating, bolt does not support deleting keys
+		// while iterating with a cursor.
+		for _, key := range expired {
+			err = bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
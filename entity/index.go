@@ -0,0 +1,195 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// sortIndexes are the query schemas of the entity buckets whose sortable
+// fields are indexed, keyed by bucket.
+var sortIndexes = map[string]*util.QuerySchema{
+	string(util.UserBucket):      UserQuery,
+	string(util.InviteBucket):    InviteQuery,
+	string(util.FeedbackBucket):  FeedbackQuery,
+	string(util.PassResetBucket): PassResetQuery,
+}
+
+// indexKeys is the bucket of the index keys of every indexed entity, keyed by
+// id, for them to be replaced when the entity is written.
+var indexKeys = []byte("keys")
+
+// sortKey returns the index key of the sortable field of an entity state.
+// Keys order as query.SortSlice orders the field, strings ignoring case, and
+// entities with equal fields by id.
+func sortKey(field util.QueryField, state map[string]interface{}, id []byte) []byte {
+	key := []byte{}
+	switch field.Type {
+	case util.TimeField:
+		number, _ := state[field.Name].(json.Number)
+		unix, _ := number.Int64()
+		key = make([]byte, 8)
+		// Flip the sign bit for negative times to order first.
+		binary.BigEndian.PutUint64(key, uint64(unix)^(1<<63))
+	case util.BoolField:
+		key = []byte{0}
+		if state[field.Name] == true {
+			key[0] = 1
+		}
+	default:
+		value, _ := state[field.Name].(string)
+		key = append([]byte(strings.ToLower(value)), 0)
+	}
+	return append(key, id...)
+}
+
+// putEntity writes the state of an entity to its bucket and indexes its
+// sortable fields. Every write to an entity bucket goes through putEntity or
+// deleteEntity so the sort indexes stay in step with the stored entities.
+func putEntity(tx *bolt.Tx, entityBucket []byte, id []byte, data []byte) error {
+	err := tx.Bucket(entityBucket).Put(id, data)
+	if err != nil {
+		return err
+	}
+	return updateSortIndex(tx, entityBucket, id, data)
+}
+
+// deleteEntity removes an entity from its bucket and its sort indexes.
+func deleteEntity(tx *bolt.Tx, entityBucket []byte, id []byte) error {
+	err := tx.Bucket(entityBucket).Delete(id)
+	if err != nil {
+		return err
+	}
+	return updateSortIndex(tx, entityBucket, id, nil)
+}
+
+// updateSortIndex indexes the sortable fields of the state an entity is
+// written with, removing the keys of its previous state. Purged entities are
+// written without a state. Buckets whose index has not been built yet are
+// left for BuildSortIndexes.
+func updateSortIndex(tx *bolt.Tx, entityBucket []byte, id []byte, data []byte) error {
+	schema, ok := sortIndexes[string(entityBucket)]
+	if !ok {
+		return nil
+	}
+
+	index := tx.Bucket(util.IndexBucket).Bucket(entityBucket)
+	if index == nil {
+		return nil
+	}
+
+	keys := index.Bucket(indexKeys)
+	previous := keys.Get(id)
+	if previous != nil {
+		stored := map[string][]byte{}
+		err := json.Unmarshal(previous, &stored)
+		if err != nil {
+			return util.ErrStorage
+		}
+
+		for name, key := range stored {
+			fieldIndex := index.Bucket([]byte(name))
+			if fieldIndex == nil {
+				continue
+			}
+
+			err = fieldIndex.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = keys.Delete(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	state := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&state)
+	if err != nil {
+		return util.ErrStorage
+	}
+
+	current := map[string][]byte{}
+	for _, field := range schema.Fields {
+		if !field.Sortable {
+			continue
+		}
+
+		fieldIndex, err := index.CreateBucketIfNotExists([]byte(field.Name))
+		if err != nil {
+			return err
+		}
+
+		key := sortKey(field, state, id)
+		err = fieldIndex.Put(key, id)
+		if err != nil {
+			return err
+		}
+		current[field.Name] = key
+	}
+
+	keysBytes, err := json.Marshal(current)
+	if err != nil {
+		return util.ErrStorage
+	}
+	return keys.Put(id, keysBytes)
+}
+
+// sortIndex returns the index of the sortable field of an entity bucket, nil
+// when the field is not indexed.
+func sortIndex(tx *bolt.Tx, entityBucket []byte, field string) *bolt.Bucket {
+	index := tx.Bucket(util.IndexBucket).Bucket(entityBucket)
+	if index == nil || field == "" {
+		return nil
+	}
+	return index.Bucket([]byte(field))
+}
+
+// BuildSortIndexes indexes the sortable fields of every entity of the buckets
+// not indexed yet, such as buckets written before sort indexes were kept.
+func BuildSortIndexes(db Store) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		for name := range sortIndexes {
+			entityBucket := []byte(name)
+			if tx.Bucket(util.IndexBucket).Bucket(entityBucket) != nil {
+				continue
+			}
+
+			index, err := tx.Bucket(util.IndexBucket).CreateBucket(entityBucket)
+			if err != nil {
+				return err
+			}
+
+			_, err = index.CreateBucket(indexKeys)
+			if err != nil {
+				return err
+			}
+
+			bucket := tx.Bucket(entityBucket)
+			if bucket == nil {
+				continue
+			}
+
+			err = bucket.ForEach(func(k, v []byte) error {
+				return updateSortIndex(tx, entityBucket, k, v)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
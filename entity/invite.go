@@ -2,7 +2,6 @@ package entity
 
 import (
 	"encoding/json"
-	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -27,14 +26,16 @@ type Invite struct {
 	Role         string `json:"role"`
 	Status       string `json:"status"`
 	LastModified int64  `json:"lastModified"`
+	ModifiedBy   string `json:"modifiedBy"`
 	CreatedOn    int64  `json:"createdOn"`
 	Expiry       int64  `json:"expiry"`
 	InvitedBy    string `json:"invitedBy"`
 	Deleted      bool   `json:"deleted"`
+	DeletedOn    int64  `json:"deletedOn"`
 }
 
 // GetInvite fetches the invite associated with the provided id.
-func GetInvite(id []byte, db *bolt.DB) (*Invite, error) {
+func GetInvite(id []byte, db Store) (*Invite, error) {
 	invite := new(Invite)
 	err := db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.InviteBucket)
@@ -44,86 +45,140 @@ func GetInvite(id []byte, db *bolt.DB) (*Invite, error) {
 		}
 
 		err := json.Unmarshal(v, invite)
-		return err
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
 	})
 	return invite, err
 }
 
-// Update stores the most updated state of the user entity.
-func (invite *Invite) Update(db *bolt.DB) error {
+// GetInviteAt fetches the state of the invite associated with the provided id
+// as it was at the provided unix time.
+func GetInviteAt(id []byte, timestamp int64, db Store) (*Invite, error) {
+	state, err := getStateAt(util.InviteBucket, id, timestamp, db)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := new(Invite)
+	err = json.Unmarshal(state, invite)
+	if err != nil {
+		return nil, util.ErrStorage
+	}
+	return invite, nil
+}
+
+// Update stores the most updated state of the user entity. The state being
+// replaced is recorded in the invite's history, attributed to the account
+// referenced by ModifiedBy.
+func (invite *Invite) Update(db Store) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.InviteBucket)
 		inviteBytes, err := json.Marshal(invite)
 		if err != nil {
-			return util.ErrMalformedJSON
+			return util.ErrStorage
 		}
 
-		err = bucket.Put([]byte(invite.Uuid), inviteBytes)
+		err = putRevision(tx, util.InviteBucket, []byte(invite.Uuid), invite.ModifiedBy)
+		if err != nil {
+			return err
+		}
+
+		err = putEntity(tx, util.InviteBucket, []byte(invite.Uuid), inviteBytes)
+		if err != nil {
+			return err
+		}
+
+		err = putChange(tx, util.InviteBucket, []byte(invite.Uuid), PutOperation, inviteBytes)
 		return err
 	})
 	return err
 }
 
+// Restore reverts the invite entity to the state recorded by the provided
+// revision, that is the state it had before the revision's write.
+func (invite *Invite) Restore(revisionId uint64, actor string, db Store) error {
+	revision, err := GetRevision(util.InviteBucket, []byte(invite.Uuid), revisionId, db)
+	if err != nil {
+		return err
+	}
+
+	if len(revision.Previous) == 0 || string(revision.Previous) == "null" {
+		return util.ErrNoPriorState
+	}
+
+	previous := new(Invite)
+	err = json.Unmarshal(revision.Previous, previous)
+	if err != nil {
+		return util.ErrStorage
+	}
+
+	previous.LastModified = time.Now().Unix()
+	previous.ModifiedBy = actor
+	err = previous.Update(db)
+	if err != nil {
+		return err
+	}
+
+	*invite = *previous
+	return nil
+}
+
 // Delete toggles the invites entity's delete status. This determines whether
 // the entity is queryable by the service, the entity will exist in storage
 // regardless of state.
-func (invite *Invite) Delete(state bool, db *bolt.DB) error {
+func (invite *Invite) Delete(state bool, db Store) error {
+	now := time.Now().Unix()
 	invite.Deleted = state
-	invite.LastModified = time.Now().Unix()
+	invite.DeletedOn = 0
+	if state {
+		invite.DeletedOn = now
+	}
+	invite.LastModified = now
 	return invite.Update(db)
 }
 
-// ListInvites returns a set of invites that match the query criteria.
-func ListInvites(db *bolt.DB, pageLimit uint32, term string, offset uint32) (*[]Invite, error) {
-	var target uint32
-	inviteList := []Invite{}
-	currInvite := new(Invite)
-	err := db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.InviteBucket)
-		cursor := bucket.Cursor()
-		// Adjust data size targets based on offset and page limit
-		if offset > 0 {
-			target = pageLimit * (offset + 1)
-		}
+// Purge permanently removes the invite entity and its history from storage.
+func (invite *Invite) Purge(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return purge(tx, util.InviteBucket, []byte(invite.Uuid))
+	})
+	return err
+}
 
-		if offset == 0 {
-			target = pageLimit
-		}
+// InviteQuery describes the filters and sort fields of invite list queries.
+var InviteQuery = &util.QuerySchema{
+	Fields: []util.QueryField{
+		{Name: "email", Type: util.StringField, Sortable: true},
+		{Name: "role", Type: util.StringField, Options: []string{util.Admin, util.Management, util.Finance}},
+		{Name: "status", Type: util.StringField, Options: []string{Pending, Cancelled, Accepted}},
+		{Name: "invitedBy", Type: util.StringField, Sortable: true},
+		{Name: "createdOn", Param: "created", Type: util.TimeField, Sortable: true},
+		{Name: "expiry", Param: "expires", Type: util.TimeField, Sortable: true},
+	},
+	Search: []string{"email", "invitedBy", "role"},
+}
 
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			err := json.Unmarshal(v, currInvite)
-			if err != nil {
-				return util.ErrMalformedJSON
-			}
-
-			if term != "" {
-				if (strings.Contains(strings.ToLower(currInvite.Email), strings.ToLower(term)) ||
-					strings.Contains(strings.ToLower(currInvite.InvitedBy), strings.ToLower(term)) ||
-					strings.Contains(strings.ToLower(currInvite.Role), strings.ToLower(term))) && !currInvite.Deleted {
-					inviteList = append(inviteList, *currInvite)
-					// Stop iterating when data target has been met.
-					if uint32(len(inviteList)) == target {
-						break
-					}
-				}
-			}
-
-			if term == "" && !currInvite.Deleted {
-				inviteList = append(inviteList, *currInvite)
-				// Stop iterating when data target has been met.
-				if uint32(len(inviteList)) == target {
-					break
-				}
-			}
-		}
+// QueryInvites returns the page of invites matching the query, deleted
+// invites are left out.
+func QueryInvites(db Store, pageLimit uint32, query *util.Query) (*[]Invite, error) {
+	inviteList := []Invite{}
+	err := queryBucket(db, util.InviteBucket, pageLimit, query, &inviteList, func(entity interface{}) bool {
+		return !entity.(*Invite).Deleted
+	})
+	return &inviteList, err
+}
 
-		// Slice the relevant data according to the page limit and offset.
-		if offset > 0 {
-			inviteList = inviteList[(pageLimit * offset):]
-		}
+// ListInvites returns a set of invites that match the query criteria.
+func ListInvites(db Store, pageLimit uint32, term string, offset uint32) (*[]Invite, error) {
+	return QueryInvites(db, pageLimit, InviteQuery.NewQuery(term, offset))
+}
 
-		return nil
+// ListDeletedInvites returns a set of soft-deleted invites.
+func ListDeletedInvites(db Store, pageLimit uint32, offset uint32) (*[]Invite, error) {
+	inviteList := []Invite{}
+	err := queryBucket(db, util.InviteBucket, pageLimit, &util.Query{Offset: offset}, &inviteList, func(entity interface{}) bool {
+		return entity.(*Invite).Deleted
 	})
-
 	return &inviteList, err
 }
@@ -5,7 +5,11 @@
 
 package entity
 
-import "github.com/btcsuite/btclog"
+import (
+	"einheit/boltkit/util"
+
+	"github.com/btcsuite/btclog"
+)
 
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
@@ -29,3 +33,9 @@ func DisableLog() {
 func UseLogger(logger btclog.Logger) {
 	log = logger
 }
+
+// logger returns the logger of a store, logging with the log fields of the
+// request it runs transactions for.
+func logger(db Store) btclog.Logger {
+	return util.ContextLogger(log, contextOf(db))
+}
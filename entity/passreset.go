@@ -3,7 +3,6 @@ package entity
 import (
 	"encoding/json"
 	"reflect"
-	"strings"
 
 	"einheit/boltkit/util"
 
@@ -27,7 +26,7 @@ type PassReset struct {
 }
 
 // GetPassReset fetches the password reset associated with the provided id.
-func GetPassReset(id []byte, db *bolt.DB) (*PassReset, error) {
+func GetPassReset(id []byte, db Store) (*PassReset, error) {
 	reset := new(PassReset)
 	err := db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.PassResetBucket)
@@ -37,29 +36,44 @@ func GetPassReset(id []byte, db *bolt.DB) (*PassReset, error) {
 		}
 
 		err := json.Unmarshal(v, reset)
-		return err
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
 	})
 	return reset, err
 }
 
 // Update stores the most updated state of the password reset entity.
-func (reset *PassReset) Update(db *bolt.DB) error {
+func (reset *PassReset) Update(db Store) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.PassResetBucket)
 		resetBytes, err := json.Marshal(reset)
 		if err != nil {
-			log.Error(util.ErrMalformedJSON)
-			return util.ErrMalformedJSON
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		err = putEntity(tx, util.PassResetBucket, []byte(reset.Uuid), resetBytes)
+		if err != nil {
+			return err
 		}
 
-		err = bucket.Put([]byte(reset.Uuid), resetBytes)
+		err = putChange(tx, util.PassResetBucket, []byte(reset.Uuid), PutOperation, resetBytes)
 		return err
 	})
 	return err
 }
 
+// Purge permanently removes the password reset entity from storage.
+func (reset *PassReset) Purge(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return purge(tx, util.PassResetBucket, []byte(reset.Uuid))
+	})
+	return err
+}
+
 // Delete not applicable for password resets.
-func (reset *PassReset) Delete(state bool, db *bolt.DB) error {
+func (reset *PassReset) Delete(state bool, db Store) error {
 	return util.ErrNotApplicable(reflect.TypeOf(reset).Name())
 }
 
@@ -69,57 +83,33 @@ func (reset *PassReset) Sanitize() {
 	reset.ResetURL = ""
 }
 
-// ListPassReset returns a set of password resets that match the query criteria.
-func ListPassReset(db *bolt.DB, pageLimit uint32, term string, offset uint32) (*[]PassReset, error) {
-	var target uint32
-	resetList := []PassReset{}
-	currReset := new(PassReset)
-	err := db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.PassResetBucket)
-		cursor := bucket.Cursor()
-		// Adjust data size targets based on offset and page limit
-		if offset > 0 {
-			target = pageLimit * (offset + 1)
-		}
-
-		if offset == 0 {
-			target = pageLimit
-		}
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			err := json.Unmarshal(v, currReset)
-			if err != nil {
-				return util.ErrMalformedJSON
-			}
-
-			if term != "" {
-				if strings.Contains(strings.ToLower(currReset.Email), strings.ToLower(term)) ||
-					strings.Contains(strings.ToLower(currReset.User), strings.ToLower(term)) {
-					currReset.Sanitize()
-					resetList = append(resetList, *currReset)
-					// Stop iterating when data target has been met.
-					if uint32(len(resetList)) == target {
-						break
-					}
-				}
-			}
-
-			if term == "" {
-				resetList = append(resetList, *currReset)
-				// Stop iterating when data target has been met.
-				if uint32(len(resetList)) == target {
-					break
-				}
-			}
-		}
-
-		// Slice the relevant data according to the page limit and offset.
-		if offset > 0 {
-			resetList = resetList[(pageLimit * offset):]
-		}
+// PassResetQuery describes the filters and sort fields of password reset list
+// queries.
+var PassResetQuery = &util.QuerySchema{
+	Fields: []util.QueryField{
+		{Name: "email", Type: util.StringField, Sortable: true},
+		{Name: "user", Type: util.StringField, Sortable: true},
+		{Name: "used", Type: util.BoolField},
+		{Name: "createdOn", Param: "created", Type: util.TimeField, Sortable: true},
+		{Name: "expiry", Param: "expires", Type: util.TimeField, Sortable: true},
+	},
+	Search: []string{"email", "user"},
+}
 
-		return nil
+// QueryPassResets returns the page of password resets matching the query.
+func QueryPassResets(db Store, pageLimit uint32, query *util.Query) (*[]PassReset, error) {
+	resetList := []PassReset{}
+	err := queryBucket(db, util.PassResetBucket, pageLimit, query, &resetList, func(entity interface{}) bool {
+		return true
 	})
 
+	for idx := range resetList {
+		resetList[idx].Sanitize()
+	}
 	return &resetList, err
 }
+
+// ListPassReset returns a set of password resets that match the query criteria.
+func ListPassReset(db Store, pageLimit uint32, term string, offset uint32) (*[]PassReset, error) {
+	return QueryPassResets(db, pageLimit, PassResetQuery.NewQuery(term, offset))
+}
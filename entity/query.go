@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// queryBucket collects the page of entities of a bucket matching the query
+// into results, a pointer to a slice of the entity type. Entities rejected by
+// include are left out regardless of the query. Sorted queries read the
+// entities in the order of the sort index of their field, stopping once the
+// page is collected like unsorted queries do.
+func queryBucket(db Store, bucketName []byte, pageLimit uint32, query *util.Query, results interface{}, include func(entity interface{}) bool) error {
+	// Reject offsets past the last page that can be addressed.
+	if uint64(pageLimit)*(uint64(query.Offset)+1) > math.MaxUint32 {
+		return util.ValidationErrors{{Field: "offset", Message: "is too large"}}
+	}
+
+	list := reflect.ValueOf(results).Elem()
+	entityType := list.Type().Elem()
+	target := int(pageLimit * (query.Offset + 1))
+	inMemory := false
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		cursor := bucket.Cursor()
+		index := sortIndex(tx, bucketName, query.Sort)
+		if index != nil {
+			cursor = index.Cursor()
+		}
+
+		// Sorted queries of unindexed fields have to collect every match
+		// before paging.
+		inMemory = query.Sort != "" && index == nil
+		next := cursor.Next
+		k, v := cursor.First()
+		if index != nil && query.Desc {
+			next = cursor.Prev
+			k, v = cursor.Last()
+		}
+
+		for ; k != nil; k, v = next() {
+			// Index entries hold the id of their entity.
+			if index != nil {
+				v = bucket.Get(v)
+				if v == nil {
+					continue
+				}
+			}
+
+			current := reflect.New(entityType)
+			err := json.Unmarshal(v, current.Interface())
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			if !include(current.Interface()) || !query.Match(current.Interface()) {
+				continue
+			}
+
+			list = reflect.Append(list, current.Elem())
+			// Stop iterating when data target has been met.
+			if !inMemory && list.Len() == target {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if inMemory {
+		query.SortSlice(list.Interface())
+	}
+
+	// Slice the relevant data according to the page limit and offset.
+	start := int(pageLimit * query.Offset)
+	if start > list.Len() {
+		start = list.Len()
+	}
+	end := start + int(pageLimit)
+	if end > list.Len() {
+		end = list.Len()
+	}
+
+	reflect.ValueOf(results).Elem().Set(list.Slice(start, end))
+	return nil
+}
@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"encoding/json"
+	"sort"
+
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// Relation describes a field of an entity holding the id of a related
+// entity.
+type Relation struct {
+	Bucket []byte
+	// ID returns the id of the entity related to the provided entity.
+	ID func(entity interface{}) string
+	// New returns a pointer to an empty related entity.
+	New func() interface{}
+}
+
+// Relations are the relations of an entity, keyed by the json name of the
+// field holding the related id.
+type Relations map[string]Relation
+
+// sanitizer is implemented by entities holding sensitive details.
+type sanitizer interface {
+	Sanitize()
+}
+
+var (
+	// UserRelations relates a user to the invite it registered with.
+	UserRelations = Relations{
+		"invite": {Bucket: util.InviteBucket, New: func() interface{} { return new(Invite) },
+			ID: func(entity interface{}) string { return entity.(*User).Invite }},
+	}
+
+	// InviteRelations relates an invite to the user who sent it.
+	InviteRelations = Relations{
+		"invitedBy": {Bucket: util.UserBucket, New: func() interface{} { return new(User) },
+			ID: func(entity interface{}) string { return entity.(*Invite).InvitedBy }},
+	}
+
+	// FeedbackRelations relates feedback to the user who submitted it.
+	FeedbackRelations = Relations{
+		"user": {Bucket: util.UserBucket, New: func() interface{} { return new(User) },
+			ID: func(entity interface{}) string { return entity.(*Feedback).User }},
+	}
+
+	// PassResetRelations relates a password reset to the user resetting
+	// their password.
+	PassResetRelations = Relations{
+		"user": {Bucket: util.UserBucket, New: func() interface{} { return new(User) },
+			ID: func(entity interface{}) string { return entity.(*PassReset).User }},
+	}
+)
+
+// Names returns the sorted names of the relations.
+func (relations Relations) Names() []string {
+	names := make([]string, 0, len(relations))
+	for name := range relations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve fetches the entities related to each of the provided entity
+// pointers by the included relations, in a single read transaction. Related
+// entities are sanitized, those missing from storage are resolved to nil.
+func (relations Relations) Resolve(db Store, include []string, entities []interface{}) ([]map[string]interface{}, error) {
+	resolved := make([]map[string]interface{}, len(entities))
+	if len(include) == 0 {
+		return resolved, nil
+	}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		for idx, current := range entities {
+			related := map[string]interface{}{}
+			for _, name := range include {
+				relation, ok := relations[name]
+				if !ok {
+					return util.ErrInvalidParameter(name)
+				}
+
+				related[name] = nil
+				v := tx.Bucket(relation.Bucket).Get([]byte(relation.ID(current)))
+				if v == nil {
+					continue
+				}
+
+				entity := relation.New()
+				err := json.Unmarshal(v, entity)
+				if err != nil {
+					return util.ErrStorage
+				}
+
+				if sanitized, ok := entity.(sanitizer); ok {
+					sanitized.Sanitize()
+				}
+				related[name] = entity
+			}
+			resolved[idx] = related
+		}
+		return nil
+	})
+	return resolved, err
+}
@@ -1,77 +1,257 @@
 package entity
 
 import (
-	"einheit/boltkit/base58"
+	"bytes"
 	"einheit/boltkit/util"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
-	"github.com/metakeule/fmtdate"
 )
 
+var (
+	// requestLogEntries is the bucket of the request log entries, keyed by
+	// the time they were made at.
+	requestLogEntries = []byte("entries")
+
+	// requestLogUsers is the bucket of the entry keys of every user, in a
+	// bucket per user.
+	requestLogUsers = []byte("users")
+
+	// errPageFull stops a scan once a page of entries is collected.
+	errPageFull = errors.New("page full")
+)
+
+// requestLogChunk is the number of entries read per transaction when
+// scanning the request log.
+const requestLogChunk = 256
+
 // RequestLog represents a service request log.
 type RequestLog struct {
+	ID          string                 `json:"id"`
+	RequestID   string                 `json:"requestId"`
 	Origin      string                 `json:"origin"`
 	Requestor   string                 `json:"requestor"`
 	RequestType string                 `json:"type"`
 	Route       string                 `json:"route"`
+	Template    string                 `json:"template"`
 	QueryParams string                 `json:"queryParams"`
 	Payload     map[string]interface{} `json:"payload"`
+	Status      int                    `json:"status"`
+	Latency     float64                `json:"latency"`
+	CreatedOn   int64                  `json:"createdOn"`
 }
 
-// ListRequestLog returns a set of users that match the query criteria.
-func ListRequestLog(db *bolt.DB, pageLimit uint32, date string, email string, requestType string, offset uint32) (*[]RequestLog, error) {
-	var target uint32
-	logList := []RequestLog{}
-	currLog := new(RequestLog)
-	time, err := fmtdate.Parse(util.TimeFormat, date)
-	if err != nil {
-		return nil, err
+// RequestLogQuery filters the request log. Entries are made at or after From
+// and before To, unix times, by the requestor user id if any. Route matches
+// the path or the route template, and Statuses lists the response statuses
+// matched.
+type RequestLogQuery struct {
+	From     int64
+	To       int64
+	User     string
+	Route    string
+	Method   string
+	Statuses []int
+}
+
+// matches asserts an entry matches the filters of the query other than its
+// time range and user.
+func (query RequestLogQuery) matches(entry *RequestLog) bool {
+	if query.Route != "" && query.Route != entry.Route && query.Route != entry.Template {
+		return false
 	}
 
-	dateStr := fmtdate.Format(util.DateFormat, time)
-	err = db.View(func(tx *bolt.Tx) error {
+	if query.Method != "" && !strings.EqualFold(query.Method, entry.RequestType) {
+		return false
+	}
+
+	if len(query.Statuses) == 0 {
+		return true
+	}
+	for _, status := range query.Statuses {
+		if status == entry.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// requestLogKey returns the key of an entry made at the provided time, the
+// sequence orders entries made at the same time.
+func requestLogKey(at time.Time, sequence uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(at.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+	return key
+}
+
+// Append records the entry in the request log, indexed by the time it was
+// made at and its requestor.
+func (requestLog *RequestLog) Append(db Store) error {
+	return db.Update(func(tx *bolt.Tx) error {
 		logBucket := tx.Bucket(util.LogBucket)
-		dateBucket := logBucket.Bucket([]byte(dateStr))
+		entries, err := logBucket.CreateBucketIfNotExists(requestLogEntries)
+		if err != nil {
+			return err
+		}
+
+		sequence, err := entries.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := requestLogKey(time.Unix(requestLog.CreatedOn, 0), sequence)
+		requestLog.ID = hex.EncodeToString(key)
+		logBytes, err := json.Marshal(requestLog)
+		if err != nil {
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
 
-		token := base58.Encode([]byte(email))
-		tokenBucket := dateBucket.Bucket([]byte(token))
-		cursor := tokenBucket.Cursor()
-		// Adjust data size targets based on offset and page limit
-		if offset > 0 {
-			target = pageLimit * (offset + 1)
+		err = entries.Put(key, logBytes)
+		if err != nil {
+			return err
 		}
 
-		if offset == 0 {
-			target = pageLimit
+		users, err := logBucket.CreateBucketIfNotExists(requestLogUsers)
+		if err != nil {
+			return err
+		}
+
+		userBucket, err := users.CreateBucketIfNotExists([]byte(requestLog.Requestor))
+		if err != nil {
+			return err
+		}
+
+		return userBucket.Put(key, []byte{})
+	})
+}
+
+// ScanRequestLog calls fn with every entry matching the query, in the order
+// they were made in. The scan stops at the first error returned by fn. The
+// log is read in chunks, fn is called between read transactions so slow
+// callers do not hold one open.
+func ScanRequestLog(db Store, query RequestLogQuery, fn func(entry *RequestLog) error) error {
+	from := requestLogKey(time.Unix(query.From, 0), 0)
+	to := requestLogKey(time.Unix(query.To, 0), 0)
+	var last []byte
+	for {
+		entries, next, err := readRequestLog(db, query, from, last, to)
+		if err != nil {
+			return err
 		}
 
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			err := json.Unmarshal(v, currLog)
+		for _, entry := range entries {
+			err = fn(entry)
 			if err != nil {
-				return util.ErrMalformedJSON
+				return err
 			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		last = next
+	}
+}
+
+// readRequestLog reads a chunk of the entries matching the query, from the
+// key after last, or from when there is none, up to to. It returns the last
+// key read, nil once the range is exhausted.
+func readRequestLog(db Store, query RequestLogQuery, from []byte, last []byte, to []byte) ([]*RequestLog, []byte, error) {
+	entries := []*RequestLog{}
+	var next []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		logBucket := tx.Bucket(util.LogBucket)
+		entryBucket := logBucket.Bucket(requestLogEntries)
+		if entryBucket == nil {
+			return nil
+		}
 
-			if requestType == "" {
-				logList = append(logList, *currLog)
-			} else if strings.ToLower(currLog.RequestType) == strings.ToLower(requestType) {
-				logList = append(logList, *currLog)
+		// Scan the entries of the user, or every entry.
+		cursor := entryBucket.Cursor()
+		if query.User != "" {
+			users := logBucket.Bucket(requestLogUsers)
+			if users == nil {
+				return nil
 			}
 
-			// Stop iterating when data target has been met.
-			if uint32(len(logList)) == target {
-				break
+			userBucket := users.Bucket([]byte(query.User))
+			if userBucket == nil {
+				return nil
 			}
+			cursor = userBucket.Cursor()
 		}
 
-		// Slice the relevant data according to the page limit and offset.
-		if offset > 0 {
-			logList = logList[(pageLimit * offset):]
+		key, value := cursor.Seek(from)
+		if last != nil {
+			key, value = cursor.Seek(last)
+			if bytes.Equal(key, last) {
+				key, value = cursor.Next()
+			}
 		}
 
+		for read := 0; key != nil && bytes.Compare(key, to) < 0; key, value = cursor.Next() {
+			if read == requestLogChunk {
+				next = append([]byte{}, last...)
+				return nil
+			}
+			read++
+			last = key
+
+			if query.User != "" {
+				value = entryBucket.Get(key)
+				if value == nil {
+					continue
+				}
+			}
+
+			entry := new(RequestLog)
+			err := json.Unmarshal(value, entry)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			if query.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	return entries, next, err
+}
+
+// ListRequestLog returns a page of the entries matching the query, in the
+// order they were made in.
+func ListRequestLog(db Store, pageLimit uint32, query RequestLogQuery, offset uint32) (*[]RequestLog, error) {
+	// Reject offsets past the last page that can be addressed.
+	if uint64(pageLimit)*(uint64(offset)+1) > math.MaxUint32 {
+		return nil, util.ValidationErrors{{Field: "offset", Message: "is too large"}}
+	}
+
+	logList := []RequestLog{}
+	skip := pageLimit * offset
+	err := ScanRequestLog(db, query, func(entry *RequestLog) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+
+		logList = append(logList, *entry)
+		if uint32(len(logList)) == pageLimit {
+			return errPageFull
+		}
 		return nil
 	})
+	if err == errPageFull {
+		err = nil
+	}
 
 	return &logList, err
 }
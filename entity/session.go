@@ -5,7 +5,6 @@ import (
 	"reflect"
 	"sync"
 
-	"github.com/boltdb/bolt"
 	cmap "github.com/orcaman/concurrent-map"
 )
 
@@ -35,11 +34,11 @@ func (session *Session) Update(sessionMap cmap.ConcurrentMap) {
 }
 
 // Delete not applicable for sessions.
-func (session *Session) Delete(state bool, db *bolt.DB, mtx *sync.Mutex) error {
+func (session *Session) Delete(state bool, db Store, mtx *sync.Mutex) error {
 	return util.ErrNotApplicable(reflect.TypeOf(session).Name())
 }
 
 // ListSessions returns a set of sessions that match the query criteria.
-func ListSessions(db *bolt.DB, pageLimit uint32, term string, offset uint32) (*[]Session, error) {
+func ListSessions(db Store, pageLimit uint32, term string, offset uint32) (*[]Session, error) {
 	return nil, util.ErrNotApplicable("session")
 }
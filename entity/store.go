@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"context"
+	"time"
+
+	"einheit/boltkit/trace"
+
+	"github.com/boltdb/bolt"
+)
+
+// Store runs the transactions entities are read and written in, it is
+// implemented by *bolt.DB and DB.
+type Store interface {
+	View(fn func(*bolt.Tx) error) error
+	Update(fn func(*bolt.Tx) error) error
+}
+
+// DB is a bolt database reporting the duration of its transactions.
+type DB struct {
+	*bolt.DB
+	// Observe is called with the kind, view or update, and the duration of
+	// every transaction.
+	Observe func(kind string, duration time.Duration)
+}
+
+// View runs fn in a read-only transaction.
+func (db *DB) View(fn func(*bolt.Tx) error) error {
+	start := time.Now()
+	err := db.DB.View(fn)
+	if db.Observe != nil {
+		db.Observe("view", time.Since(start))
+	}
+	return err
+}
+
+// Update runs fn in a read-write transaction.
+func (db *DB) Update(fn func(*bolt.Tx) error) error {
+	start := time.Now()
+	err := db.DB.Update(fn)
+	if db.Observe != nil {
+		db.Observe("update", time.Since(start))
+	}
+	return err
+}
+
+// WithContext returns a store tracing its transactions as children of the
+// span of the context, and logging with the log fields of the context.
+func (db *DB) WithContext(ctx context.Context) Store {
+	return contextStore{db: db, ctx: ctx}
+}
+
+// contextStore runs the transactions of a DB for the request of a context.
+type contextStore struct {
+	db  *DB
+	ctx context.Context
+}
+
+// View runs fn in a traced read-only transaction.
+func (store contextStore) View(fn func(*bolt.Tx) error) error {
+	_, span := trace.Start(store.ctx, "bolt.view", trace.KindInternal)
+	defer span.Finish()
+
+	err := store.db.View(fn)
+	span.SetError(err)
+	return err
+}
+
+// Update runs fn in a traced read-write transaction.
+func (store contextStore) Update(fn func(*bolt.Tx) error) error {
+	_, span := trace.Start(store.ctx, "bolt.update", trace.KindInternal)
+	defer span.Finish()
+
+	err := store.db.Update(fn)
+	span.SetError(err)
+	return err
+}
+
+// contextOf returns the context a store runs its transactions for.
+func contextOf(db Store) context.Context {
+	if store, ok := db.(contextStore); ok {
+		return store.ctx
+	}
+	return context.Background()
+}
+
+// TxStore runs every transaction in an open read-write transaction, grouping
+// the writes of several entities into a single commit.
+type TxStore struct {
+	Tx *bolt.Tx
+}
+
+// View runs fn in the open transaction.
+func (store TxStore) View(fn func(*bolt.Tx) error) error {
+	return fn(store.Tx)
+}
+
+// Update runs fn in the open transaction, it is committed or rolled back by
+// the owner of the transaction.
+func (store TxStore) Update(fn func(*bolt.Tx) error) error {
+	return fn(store.Tx)
+}
@@ -2,7 +2,6 @@ package entity
 
 import (
 	"encoding/json"
-	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -20,13 +19,15 @@ type User struct {
 	Role         string `json:"role"`
 	LastLogin    int64  `json:"lastLogin"`
 	LastModified int64  `json:"lastModified"`
+	ModifiedBy   string `json:"modifiedBy"`
 	CreatedOn    int64  `json:"createdOn"`
 	Deleted      bool   `json:"deleted"`
+	DeletedOn    int64  `json:"deletedOn"`
 	Invite       string `json:"invite"`
 }
 
 // GetUser fetches the user associated with the provided id.
-func GetUser(id []byte, db *bolt.DB) (*User, error) {
+func GetUser(id []byte, db Store) (*User, error) {
 	user := new(User)
 	err := db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.UserBucket)
@@ -36,95 +37,193 @@ func GetUser(id []byte, db *bolt.DB) (*User, error) {
 		}
 
 		err := json.Unmarshal(v, user)
-		return err
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
 	})
 	return user, err
 }
 
-// Update stores the most updated state of the user entity.
-func (user *User) Update(db *bolt.DB) error {
+// GetUserAt fetches the state of the user associated with the provided id as
+// it was at the provided unix time.
+func GetUserAt(id []byte, timestamp int64, db Store) (*User, error) {
+	state, err := getStateAt(util.UserBucket, id, timestamp, db)
+	if err != nil {
+		return nil, err
+	}
+
+	user := new(User)
+	err = json.Unmarshal(state, user)
+	if err != nil {
+		return nil, util.ErrStorage
+	}
+	return user, nil
+}
+
+// Update stores the most updated state of the user entity. The state being
+// replaced is recorded in the user's history, attributed to the account
+// referenced by ModifiedBy.
+func (user *User) Update(db Store) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.UserBucket)
 		userBytes, err := json.Marshal(user)
 		if err != nil {
-			log.Error(util.ErrMalformedJSON)
-			return util.ErrMalformedJSON
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		err = putRevision(tx, util.UserBucket, []byte(user.Uuid), user.ModifiedBy)
+		if err != nil {
+			return err
+		}
+
+		err = putEntity(tx, util.UserBucket, []byte(user.Uuid), userBytes)
+		if err != nil {
+			return err
 		}
 
-		err = bucket.Put([]byte(user.Uuid), userBytes)
+		err = putChange(tx, util.UserBucket, []byte(user.Uuid), PutOperation, userBytes)
 		return err
 	})
 	return err
 }
 
+// TouchLogin records the time the user last signed in. Sign-ins do not
+// change the account, the stored user is written without a revision or a
+// change event so history and the change feed are not filled with them.
+func (user *User) TouchLogin(lastLogin int64, db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.UserBucket)
+		v := bucket.Get([]byte(user.Uuid))
+		if v == nil {
+			return util.ErrKeyNotFound(user.Uuid)
+		}
+
+		stored := new(User)
+		err := json.Unmarshal(v, stored)
+		if err != nil {
+			return util.ErrStorage
+		}
+
+		stored.LastLogin = lastLogin
+		userBytes, err := json.Marshal(stored)
+		if err != nil {
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		return putEntity(tx, util.UserBucket, []byte(user.Uuid), userBytes)
+	})
+	if err != nil {
+		return err
+	}
+
+	user.LastLogin = lastLogin
+	return nil
+}
+
+// Restore reverts the user entity to the state recorded by the provided
+// revision, that is the state it had before the revision's write. The current
+// password is kept, restoring a revision does not restore old credentials.
+func (user *User) Restore(revisionId uint64, actor string, db Store) error {
+	revision, err := GetRevision(util.UserBucket, []byte(user.Uuid), revisionId, db)
+	if err != nil {
+		return err
+	}
+
+	if len(revision.Previous) == 0 || string(revision.Previous) == "null" {
+		return util.ErrNoPriorState
+	}
+
+	previous := new(User)
+	err = json.Unmarshal(revision.Previous, previous)
+	if err != nil {
+		return util.ErrStorage
+	}
+
+	previous.Password = user.Password
+	previous.LastModified = time.Now().Unix()
+	previous.ModifiedBy = actor
+	err = previous.Update(db)
+	if err != nil {
+		return err
+	}
+
+	*user = *previous
+	return nil
+}
+
 // Delete toggles the user entity's delete status. This determines whether
 // the entity is queryable by the service, the entity will exist in storage
 // regardless of state.
-func (user *User) Delete(state bool, db *bolt.DB) error {
+func (user *User) Delete(state bool, db Store) error {
+	now := time.Now().Unix()
 	user.Deleted = state
-	user.LastModified = time.Now().Unix()
+	user.DeletedOn = 0
+	if state {
+		user.DeletedOn = now
+	}
+	user.LastModified = now
 	return user.Update(db)
 }
 
+// Purge permanently removes the user entity and its history from storage.
+func (user *User) Purge(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return purge(tx, util.UserBucket, []byte(user.Uuid))
+	})
+	return err
+}
+
 // Sanitize prepares the user entity to be sent a request response.
 // This removes all sensitive details from the entity.
 func (user *User) Sanitize() {
 	user.Password = ""
 }
 
-// ListUsers returns a set of users that match the query criteria.
-func ListUsers(db *bolt.DB, pageLimit uint32, term string, offset uint32) (*[]User, error) {
-	var target uint32
-	userList := []User{}
-	currUser := new(User)
-	err := db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(util.UserBucket)
-		cursor := bucket.Cursor()
-		// Adjust data size targets based on offset and page limit
-		if offset > 0 {
-			target = pageLimit * (offset + 1)
-		}
+// UserQuery describes the filters and sort fields of user list queries.
+var UserQuery = &util.QuerySchema{
+	Fields: []util.QueryField{
+		{Name: "email", Type: util.StringField, Sortable: true},
+		{Name: "role", Type: util.StringField, Options: []string{util.Admin, util.Management, util.Finance}},
+		{Name: "firstName", Type: util.StringField, Sortable: true},
+		{Name: "lastName", Type: util.StringField, Sortable: true},
+		{Name: "createdOn", Param: "created", Type: util.TimeField, Sortable: true},
+		{Name: "lastLogin", Type: util.TimeField, Sortable: true},
+		{Name: "lastModified", Param: "modified", Type: util.TimeField, Sortable: true},
+	},
+	Search: []string{"email", "role"},
+}
 
-		if offset == 0 {
-			target = pageLimit
-		}
+// QueryUsers returns the page of users matching the query. Deleted users are
+// left out, as is the admin when the query neither searches nor filters.
+func QueryUsers(db Store, pageLimit uint32, query *util.Query) (*[]User, error) {
+	userList := []User{}
+	err := queryBucket(db, util.UserBucket, pageLimit, query, &userList, func(entity interface{}) bool {
+		user := entity.(*User)
+		return !user.Deleted && (user.Role != util.Admin || !query.Unfiltered())
+	})
 
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			err := json.Unmarshal(v, currUser)
-			if err != nil {
-				return util.ErrMalformedJSON
-			}
-
-			if term != "" {
-				if (strings.Contains(strings.ToLower(currUser.Email), strings.ToLower(term)) ||
-					strings.Contains(strings.ToLower(currUser.Role), strings.ToLower(term))) && !currUser.Deleted {
-					currUser.Sanitize()
-					userList = append(userList, *currUser)
-					// Stop iterating when data target has been met.
-					if uint32(len(userList)) == target {
-						break
-					}
-				}
-			}
-
-			if term == "" {
-				if !currUser.Deleted && currUser.Role != util.Admin {
-					userList = append(userList, *currUser)
-					// Stop iterating when data target has been met.
-					if uint32(len(userList)) == target {
-						break
-					}
-				}
-			}
-		}
+	for idx := range userList {
+		userList[idx].Sanitize()
+	}
+	return &userList, err
+}
 
-		// Slice the relevant data according to the page limit and offset.
-		if offset > 0 {
-			userList = userList[(pageLimit * offset):]
-		}
+// ListUsers returns a set of users that match the query criteria.
+func ListUsers(db Store, pageLimit uint32, term string, offset uint32) (*[]User, error) {
+	return QueryUsers(db, pageLimit, UserQuery.NewQuery(term, offset))
+}
 
-		return nil
+// ListDeletedUsers returns a set of soft-deleted users.
+func ListDeletedUsers(db Store, pageLimit uint32, offset uint32) (*[]User, error) {
+	userList := []User{}
+	err := queryBucket(db, util.UserBucket, pageLimit, &util.Query{Offset: offset}, &userList, func(entity interface{}) bool {
+		return entity.(*User).Deleted
 	})
 
+	for idx := range userList {
+		userList[idx].Sanitize()
+	}
 	return &userList, err
 }
@@ -0,0 +1,293 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"einheit/boltkit/util"
+)
+
+// Webhook events.
+var (
+	InviteCreatedEvent    = "invite.created"
+	InviteAcceptedEvent   = "invite.accepted"
+	InviteCancelledEvent  = "invite.cancelled"
+	UserCreatedEvent      = "user.created"
+	FeedbackCreatedEvent  = "feedback.created"
+	FeedbackResolvedEvent = "feedback.resolved"
+	ResetRequestedEvent   = "reset.requested"
+)
+
+// WebhookEvents is the set of events a webhook can subscribe to.
+var WebhookEvents = []string{
+	InviteCreatedEvent,
+	InviteAcceptedEvent,
+	InviteCancelledEvent,
+	UserCreatedEvent,
+	FeedbackCreatedEvent,
+	FeedbackResolvedEvent,
+	ResetRequestedEvent,
+}
+
+// Delivery states.
+var (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook describes an outbound webhook subscription.
+type Webhook struct {
+	Uuid         string   `json:"uuid"`
+	URL          string   `json:"url"`
+	Events       []string `json:"events"`
+	Secret       string   `json:"secret,omitempty"`
+	LastModified int64    `json:"lastModified"`
+	ModifiedBy   string   `json:"modifiedBy"`
+	CreatedOn    int64    `json:"createdOn"`
+	Deleted      bool     `json:"deleted"`
+}
+
+// Delivery describes a single delivery of an event to a webhook. Deliveries
+// are queued as pending and retried with exponential backoff until they are
+// delivered or run out of attempts.
+type Delivery struct {
+	Uuid         string          `json:"uuid"`
+	Webhook      string          `json:"webhook"`
+	Event        string          `json:"event"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"`
+	Attempts     uint32          `json:"attempts"`
+	NextAttempt  int64           `json:"nextAttempt"`
+	ResponseCode int             `json:"responseCode"`
+	LastError    string          `json:"lastError"`
+	LastModified int64           `json:"lastModified"`
+	CreatedOn    int64           `json:"createdOn"`
+}
+
+// GetWebhook fetches the webhook associated with the provided id.
+func GetWebhook(id []byte, db Store) (*Webhook, error) {
+	webhook := new(Webhook)
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.WebhookBucket)
+		v := bucket.Get(id)
+		if v == nil {
+			return util.ErrKeyNotFound(string(id))
+		}
+
+		err := json.Unmarshal(v, webhook)
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
+	})
+	return webhook, err
+}
+
+// Update stores the most updated state of the webhook entity.
+func (webhook *Webhook) Update(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		webhookBytes, err := json.Marshal(webhook)
+		if err != nil {
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		err = putEntity(tx, util.WebhookBucket, []byte(webhook.Uuid), webhookBytes)
+		if err != nil {
+			return err
+		}
+
+		err = putChange(tx, util.WebhookBucket, []byte(webhook.Uuid), PutOperation, webhookBytes)
+		return err
+	})
+	return err
+}
+
+// Delete toggles the webhook entity's delete status. Deleted webhooks receive
+// no deliveries.
+func (webhook *Webhook) Delete(state bool, db Store) error {
+	webhook.Deleted = state
+	webhook.LastModified = time.Now().Unix()
+	return webhook.Update(db)
+}
+
+// Sanitize prepares the webhook entity to be sent a request response.
+// This removes all sensitive details from the entity.
+func (webhook *Webhook) Sanitize() {
+	webhook.Secret = ""
+}
+
+// Subscribed asserts the webhook is subscribed to the provided event.
+func (webhook *Webhook) Subscribed(event string) bool {
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ListWebhooks returns a set of webhooks that match the query criteria.
+func ListWebhooks(db Store, pageLimit uint32, term string, offset uint32) (*[]Webhook, error) {
+	term = strings.ToLower(term)
+	webhookList := []Webhook{}
+	err := queryBucket(db, util.WebhookBucket, pageLimit, &util.Query{Offset: offset}, &webhookList, func(entity interface{}) bool {
+		webhook := entity.(*Webhook)
+		return !webhook.Deleted && strings.Contains(strings.ToLower(webhook.URL), term)
+	})
+
+	for idx := range webhookList {
+		webhookList[idx].Sanitize()
+	}
+	return &webhookList, err
+}
+
+// ListSubscribedWebhooks returns all active webhooks subscribed to the
+// provided event.
+func ListSubscribedWebhooks(db Store, event string) (*[]Webhook, error) {
+	webhookList := []Webhook{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.WebhookBucket)
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			webhook := Webhook{}
+			err := json.Unmarshal(v, &webhook)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			if !webhook.Deleted && webhook.Subscribed(event) {
+				webhookList = append(webhookList, webhook)
+			}
+		}
+
+		return nil
+	})
+	return &webhookList, err
+}
+
+// GetDelivery fetches the delivery associated with the provided webhook and
+// delivery ids.
+func GetDelivery(webhookId []byte, id []byte, db Store) (*Delivery, error) {
+	delivery := new(Delivery)
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.DeliveryBucket).Bucket(webhookId)
+		if bucket == nil {
+			return util.ErrKeyNotFound(string(webhookId))
+		}
+
+		v := bucket.Get(id)
+		if v == nil {
+			return util.ErrKeyNotFound(string(id))
+		}
+
+		err := json.Unmarshal(v, delivery)
+		if err != nil {
+			return util.ErrStorage
+		}
+		return nil
+	})
+	return delivery, err
+}
+
+// Update stores the most updated state of the delivery. Deliveries are kept
+// in a bucket per webhook, which doubles as the webhook's delivery log.
+func (delivery *Delivery) Update(db Store) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(util.DeliveryBucket).CreateBucketIfNotExists([]byte(delivery.Webhook))
+		if err != nil {
+			return err
+		}
+
+		deliveryBytes, err := json.Marshal(delivery)
+		if err != nil {
+			logger(db).Error(util.ErrStorage)
+			return util.ErrStorage
+		}
+
+		err = bucket.Put([]byte(delivery.Uuid), deliveryBytes)
+		return err
+	})
+	return err
+}
+
+// ListDeliveries returns a page of the delivery log of the provided webhook,
+// oldest first.
+func ListDeliveries(db Store, pageLimit uint32, webhookId string, offset uint32) (*[]Delivery, error) {
+	var target uint32
+	deliveryList := []Delivery{}
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.DeliveryBucket).Bucket([]byte(webhookId))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		// Adjust data size targets based on offset and page limit
+		if offset > 0 {
+			target = pageLimit * (offset + 1)
+		}
+
+		if offset == 0 {
+			target = pageLimit
+		}
+
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			delivery := Delivery{}
+			err := json.Unmarshal(v, &delivery)
+			if err != nil {
+				return util.ErrStorage
+			}
+
+			deliveryList = append(deliveryList, delivery)
+			// Stop iterating when data target has been met.
+			if uint32(len(deliveryList)) == target {
+				break
+			}
+		}
+
+		// Slice the relevant data according to the page limit and offset.
+		start := int(pageLimit * offset)
+		if start > len(deliveryList) {
+			start = len(deliveryList)
+		}
+		deliveryList = deliveryList[start:]
+
+		return nil
+	})
+
+	return &deliveryList, err
+}
+
+// ListDueDeliveries returns all pending deliveries whose next attempt is due.
+func ListDueDeliveries(db Store) (*[]Delivery, error) {
+	deliveryList := []Delivery{}
+	now := time.Now().Unix()
+	err := db.View(func(tx *bolt.Tx) error {
+		deliveryBucket := tx.Bucket(util.DeliveryBucket)
+		return deliveryBucket.ForEach(func(webhookId []byte, _ []byte) error {
+			bucket := deliveryBucket.Bucket(webhookId)
+			if bucket == nil {
+				return nil
+			}
+
+			return bucket.ForEach(func(_ []byte, v []byte) error {
+				delivery := Delivery{}
+				err := json.Unmarshal(v, &delivery)
+				if err != nil {
+					return util.ErrStorage
+				}
+
+				if delivery.Status == DeliveryPending && delivery.NextAttempt <= now {
+					deliveryList = append(deliveryList, delivery)
+				}
+				return nil
+			})
+		})
+	})
+	return &deliveryList, err
+}
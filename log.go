@@ -19,69 +19,131 @@ import (
 	"einheit/boltkit/util"
 )
 
+// Log formats.
+const (
+	textLogFormat = "text"
+	jsonLogFormat = "json"
+)
+
+const (
+	// defaultLogFileSize is the size, in KB, the log file is rolled at when
+	// none is configured.
+	defaultLogFileSize = 10 * 1024
+
+	// defaultLogFiles is the number of rolled log files kept when none is
+	// configured.
+	defaultLogFiles = 3
+
+	// defaultLogLevelRevert is how long, in seconds, debug logging enabled
+	// with SIGUSR1 lasts when no duration is configured.
+	defaultLogLevelRevert = 15 * 60
+)
+
 // logWriter implements an io.Writer that outputs to both standard output and
 // the write-end pipe of an initialized log rotator.
 type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
 	os.Stdout.Write(p)
-	logRotator.Write(p)
+	// Messages logged before the rotator is initialized, while loading the
+	// configuration, only go to standard output.
+	if logRotator != nil {
+		logRotator.Write(p)
+	}
 	return len(p), nil
 }
 
+// logBackend creates subsystem loggers writing to a shared output, it is
+// implemented by *btclog.Backend and *util.JSONBackend.
+type logBackend interface {
+	Logger(subsystemTag string) btclog.Logger
+}
+
 // Loggers per subsystem.  A single backend logger is created and all subsytem
 // loggers created from it will write to the backend.  When adding new
-// subsystems, add the subsystem logger variable here and to the
-// subsystemLoggers map.
+// subsystems, add the subsystem logger variable here and to useLogBackend.
 //
 // Loggers can not be used before the log rotator has been initialized with a
 // log file.  This must be performed early during application startup by calling
 // initLogRotator.
 var (
-	// backendLog is the logging backend used to create all subsystem loggers.
-	// The backend must not be used before the log rotator has been initialized,
-	// or data races and/or nil pointer dereferences will occur.
-	backendLog = btclog.NewBackend(logWriter{})
-
 	// logRotator is one of the logging outputs.  It should be closed on
 	// application shutdown.
 	logRotator *rotator.Rotator
 
-	log          = backendLog.Logger("BTKT")
-	utilLog      = backendLog.Logger("UTIL")
-	serviceLog   = backendLog.Logger("SRVC")
-	schedulerLog = backendLog.Logger("SCDL")
-	entityLog    = backendLog.Logger("ENTY")
+	log          btclog.Logger
+	utilLog      btclog.Logger
+	serviceLog   btclog.Logger
+	schedulerLog btclog.Logger
+	entityLog    btclog.Logger
+
+	// subsystemLoggers maps each subsystem identifier to its associated
+	// logger.
+	subsystemLoggers map[string]btclog.Logger
 )
 
 // Initialize package-global logger variables.
 func init() {
+	useLogBackend(btclog.NewBackend(logWriter{}))
+}
+
+// useLogBackend creates the subsystem loggers from a backend and hands them
+// to their packages.
+func useLogBackend(backend logBackend) {
+	log = backend.Logger("BTKT")
+	utilLog = backend.Logger("UTIL")
+	serviceLog = backend.Logger("SRVC")
+	schedulerLog = backend.Logger("SCDL")
+	entityLog = backend.Logger("ENTY")
+
 	util.UseLogger(utilLog)
 	service.UseLogger(serviceLog)
 	entity.UseLogger(entityLog)
 	scheduler.UseLogger(schedulerLog)
+
+	subsystemLoggers = map[string]btclog.Logger{
+		"BTKT": log,
+		"UTIL": utilLog,
+		"SRVC": serviceLog,
+		"SCDL": schedulerLog,
+		"ENTY": entityLog,
+	}
 }
 
-// subsystemLoggers maps each subsystem identifier to its associated logger.
-var subsystemLoggers = map[string]btclog.Logger{
-	"BTKT": log,
-	"UTIL": utilLog,
-	"SRVC": serviceLog,
-	"SCDL": schedulerLog,
-	"ENTY": entityLog,
+// initLogFormat switches the subsystem loggers to the configured format,
+// text lines by default or json lines carrying the fields of the request
+// logged for.
+func initLogFormat(format string) error {
+	switch format {
+	case "", textLogFormat:
+		return nil
+	case jsonLogFormat:
+		useLogBackend(util.NewJSONBackend(logWriter{}))
+		return nil
+	}
+	return util.ErrInvalidParameterOption("logformat", format,
+		fmt.Sprintf("%s or %s", textLogFormat, jsonLogFormat))
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
-// create roll files in the same directory.  It must be called before the
-// package-global log rotater variables are used.
-func initLogRotator(logFile string) {
+// create roll files in the same directory.  Files are rolled once they reach
+// sizeKB and maxRolls of them are kept, the defaults are used when zero.  It
+// must be called before the package-global log rotater variables are used.
+func initLogRotator(logFile string, sizeKB int64, maxRolls int) {
+	if sizeKB <= 0 {
+		sizeKB = defaultLogFileSize
+	}
+	if maxRolls <= 0 {
+		maxRolls = defaultLogFiles
+	}
+
 	logDir, _ := filepath.Split(logFile)
 	err := os.MkdirAll(logDir, 0700)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create log directory: %v\n", err)
 		os.Exit(1)
 	}
-	r, err := rotator.New(logFile, 10*1024, false, 3)
+	r, err := rotator.New(logFile, sizeKB, false, maxRolls)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create file rotator: %v\n", err)
 		os.Exit(1)
@@ -90,30 +152,19 @@ func initLogRotator(logFile string) {
 	logRotator = r
 }
 
-// setLogLevel sets the logging level for provided subsystem.  Invalid
-// subsystems are ignored.  Uninitialized subsystems are dynamically created as
-// needed.
-func setLogLevel(subsystemID string, logLevel string) {
-	// Ignore invalid subsystems.
-	logger, ok := subsystemLoggers[subsystemID]
-	if !ok {
-		return
+// initLogLevels creates the runtime level control of the subsystem loggers,
+// starting at the provided level, info when empty.
+func initLogLevels(logLevel string) (*util.LogLevels, error) {
+	levels := util.NewLogLevels(subsystemLoggers)
+	if logLevel == "" {
+		return levels, nil
 	}
 
-	// Defaults to info if the log level is invalid.
-	level, _ := btclog.LevelFromString(logLevel)
-	logger.SetLevel(level)
-}
-
-// setLogLevels sets the log level for all subsystem loggers to the passed
-// level.  It also dynamically creates the subsystem loggers as needed, so it
-// can be used to initialize the logging system.
-func setLogLevels(logLevel string) {
-	// Configure all sub-systems with the new logging level.  Dynamically
-	// create loggers as needed.
-	for subsystemID := range subsystemLoggers {
-		setLogLevel(subsystemID, logLevel)
+	err := levels.Set("", logLevel, 0)
+	if err != nil {
+		return nil, err
 	}
+	return levels, nil
 }
 
 // fatalf logs a message, flushes the logger, and finally exit the process with
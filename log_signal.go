@@ -0,0 +1,35 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"einheit/boltkit/util"
+)
+
+// handleLogSignals toggles debug logging of every subsystem on SIGUSR1,
+// reverting after the provided duration unless toggled back. The levels of
+// the subsystems are logged on every signal.
+func handleLogSignals(levels *util.LogLevels, revertAfter time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	for range signals {
+		_, revertAt := levels.Levels()
+		if revertAt.IsZero() {
+			levels.Set("", "debug", revertAfter)
+			log.Infof("Debug logging enabled for %v.", revertAfter)
+		} else {
+			levels.Revert()
+			log.Info("Debug logging disabled.")
+		}
+
+		current, _ := levels.Levels()
+		for _, subsystem := range levels.Subsystems() {
+			log.Infof("Log level of %s: %s", subsystem, current[subsystem])
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import (
+	"time"
+
+	"einheit/boltkit/util"
+)
+
+// handleLogSignals does nothing, SIGUSR1 is not available on windows where
+// log levels are only set over the api.
+func handleLogSignals(levels *util.LogLevels, revertAfter time.Duration) {}
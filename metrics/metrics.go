@@ -0,0 +1,299 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of latency histograms, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry metrics are created in.
+var Default = NewRegistry()
+
+// Collector is a metric family written in the prometheus text format.
+type Collector interface {
+	Name() string
+	Write(writer io.Writer)
+}
+
+// Registry holds the collectors exposed together.
+type Registry struct {
+	mtx        sync.Mutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]Collector{}}
+}
+
+// Register adds a collector, replacing the collector of the same name.
+func (registry *Registry) Register(collector Collector) {
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+	registry.collectors[collector.Name()] = collector
+}
+
+// WriteText writes every collector in the prometheus text format, sorted by
+// name.
+func (registry *Registry) WriteText(writer io.Writer) error {
+	registry.mtx.Lock()
+	names := make([]string, 0, len(registry.collectors))
+	for name := range registry.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, registry.collectors[name])
+	}
+	registry.mtx.Unlock()
+
+	buffered := bufio.NewWriter(writer)
+	for _, collector := range collectors {
+		collector.Write(buffered)
+	}
+	return buffered.Flush()
+}
+
+// family holds the series of a metric, keyed by their label values.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mtx    sync.Mutex
+	series map[string][]string
+}
+
+// Name returns the name of the metric.
+func (family *family) Name() string {
+	return family.name
+}
+
+// key returns the series key of label values, panicking on a label count
+// mismatch as it is a programming error.
+func (family *family) key(values []string) string {
+	if len(values) != len(family.labels) {
+		panic(fmt.Sprintf("metric %s expects %d labels, got %d", family.name, len(family.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// keys returns the sorted series keys, the family must be locked.
+func (family *family) keys() []string {
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeHeader writes the help and type lines of the metric.
+func (family *family) writeHeader(writer io.Writer) {
+	fmt.Fprintf(writer, "# HELP %s %s\n", family.name, escapeHelp(family.help))
+	fmt.Fprintf(writer, "# TYPE %s %s\n", family.name, family.kind)
+}
+
+// labelPairs formats label values, along with an extra label if any.
+func (family *family) labelPairs(values []string, extra ...string) string {
+	pairs := []string{}
+	for idx, label := range family.labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, label, escapeLabel(values[idx])))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[0], escapeLabel(extra[1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a metric that only goes up, partitioned by labels.
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+// NewCounter creates a counter registered in the default registry.
+func NewCounter(name string, help string, labels ...string) *Counter {
+	counter := &Counter{
+		family: family{name: name, help: help, kind: "counter", labels: labels, series: map[string][]string{}},
+		values: map[string]float64{},
+	}
+	Default.Register(counter)
+	return counter
+}
+
+// Inc increments the series of the provided label values.
+func (counter *Counter) Inc(values ...string) {
+	counter.Add(1, values...)
+}
+
+// Add adds a non-negative amount to the series of the provided label values.
+func (counter *Counter) Add(amount float64, values ...string) {
+	if amount < 0 {
+		return
+	}
+
+	key := counter.key(values)
+	counter.mtx.Lock()
+	defer counter.mtx.Unlock()
+	if _, ok := counter.series[key]; !ok {
+		counter.series[key] = append([]string{}, values...)
+	}
+	counter.values[key] += amount
+}
+
+// Value returns the value of the series of the provided label values.
+func (counter *Counter) Value(values ...string) float64 {
+	key := counter.key(values)
+	counter.mtx.Lock()
+	defer counter.mtx.Unlock()
+	return counter.values[key]
+}
+
+// Write writes the counter in the prometheus text format.
+func (counter *Counter) Write(writer io.Writer) {
+	counter.mtx.Lock()
+	defer counter.mtx.Unlock()
+
+	counter.writeHeader(writer)
+	for _, key := range counter.keys() {
+		fmt.Fprintf(writer, "%s%s %s\n", counter.name, counter.labelPairs(counter.series[key]),
+			formatValue(counter.values[key]))
+	}
+}
+
+// Histogram counts observations in buckets, partitioned by labels.
+type Histogram struct {
+	family
+	buckets []float64
+	counts  map[string][]uint64
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+// NewHistogram creates a histogram with the provided bucket upper bounds,
+// registered in the default registry.
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{
+		family:  family{name: name, help: help, kind: "histogram", labels: labels, series: map[string][]string{}},
+		buckets: buckets,
+		counts:  map[string][]uint64{},
+		sums:    map[string]float64{},
+		totals:  map[string]uint64{},
+	}
+	Default.Register(histogram)
+	return histogram
+}
+
+// Observe records an observation in the series of the provided label values.
+func (histogram *Histogram) Observe(value float64, values ...string) {
+	key := histogram.key(values)
+	histogram.mtx.Lock()
+	defer histogram.mtx.Unlock()
+
+	counts, ok := histogram.counts[key]
+	if !ok {
+		counts = make([]uint64, len(histogram.buckets))
+		histogram.counts[key] = counts
+		histogram.series[key] = append([]string{}, values...)
+	}
+
+	for idx, bound := range histogram.buckets {
+		if value <= bound {
+			counts[idx]++
+		}
+	}
+	histogram.sums[key] += value
+	histogram.totals[key]++
+}
+
+// Count returns the number of observations of the series of the provided
+// label values.
+func (histogram *Histogram) Count(values ...string) uint64 {
+	key := histogram.key(values)
+	histogram.mtx.Lock()
+	defer histogram.mtx.Unlock()
+	return histogram.totals[key]
+}
+
+// Write writes the histogram in the prometheus text format.
+func (histogram *Histogram) Write(writer io.Writer) {
+	histogram.mtx.Lock()
+	defer histogram.mtx.Unlock()
+
+	histogram.writeHeader(writer)
+	for _, key := range histogram.keys() {
+		values := histogram.series[key]
+		for idx, bound := range histogram.buckets {
+			fmt.Fprintf(writer, "%s_bucket%s %d\n", histogram.name,
+				histogram.labelPairs(values, "le", formatValue(bound)), histogram.counts[key][idx])
+		}
+		fmt.Fprintf(writer, "%s_bucket%s %d\n", histogram.name,
+			histogram.labelPairs(values, "le", "+Inf"), histogram.totals[key])
+		fmt.Fprintf(writer, "%s_sum%s %s\n", histogram.name, histogram.labelPairs(values),
+			formatValue(histogram.sums[key]))
+		fmt.Fprintf(writer, "%s_count%s %d\n", histogram.name, histogram.labelPairs(values),
+			histogram.totals[key])
+	}
+}
+
+// Gauge is a metric read when written, from a function returning its value.
+type Gauge struct {
+	family
+	value func() float64
+}
+
+// NewGauge creates a gauge registered in the default registry, replacing
+// the gauge of the same name.
+func NewGauge(name string, help string, value func() float64) *Gauge {
+	gauge := &Gauge{
+		family: family{name: name, help: help, kind: "gauge"},
+		value:  value,
+	}
+	Default.Register(gauge)
+	return gauge
+}
+
+// Write writes the gauge in the prometheus text format.
+func (gauge *Gauge) Write(writer io.Writer) {
+	gauge.writeHeader(writer)
+	fmt.Fprintf(writer, "%s %s\n", gauge.name, formatValue(gauge.value()))
+}
+
+// formatValue formats a sample value.
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeHelp escapes the backslashes and line feeds of a help text.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabel escapes the backslashes, quotes and line feeds of a label
+// value.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -1,13 +1,17 @@
 package scheduler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/robfig/cron"
 
 	"einheit/boltkit/entity"
+	"einheit/boltkit/metrics"
 	"einheit/boltkit/service"
 	"einheit/boltkit/util"
 )
@@ -17,10 +21,42 @@ var (
 	AppScheduler *Scheduler
 )
 
+const (
+	// defaultTrashRetention is how long, in days, soft-deleted users and
+	// invites are kept for when no retention is configured.
+	defaultTrashRetention = 30
+
+	// defaultChangeRetention is how long, in days, changes are kept in the
+	// change feed when no retention is configured.
+	defaultChangeRetention = 7
+)
+
+// jobs are the recurring jobs, keyed by name.
+var jobs = map[string]func(app *service.Service) error{
+	util.InviteJob:      ExpiredInvites,
+	util.PassResetJob:   ExpiredPassReset,
+	util.PurgeJob:       PurgeDeleted,
+	util.WebhookJob:     PendingDeliveries,
+	util.IdempotencyJob: ExpiredIdempotencyRecords,
+	util.CompactionJob:  CompactChanges,
+}
+
+// Metrics of the recurring jobs.
+var (
+	jobRuns = metrics.NewCounter("boltkit_job_runs_total",
+		"Recurring job runs, by job.", "job")
+	jobFailures = metrics.NewCounter("boltkit_job_failures_total",
+		"Recurring job runs that failed, by job.", "job")
+	jobDuration = metrics.NewHistogram("boltkit_job_duration_seconds",
+		"Duration of recurring job runs, by job.", metrics.DefaultBuckets, "job")
+)
+
 // Scheduler represents a background job scheduler.
 type Scheduler struct {
-	Ch   chan string
-	Cron *cron.Cron
+	Ch         chan string
+	Cron       *cron.Cron
+	scheduled  int32
+	processing int32
 }
 
 // NewScheduler creates a background task scheduler
@@ -42,27 +78,66 @@ func (scheduler *Scheduler) Schedule(app *service.Service) {
 	scheduler.Cron.AddFunc("0 0 20 * * *", func() { scheduler.Send(util.InviteJob) })
 	// Scheduled to run at 9pm each day.
 	scheduler.Cron.AddFunc("0 0 21 * * *", func() { scheduler.Send(util.PassResetJob) })
+	// Scheduled to run at 10pm each day.
+	scheduler.Cron.AddFunc("0 0 22 * * *", func() { scheduler.Send(util.PurgeJob) })
+	// Scheduled to run every 30 seconds.
+	scheduler.Cron.AddFunc("*/30 * * * * *", func() { scheduler.Send(util.WebhookJob) })
+	// Scheduled to run at the start of every hour.
+	scheduler.Cron.AddFunc("0 0 * * * *", func() { scheduler.Send(util.IdempotencyJob) })
+	// Scheduled to run at 11pm each day.
+	scheduler.Cron.AddFunc("0 0 23 * * *", func() { scheduler.Send(util.CompactionJob) })
+	scheduler.Cron.Start()
+	atomic.StoreInt32(&scheduler.scheduled, 1)
 
 	log.Info("Scheduled recurring jobs.")
 }
 
+// Check asserts recurring jobs are scheduled and processed, it is the
+// readiness check of the scheduler.
+func (scheduler *Scheduler) Check(ctx context.Context) error {
+	if atomic.LoadInt32(&scheduler.scheduled) == 0 {
+		return errors.New("jobs are not scheduled")
+	}
+
+	if atomic.LoadInt32(&scheduler.processing) == 0 {
+		return errors.New("jobs are not processed")
+	}
+	return nil
+}
+
 // Process receives and executes the posted job.
 func (scheduler *Scheduler) Process(app *service.Service) {
+	atomic.StoreInt32(&scheduler.processing, 1)
+	defer atomic.StoreInt32(&scheduler.processing, 0)
+
 	for {
 		job := <-scheduler.Ch
-		switch job {
-		case util.InviteJob:
-			ExpiredInvites(app)
-		case util.InviteJob:
-			ExpiredPassReset(app)
-		default:
+		// Jobs write to storage, a read-only replica leaves them to its
+		// primary.
+		if app.ReadOnly() {
+			continue
+		}
+
+		run, ok := jobs[job]
+		if !ok {
 			log.Error("unknown job received: ", job)
+			continue
+		}
+
+		start := time.Now()
+		err := run(app)
+		jobDuration.Observe(time.Since(start).Seconds(), job)
+		jobRuns.Inc(job)
+		if err != nil {
+			jobFailures.Inc(job)
+			log.Errorf("%s job failed: %v", job, err)
 		}
 	}
 }
 
-// ExpiredInvites removes expired invitations from storage.
-func ExpiredInvites(app *service.Service) {
+// ExpiredInvites removes expired invitations from storage. Failing purges do
+// not stop the job, the first failure is returned.
+func ExpiredInvites(app *service.Service) error {
 	expiredInvites := []entity.Invite{}
 	err := app.Bolt.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.InviteBucket)
@@ -85,29 +160,21 @@ func ExpiredInvites(app *service.Service) {
 	})
 
 	if err != nil {
-		log.Error("expired invites job failed: ", err)
+		return err
 	}
 
-	if len(expiredInvites) > 0 {
-		err = app.Bolt.Update(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(util.InviteBucket)
-			for _, invite := range expiredInvites {
-				err := bucket.Delete([]byte(invite.Uuid))
-				if err != nil {
-					return err
-				}
-			}
-
-			return nil
-		})
-		if err != nil {
-			log.Error("expired invites job failed: ", err)
+	for _, invite := range expiredInvites {
+		purgeErr := invite.Purge(app.Bolt)
+		if purgeErr != nil && err == nil {
+			err = purgeErr
 		}
 	}
+	return err
 }
 
-// ExpiredPassReset removes expired password resets from storage.
-func ExpiredPassReset(app *service.Service) {
+// ExpiredPassReset removes expired password resets from storage. Failing
+// purges do not stop the job, the first failure is returned.
+func ExpiredPassReset(app *service.Service) error {
 	expiredResets := []entity.PassReset{}
 	err := app.Bolt.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.PassResetBucket)
@@ -130,23 +197,127 @@ func ExpiredPassReset(app *service.Service) {
 	})
 
 	if err != nil {
-		log.Error("expired password resets job failed: ", err)
+		return err
+	}
+
+	for _, reset := range expiredResets {
+		purgeErr := reset.Purge(app.Bolt)
+		if purgeErr != nil && err == nil {
+			err = purgeErr
+		}
+	}
+	return err
+}
+
+// PurgeDeleted permanently removes users and invites that have been
+// soft-deleted for longer than the configured trash retention period.
+// Records deleted before deletion times were kept are aged by their last
+// modification.
+func PurgeDeleted(app *service.Service) error {
+	retention := app.Cfg.TrashRetention
+	if retention == 0 {
+		retention = defaultTrashRetention
 	}
 
-	if len(expiredResets) > 0 {
-		err = app.Bolt.Update(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(util.PassResetBucket)
-			for _, reset := range expiredResets {
-				err := bucket.Delete([]byte(reset.Uuid))
-				if err != nil {
-					return err
-				}
+	cutoff := util.GetPastTime(time.Now(), time.Duration(retention), 0, 0, 0).Unix()
+	expiredUsers := []entity.User{}
+	expiredInvites := []entity.Invite{}
+	err := app.Bolt.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.UserBucket)
+		cursor := bucket.Cursor()
+		user := new(entity.User)
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			err := json.Unmarshal(v, user)
+			if err != nil {
+				return err
+			}
+
+			// Only load users deleted before the retention cutoff.
+			if user.Deleted && deletedOn(user.DeletedOn, user.LastModified) < cutoff {
+				expiredUsers = append(expiredUsers, *user)
 			}
+		}
 
-			return nil
-		})
+		bucket = tx.Bucket(util.InviteBucket)
+		cursor = bucket.Cursor()
+		invite := new(entity.Invite)
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			err := json.Unmarshal(v, invite)
+			if err != nil {
+				return err
+			}
+
+			// Only load invites deleted before the retention cutoff.
+			if invite.Deleted && deletedOn(invite.DeletedOn, invite.LastModified) < cutoff {
+				expiredInvites = append(expiredInvites, *invite)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, user := range expiredUsers {
+		purgeErr := user.Purge(app.Bolt)
+		if purgeErr != nil && err == nil {
+			err = purgeErr
+		}
+	}
+
+	for _, invite := range expiredInvites {
+		purgeErr := invite.Purge(app.Bolt)
+		if purgeErr != nil && err == nil {
+			err = purgeErr
+		}
+	}
+	return err
+}
+
+// deletedOn returns the time a record was soft-deleted at, its last
+// modification when the deletion time is unknown.
+func deletedOn(deleted int64, lastModified int64) int64 {
+	if deleted == 0 {
+		return lastModified
+	}
+	return deleted
+}
+
+// PendingDeliveries attempts all webhook deliveries that are due. Failed
+// deliveries are rescheduled rather than failing the job.
+func PendingDeliveries(app *service.Service) error {
+	deliveries, err := entity.ListDueDeliveries(app.Bolt)
+	if err != nil {
+		return err
+	}
+
+	for idx := range *deliveries {
+		delivery := &(*deliveries)[idx]
+		err = app.Deliver(delivery)
 		if err != nil {
-			log.Error("expired password resets job failed: ", err)
+			log.Errorf("webhook delivery %s attempt %d failed: %v",
+				delivery.Uuid, delivery.Attempts, err)
 		}
 	}
+	return nil
+}
+
+// ExpiredIdempotencyRecords removes expired idempotency records from storage.
+func ExpiredIdempotencyRecords(app *service.Service) error {
+	return entity.PurgeExpiredIdempotencyRecords(time.Now().Unix(), app.Bolt)
+}
+
+// CompactChanges removes changes older than the configured change retention
+// period from the change feed. Replicas and consumers further behind can no
+// longer resume and have to start again from a snapshot.
+func CompactChanges(app *service.Service) error {
+	retention := app.Cfg.ChangeRetention
+	if retention == 0 {
+		retention = defaultChangeRetention
+	}
+
+	cutoff := util.GetPastTime(time.Now(), time.Duration(retention), 0, 0, 0).Unix()
+	return entity.CompactChanges(app.Bolt, cutoff)
 }
@@ -4,24 +4,63 @@ import (
 	"context"
 	"einheit/boltkit/scheduler"
 	"einheit/boltkit/service"
+	"einheit/boltkit/trace"
+	"einheit/boltkit/util"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/handlers"
 )
 
+// startupTimeout is how long the critical dependencies are checked for at
+// startup.
+const startupTimeout = 10 * time.Second
+
 func main() {
-	var err error
+	// Load the configuration, logging is set up from it.
+	cfg, err := util.NewConfig("config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize log rotation.
-	initLogRotator(filepath.Join("log", "server.log"))
+	initLogRotator(filepath.Join("log", "server.log"), cfg.LogFileSize, cfg.LogFiles)
+	err = initLogFormat(cfg.LogFormat)
+	if err != nil {
+		fatalf("Failed to start: %v", err)
+	}
+
+	// Control the log levels at runtime.
+	logLevels, err := initLogLevels(cfg.LogLevel)
+	if err != nil {
+		fatalf("Failed to start: %v", err)
+	}
+
+	revertAfter := cfg.LogLevelRevert
+	if revertAfter == 0 {
+		revertAfter = defaultLogLevelRevert
+	}
+	go handleLogSignals(logLevels, time.Duration(revertAfter)*time.Second)
 
 	// Initialize application.
-	service.App, err = service.NewService("config.json")
+	service.App, err = service.NewServiceFromConfig(cfg)
 	if err != nil {
-		log.Error(err)
+		fatalf("Failed to start: %v", err)
+	}
+	service.App.LogLevels = logLevels
+
+	// Fail fast when a critical dependency is down.
+	ctx, cancel := context.WithTimeout(context.Background(), startupTimeout)
+	err = service.App.CheckStartup(ctx)
+	cancel()
+	if err != nil {
+		fatalf("Failed to start: %v", err)
 	}
 
 	// Initialize the http server.
@@ -30,41 +69,89 @@ func main() {
 		Addr:    service.App.Cfg.Port,
 		Handler: handlers.CORS()(service.App.Router),
 	}
+	// End the event streams, which never go idle, once shutting down.
+	service.Server.RegisterOnShutdown(service.App.Events.Close)
 
 	// Initialize the job scheduler.
 	scheduler.AppScheduler = scheduler.NewScheduler()
 	scheduler.AppScheduler.Schedule(service.App)
+	go scheduler.AppScheduler.Process(service.App)
+	service.App.AddCheck("scheduler", true, scheduler.AppScheduler.Check)
 
+	// Follow the primary when running as a replica.
+	if service.App.Replica != nil {
+		go service.App.Follow()
+	}
+
+	// Shut down on an interrupt or when either server fails.
+	shutdown := make(chan struct{}, 2)
 	idleConnsClosed := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt)
-		<-sigint
+		select {
+		case <-sigint:
+		case <-shutdown:
+		}
+
+		// Stop accepting requests before tearing down the service.
+		if err := service.Server.Shutdown(context.Background()); err != nil {
+			log.Errorf("Failed to shutdown server: %v", err)
+		}
+		if service.App.Cfg.GRPCPort != "" {
+			service.App.RPC.GracefulStop()
+		}
 
 		// Teardown service.
 		// Save all sessions to the session store before shutdown.
 		service.App.SaveSessions()
+		if service.App.Cfg.PersistRateLimits {
+			service.App.SaveRateLimits()
+		}
 		service.App.Bolt.Close()
+		trace.Default.Shutdown()
 		log.Info("Shutdown complete.")
-
-		// Received an interrupt signal, shut down.
-		if err := service.Server.Shutdown(context.Background()); err != nil {
-			log.Errorf("Failed to shutdown server: %v", err)
-		}
 		close(idleConnsClosed)
 	}()
 
-	// Start the server.
+	// Start the servers.
+	if service.App.Cfg.GRPCPort != "" {
+		log.Infof("Starting %s grpc on port %s", service.App.Cfg.Server, service.App.Cfg.GRPCPort)
+		go serveRPC(service.App.Cfg.GRPCPort, shutdown)
+	}
+
 	log.Infof("Starting %s on port %s", service.App.Cfg.Server, service.App.Cfg.Port)
-	if service.App.Cfg.HTTPS {
-		if err := service.Server.ListenAndServeTLS("cert.pem", "privkey.pem"); err != http.ErrServerClosed {
-			log.Error(err)
-		}
+	serve(&service.Server, service.App.Cfg.HTTPS, shutdown)
+
+	<-idleConnsClosed
+}
+
+// serve runs a server until it is shut down, signalling shutdown when it
+// fails.
+func serve(server *http.Server, https bool, shutdown chan<- struct{}) {
+	var err error
+	if https {
+		err = server.ListenAndServeTLS("cert.pem", "privkey.pem")
 	} else {
-		if err := service.Server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Error(err)
-		}
+		err = server.ListenAndServe()
 	}
 
-	<-idleConnsClosed
+	if err != http.ErrServerClosed {
+		log.Error(err)
+		shutdown <- struct{}{}
+	}
+}
+
+// serveRPC runs the grpc server, served over tls when https is enabled,
+// until it is stopped, signalling shutdown when it fails.
+func serveRPC(addr string, shutdown chan<- struct{}) {
+	listener, err := net.Listen("tcp", addr)
+	if err == nil {
+		err = service.App.RPC.Serve(listener)
+	}
+
+	if err != nil {
+		log.Error(err)
+		shutdown <- struct{}{}
+	}
 }
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+)
+
+// atomicRoutes are the routes an atomic batch can run, their handlers read
+// and write through the store of the request and defer side effects until
+// the batch commits.
+var atomicRoutes = map[string]bool{
+	"POST /invites":        true,
+	"PUT /invites/{id}":    true,
+	"DELETE /invites/{id}": true,
+	"PUT /users/{id}":      true,
+	"PUT /users/{id}/role": true,
+	"DELETE /users/{id}":   true,
+}
+
+// errBatchAborted rolls back the transaction of an atomic batch.
+var errBatchAborted = errors.New("batch aborted")
+
+func CreateBatchRoutes(router *mux.Router) {
+	router.HandleFunc("/batch", App.Authorize(App.Batch, util.Management, util.Finance)).Methods(http.MethodPost)
+}
+
+// batchRequest is the payload of a batch of sub-requests.
+type batchRequest struct {
+	Atomic   bool        `json:"atomic"`
+	Requests []batchItem `json:"requests" validate:"required,nonempty,max=100"`
+}
+
+// batchItem is a sub-request of a batch, its path includes the api version.
+type batchItem struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// batchResult is the response to a sub-request of a batch.
+type batchResult struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// batchResponse holds the responses to the sub-requests of a batch, in
+// order. The writes of an atomic batch are discarded unless committed.
+type batchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchScope is the state shared by the sub-requests of a batch.
+type batchScope struct {
+	// store is the transaction of an atomic batch.
+	store    entity.Store
+	deferred []func()
+}
+
+// Batch runs a list of sub-requests against the router, with the session of
+// the batch. Sub-requests of an atomic batch run in a single transaction
+// which is rolled back when any of them fails, the sub-requests following the
+// failure are not run and respond with status 424.
+func (service *Service) Batch(writer http.ResponseWriter, req *http.Request) {
+	payload := batchRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	err = service.validateBatch(payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	scope := &batchScope{}
+	response := batchResponse{Results: make([]batchResult, 0, len(payload.Requests))}
+	if !payload.Atomic {
+		ctx := context.WithValue(req.Context(), batchKey, scope)
+		for _, item := range payload.Requests {
+			response.Results = append(response.Results, service.dispatch(ctx, req, item))
+		}
+		response.Committed = true
+		util.RespondWithJSON(writer, http.StatusOK, response)
+		return
+	}
+
+	err = service.Bolt.Update(func(tx *bolt.Tx) error {
+		scope.store = entity.TxStore{Tx: tx}
+		ctx := context.WithValue(req.Context(), batchKey, scope)
+		for _, item := range payload.Requests {
+			result := service.dispatch(ctx, req, item)
+			response.Results = append(response.Results, result)
+			if result.Status >= http.StatusBadRequest {
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errBatchAborted {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	response.Committed = err == nil
+	if response.Committed {
+		for _, fn := range scope.deferred {
+			fn()
+		}
+	}
+
+	for idx := len(response.Results); idx < len(payload.Requests); idx++ {
+		response.Results = append(response.Results, batchResult{
+			ID:     payload.Requests[idx].ID,
+			Status: http.StatusFailedDependency,
+		})
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, response)
+	return
+}
+
+// validateBatch asserts every sub-request of a batch can be run, routes of an
+// atomic batch must be one of the atomic routes.
+func (service *Service) validateBatch(payload batchRequest) error {
+	errs := util.ValidationErrors{}
+	for idx, item := range payload.Requests {
+		field := fmt.Sprintf("requests[%d]", idx)
+		switch item.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+		default:
+			errs = append(errs, util.FieldError{Field: field + ".method", Message: "must be one of 'GET, POST, PUT, DELETE'"})
+			continue
+		}
+
+		sub, err := http.NewRequest(item.Method, item.Path, nil)
+		if err != nil || !strings.HasPrefix(item.Path, "/") {
+			errs = append(errs, util.FieldError{Field: field + ".path", Message: "must be an absolute path"})
+			continue
+		}
+
+		match := mux.RouteMatch{}
+		if !service.Router.Match(sub, &match) || match.Route == nil {
+			errs = append(errs, util.FieldError{Field: field + ".path", Message: "must be a route of the api"})
+			continue
+		}
+
+		template, err := match.Route.GetPathTemplate()
+		if err != nil {
+			errs = append(errs, util.FieldError{Field: field + ".path", Message: "must be a route of the api"})
+			continue
+		}
+
+		route := fmt.Sprint(item.Method, " ", unversioned(template))
+		if route == "POST /batch" {
+			errs = append(errs, util.FieldError{Field: field + ".path", Message: "must not be a batch"})
+			continue
+		}
+
+		if payload.Atomic && !atomicRoutes[route] {
+			errs = append(errs, util.FieldError{Field: field + ".path", Message: "is not supported by atomic batches"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// dispatch runs a sub-request of a batch against the router, with the
+// credentials of the batch.
+func (service *Service) dispatch(ctx context.Context, batch *http.Request, item batchItem) batchResult {
+	sub, err := http.NewRequest(item.Method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		return batchResult{ID: item.ID, Status: http.StatusBadRequest}
+	}
+
+	sub = sub.WithContext(ctx)
+	sub.RemoteAddr = batch.RemoteAddr
+	for _, header := range []string{"Authorization", "X-Forwarded-For"} {
+		if value := batch.Header.Get(header); value != "" {
+			sub.Header.Set(header, value)
+		}
+	}
+	sub.Header.Set("Content-Type", "application/json")
+
+	status, body := service.roundTrip(sub)
+	result := batchResult{ID: item.ID, Status: status}
+	if json.Valid(body) {
+		result.Body = body
+	}
+	return result
+}
+
+// roundTrip runs a request against the router in process, returning the
+// response status and body.
+func (service *Service) roundTrip(req *http.Request) (int, []byte) {
+	recorder := &bodyRecorder{ResponseWriter: &discardWriter{header: http.Header{}}, status: http.StatusOK}
+	service.Router.ServeHTTP(recorder, req)
+	return recorder.status, recorder.body.Bytes()
+}
+
+// store returns the storage a request reads and writes through, the
+// transaction of the atomic batch the request is part of if any. Requests
+// outside a batch run traced transactions.
+func (service *Service) store(req *http.Request) entity.Store {
+	return service.storeOf(req.Context())
+}
+
+// storeOf returns the storage of the request a context belongs to, as store
+// does, for code handed the context rather than the request.
+func (service *Service) storeOf(ctx context.Context) entity.Store {
+	scope, ok := ctx.Value(batchKey).(*batchScope)
+	if ok && scope.store != nil {
+		return scope.store
+	}
+	return service.Bolt.WithContext(ctx)
+}
+
+// afterCommit runs a side effect of a request, such as a notification, once
+// its writes are stored. Side effects of an atomic batch are deferred until
+// the batch commits and dropped when it is rolled back.
+func (service *Service) afterCommit(req *http.Request, fn func()) {
+	scope, ok := req.Context().Value(batchKey).(*batchScope)
+	if ok && scope.store != nil {
+		scope.deferred = append(scope.deferred, fn)
+		return
+	}
+	fn()
+}
+
+// discardWriter is the response writer of a sub-request, its response is
+// captured by a bodyRecorder.
+type discardWriter struct {
+	header http.Header
+}
+
+// Header returns the response headers.
+func (writer *discardWriter) Header() http.Header {
+	return writer.header
+}
+
+// Write discards the response body.
+func (writer *discardWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+// WriteHeader discards the response status.
+func (writer *discardWriter) WriteHeader(status int) {}
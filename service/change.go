@@ -0,0 +1,195 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxChangeWait is the longest a long-poll for changes is held open.
+	maxChangeWait = time.Second * 60
+)
+
+func CreateChangeRoutes(router *mux.Router) {
+	router.HandleFunc("/changes", App.Authorize(App.ListChanges, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/changes/stream", App.Authorize(App.StreamChanges, util.Admin)).Methods(http.MethodGet)
+}
+
+// ListChanges responds with the changes committed after the requested
+// sequence number. When there are none and a wait is requested the request
+// is held open until a change is committed or the wait elapses.
+func (service *Service) ListChanges(writer http.ResponseWriter, req *http.Request) {
+	after, limit, err := service.readChangeQuery(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	wait, err := readChangeWait(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	changes, err := service.awaitChanges(req, after, limit, wait)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	last := after
+	for idx := range *changes {
+		(*changes)[idx].Sanitize()
+		last = (*changes)[idx].Sequence
+	}
+
+	meta := map[string]interface{}{}
+	meta["count"] = len(*changes)
+	meta["after"] = after
+	meta["last"] = last
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = changes
+	util.RespondWithJSON(writer, http.StatusOK, response)
+	return
+}
+
+// StreamChanges streams changes committed after the requested sequence
+// number as newline delimited JSON, until the client disconnects. A consumer
+// resumes by reconnecting with the sequence of the last change it processed.
+func (service *Service) StreamChanges(writer http.ResponseWriter, req *http.Request) {
+	after, limit, err := service.readChangeQuery(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		util.RespondWithError(writer, util.ErrStreamingUnsupported)
+		return
+	}
+
+	// Refuse resuming from compacted changes before the stream is started,
+	// the error can not be responded with after.
+	compacted, err := entity.CompactedSequence(service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if after < compacted {
+		util.RespondWithError(writer, util.ErrChangesCompacted)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/x-ndjson")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(writer)
+	for {
+		signal := entity.ChangeSignal()
+		changes, err := entity.ListChanges(service.Bolt, after, limit)
+		if err != nil {
+			requestLog(req).Errorf("failed to stream changes: %v", err)
+			return
+		}
+
+		for idx := range *changes {
+			change := (*changes)[idx]
+			change.Sanitize()
+			err = encoder.Encode(change)
+			if err != nil {
+				return
+			}
+			after = change.Sequence
+		}
+		flusher.Flush()
+
+		// Keep reading while there is a backlog to drain.
+		if uint32(len(*changes)) == limit {
+			continue
+		}
+
+		select {
+		case <-signal:
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// awaitChanges fetches the changes committed after the provided sequence.
+// When there are none it waits up to the provided duration for a change to
+// be committed.
+func (service *Service) awaitChanges(req *http.Request, after uint64, limit uint32, wait time.Duration) (*[]entity.Change, error) {
+	// Fetch the signal before listing so a change committed in between
+	// is not missed.
+	signal := entity.ChangeSignal()
+	changes, err := entity.ListChanges(service.Bolt, after, limit)
+	if err != nil || len(*changes) > 0 || wait == 0 {
+		return changes, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-signal:
+		return entity.ListChanges(service.Bolt, after, limit)
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+
+	return changes, nil
+}
+
+// readChangeWait reads how long a change feed request waits for changes from
+// its query parameters.
+func readChangeWait(req *http.Request) (time.Duration, error) {
+	if req.FormValue("wait") == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.ParseUint(req.FormValue("wait"), 10, 32)
+	if err != nil {
+		return 0, util.ErrInvalidParameter("wait")
+	}
+
+	wait := time.Second * time.Duration(seconds)
+	if wait > maxChangeWait {
+		wait = maxChangeWait
+	}
+
+	return wait, nil
+}
+
+// readChangeQuery reads the resume sequence and page size of a change feed
+// request from its query parameters.
+func (service *Service) readChangeQuery(req *http.Request) (uint64, uint32, error) {
+	var after uint64
+	var err error
+	if req.FormValue("after") != "" {
+		after, err = strconv.ParseUint(req.FormValue("after"), 10, 64)
+		if err != nil {
+			return 0, 0, util.ErrInvalidParameter("after")
+		}
+	}
+
+	limit := service.Cfg.PageLimit
+	if req.FormValue("limit") != "" {
+		value, err := strconv.ParseUint(req.FormValue("limit"), 10, 32)
+		if err != nil || value == 0 {
+			return 0, 0, util.ErrInvalidParameter("limit")
+		}
+		limit = uint32(value)
+	}
+
+	return after, limit, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultEventHistory is the number of past events kept for resuming
+	// streams when none is configured.
+	defaultEventHistory = 256
+
+	// defaultEventHeartbeat is the interval in seconds between heartbeats of
+	// an event stream when none is configured.
+	defaultEventHeartbeat = 15
+
+	// eventRetry is the reconnection delay in milliseconds suggested to
+	// event stream clients.
+	eventRetry = 3000
+)
+
+// eventRoles are the roles, other than the admin, receiving each event on
+// the event stream.
+var eventRoles = map[string][]string{
+	entity.InviteCreatedEvent:    {},
+	entity.InviteAcceptedEvent:   {},
+	entity.InviteCancelledEvent:  {},
+	entity.UserCreatedEvent:      {},
+	entity.FeedbackCreatedEvent:  {util.Management},
+	entity.FeedbackResolvedEvent: {util.Management},
+	entity.ResetRequestedEvent:   {},
+}
+
+func CreateEventRoutes(router *mux.Router) {
+	router.HandleFunc("/events", App.Authorize(App.StreamEvents, util.Admin, util.Management)).Methods(http.MethodGet)
+}
+
+// StreamEvents streams the events published while connected as server-sent
+// events, limited to the events the role of the session receives and
+// optionally to the events listed in the events query parameter. Clients
+// reconnecting with the Last-Event-ID header are sent the events they
+// missed first. The stream ends when the session expires.
+func (service *Service) StreamEvents(writer http.ResponseWriter, req *http.Request) {
+	session, _ := SessionFrom(req)
+	names, err := parseEventNames(req.URL.Query()["events"])
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	lastID := uint64(0)
+	if header := req.Header.Get("Last-Event-ID"); header != "" {
+		lastID, err = strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			util.RespondWithError(writer, util.ErrInvalidParameterOption("Last-Event-ID", header, "an event id"))
+			return
+		}
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		util.RespondWithError(writer, util.ErrInternal)
+		return
+	}
+
+	heartbeat := service.Cfg.EventHeartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultEventHeartbeat
+	}
+
+	subscription := service.Events.Subscribe(lastID)
+	defer subscription.Close()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(writer, "retry: %d\n\n", eventRetry)
+	flusher.Flush()
+
+	ticker := time.NewTicker(time.Second * time.Duration(heartbeat))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+			if !service.sessionActive(session.Token) {
+				return
+			}
+
+			fmt.Fprint(writer, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-subscription.Events:
+			// Dropped subscriptions end the stream, the client resumes
+			// from the last event it received.
+			if !ok {
+				return
+			}
+
+			if !granted(session, event.Roles...) || (names != nil && !names[event.Name]) {
+				continue
+			}
+
+			fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name, event.Data)
+			flusher.Flush()
+		}
+	}
+}
+
+// publish sends an event to the event stream, with its data encoded once
+// for every subscriber.
+func (service *Service) publish(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("failed to encode %s event: %v", event, err)
+		return
+	}
+
+	service.Events.Publish(event, json.RawMessage(payload), eventRoles[event]...)
+}
+
+// sessionActive asserts the session of a token is still unexpired.
+func (service *Service) sessionActive(token string) bool {
+	entry, ok := service.SessionMap.Get(token)
+	if !ok {
+		return false
+	}
+	return time.Now().Unix() <= entry.(entity.Session).Expiry
+}
+
+// parseEventNames parses the events a stream is limited to, all events are
+// streamed when none are listed.
+func parseEventNames(values []string) (map[string]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	names := map[string]bool{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if _, ok := eventRoles[name]; !ok {
+				return nil, util.ErrInvalidParameterOption("events", name, entity.WebhookEvents)
+			}
+			names[name] = true
+		}
+	}
+	return names, nil
+}
@@ -3,8 +3,6 @@ package service
 import (
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
-	"encoding/json"
-	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -13,63 +11,60 @@ import (
 )
 
 func CreateFeedbackRoutes(router *mux.Router) {
-	router.HandleFunc("/feedback/{id}", App.GetFeedback).Methods(http.MethodGet)
+	router.HandleFunc("/feedback/{id}", App.Authorize(App.GetFeedback, util.Admin)).Methods(http.MethodGet)
 	router.HandleFunc("/feedback", App.CreateFeedback).Methods(http.MethodPost)
-	router.HandleFunc("/feedback/{id}", App.UpdateFeedbackStatus).Methods(http.MethodPut)
-	router.HandleFunc("/feedback/list", App.ListFeedback).Methods(http.MethodPost)
+	router.HandleFunc("/feedback", App.Authorize(App.QueryFeedback, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/feedback/{id}", App.Authorize(App.UpdateFeedbackStatus, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/feedback/list", App.Authorize(App.ListFeedback, util.Admin)).Methods(http.MethodPost)
+}
+
+// createFeedbackRequest is the payload of a feedback submission.
+type createFeedbackRequest struct {
+	User    string `json:"user" validate:"required,nonempty"`
+	Details string `json:"details" validate:"required,nonempty"`
 }
 
 func (service *Service) GetFeedback(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	view, err := util.ParseView(req.URL.Query(), entity.Feedback{}, entity.FeedbackRelations.Names())
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
-		return
-	}
-
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		feedback, err := entity.GetFeedback([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		util.RespondWithJSON(writer, http.StatusOK, feedback)
+		util.RespondWithError(writer, err)
 		return
 	}
-}
 
-func (service *Service) CreateFeedback(writer http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
+	params := mux.Vars(req)
+	id := params["id"]
+	feedback, err := entity.GetFeedback([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-		return
-	}
-	if len(body) == 0 {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	payload := map[string]interface{}{}
-	err = json.Unmarshal(body, &payload)
+	rendered, err := service.render(service.store(req), view, entity.FeedbackRelations, feedback)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	user, ok := payload["user"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("user"))
-		return
-	}
+	util.RespondWithJSON(writer, http.StatusOK, rendered[0])
+	return
+}
+
+// updateFeedbackRequest is the payload of a feedback status update.
+type updateFeedbackRequest struct {
+	Resolved bool `json:"resolved"`
+}
 
-	details, ok := payload["details"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("details"))
+func (service *Service) CreateFeedback(writer http.ResponseWriter, req *http.Request) {
+	payload := createFeedbackRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
+	user := payload.User
+	details := payload.Details
+
 	now := time.Now()
 	feedback := entity.Feedback{
 		Uuid:         ksuid.New().String(),
@@ -79,119 +74,100 @@ func (service *Service) CreateFeedback(writer http.ResponseWriter, req *http.Req
 		CreatedOn:    now.Unix(),
 	}
 
-	err = feedback.Update(service.Bolt)
+	err = feedback.Update(service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
 	// Send feedback email.
 	if !service.Cfg.Debug {
-		util.SendEmail(service.MailGun, service.Cfg.FeedbackEmail, service.Cfg.FeedbackEmail,
+		util.SendEmail(req.Context(), service.MailGun, service.Cfg.FeedbackEmail, service.Cfg.FeedbackEmail,
 			"Feedback submitted.", entity.FeedbackTemplate, service.Cfg.AdminEmail)
 	}
 
+	service.Notify(entity.FeedbackCreatedEvent, feedback)
 	util.RespondWithJSON(writer, http.StatusCreated, feedback)
 	return
 }
 
 func (service *Service) UpdateFeedbackStatus(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	feedback, err := entity.GetFeedback([]byte(id), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	payload := updateFeedbackRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	resolved := payload.Resolved
+	newlyResolved := resolved && !feedback.Resolved
+	feedback.Resolved = resolved
+	err = feedback.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if newlyResolved {
+		service.Notify(entity.FeedbackResolvedEvent, feedback)
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, feedback)
+	return
+}
+
+func (service *Service) QueryFeedback(writer http.ResponseWriter, req *http.Request) {
+	query, err := entity.FeedbackQuery.Parse(req.URL.Query())
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		feedback, err := entity.GetFeedback([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		resolved, _ := payload["resolved"].(bool)
-		feedback.Resolved = resolved
-		err = feedback.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		util.RespondWithJSON(writer, http.StatusOK, feedback)
+	view, err := util.ParseView(req.URL.Query(), entity.Feedback{}, entity.FeedbackRelations.Names())
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithFeedback(writer, req, query, view)
+	return
 }
 
+// ListFeedback is the search term only variant of QueryFeedback.
 func (service *Service) ListFeedback(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	payload := listRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	service.respondWithFeedback(writer, req, entity.FeedbackQuery.NewQuery(payload.Term, *payload.Offset), nil)
+	return
+}
+
+// respondWithFeedback responds with the page of feedback matching the query, shaped
+// by the view.
+func (service *Service) respondWithFeedback(writer http.ResponseWriter, req *http.Request, query *util.Query, view *util.View) {
+	feedback, err := entity.QueryFeedback(service.store(req), service.Cfg.PageLimit, query)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		term, ok := payload["term"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("term"))
-			return
-		}
-
-		offset, ok := payload["offset"].(float64)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("offset"))
-			return
-		}
-
-		feedback, err := entity.ListFeedback(service.Bolt, service.Cfg.PageLimit, term, uint32(offset))
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		meta := map[string]interface{}{}
-		meta["count"] = len(*feedback)
-		meta["offset"] = uint32(offset)
-		meta["pagesize"] = service.Cfg.PageLimit
-		response := map[string]interface{}{}
-		response["meta"] = meta
-		response["results"] = feedback
-		util.RespondWithJSON(writer, http.StatusOK, response)
+	results, err := service.renderList(service.store(req), view, entity.FeedbackRelations, feedback)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithPage(writer, results, len(*feedback), query.Offset)
 }
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// defaultGraphQLMaxDepth is the deepest nesting of selections a graphql
+	// query can have when not configured.
+	defaultGraphQLMaxDepth = 6
+
+	// defaultGraphQLMaxComplexity is the highest cost a graphql query can
+	// have when not configured.
+	defaultGraphQLMaxComplexity = 5000
+)
+
+func CreateGraphQLRoutes(router *mux.Router) {
+	router.HandleFunc("/graphql", App.Authorize(App.GraphQL, util.Management, util.Finance)).Methods(http.MethodPost)
+	router.HandleFunc("/graphql/schema", App.Authorize(App.GraphQLSchema, util.Management, util.Finance)).Methods(http.MethodGet)
+}
+
+// graphqlRequest is the payload of a graphql query.
+type graphqlRequest struct {
+	Query         string                 `json:"query" validate:"required,nonempty"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// fieldError is the error a field failed to resolve with, described by its
+// problem details.
+type fieldError struct {
+	problem *util.Problem
+}
+
+// Error implements the error interface.
+func (err *fieldError) Error() string {
+	return err.problem.Detail
+}
+
+// Extensions are the problem details of the error.
+func (err *fieldError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code":   err.problem.Code,
+		"status": err.problem.Status,
+	}
+	if len(err.problem.Fields) > 0 {
+		extensions["fields"] = err.problem.Fields
+	}
+	return extensions
+}
+
+// queryError creates an error of the query document with the provided code.
+func queryError(code string, err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.FormatError(err)
+	formatted.Extensions = map[string]interface{}{"code": code}
+	return formatted
+}
+
+// GraphQL executes a graphql query against the entities. Fields are
+// authorized with the roles of the equivalent endpoints, queries which can
+// not be executed respond with status 400.
+func (service *Service) GraphQL(writer http.ResponseWriter, req *http.Request) {
+	payload := graphqlRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	response := service.executeGraphQL(req.Context(), payload)
+
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	util.RespondWithJSON(writer, status, response)
+	return
+}
+
+// executeGraphQL validates and executes a query. Queries deeper or more
+// complex than the configured limits are rejected before any field is
+// resolved.
+func (service *Service) executeGraphQL(ctx context.Context, payload graphqlRequest) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: payload.Query})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{queryError("syntax_error", err)}}
+	}
+
+	validation := graphql.ValidateDocument(service.Schema, doc, nil)
+	if !validation.IsValid {
+		for idx, invalid := range validation.Errors {
+			validation.Errors[idx] = queryError("invalid_query", invalid)
+		}
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	maxDepth := service.Cfg.GraphQLMaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultGraphQLMaxDepth
+	}
+
+	maxComplexity := service.Cfg.GraphQLMaxComplexity
+	if maxComplexity == 0 {
+		maxComplexity = defaultGraphQLMaxComplexity
+	}
+
+	cost := newQueryCost(doc, int(service.Cfg.PageLimit))
+	operation := cost.operation(payload.OperationName)
+	if operation != nil {
+		complexity, depth := cost.measure(service.Schema.QueryType(), operation.SelectionSet, 1)
+		if depth > int(maxDepth) {
+			return &graphql.Result{Errors: []gqlerrors.FormattedError{queryError("query_too_deep",
+				fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth))}}
+		}
+
+		if complexity > int(maxComplexity) {
+			return &graphql.Result{Errors: []gqlerrors.FormattedError{queryError("query_too_complex",
+				fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, maxComplexity))}}
+		}
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        *service.Schema,
+		AST:           doc,
+		OperationName: payload.OperationName,
+		Args:          payload.Variables,
+		Context:       ctx,
+	})
+
+	// Queries whose operation or variables are invalid are not executed.
+	if result.Data == nil {
+		for idx, failed := range result.Errors {
+			if failed.Extensions == nil {
+				result.Errors[idx] = queryError("invalid_query", failed)
+			}
+		}
+	}
+	return result
+}
+
+// GraphQLSchema responds with the graphql schema in the schema definition
+// language.
+func (service *Service) GraphQLSchema(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write([]byte(schemaSDL(service.Schema)))
+	return
+}
+
+// described wraps a resolver, describing its errors as problem details.
+func described(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		value, err := resolve(params)
+		if err != nil {
+			return nil, &fieldError{problem: util.NewProblem(err)}
+		}
+		return value, nil
+	}
+}
+
+// authorized wraps a resolver with the role check of Authorize.
+func authorized(resolve graphql.FieldResolveFn, roles ...string) graphql.FieldResolveFn {
+	return described(func(params graphql.ResolveParams) (interface{}, error) {
+		session, _ := params.Context.Value(sessionKey).(entity.Session)
+		if !granted(session, roles...) {
+			return nil, util.ErrForbidden
+		}
+		return resolve(params)
+	})
+}
+
+// listArgs describes the filters of a list query schema as field arguments,
+// leaving out the omitted parameters.
+func listArgs(schema *util.QuerySchema, omit ...string) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{}
+	for _, param := range schema.Params() {
+		if contains(omit, param.Name) {
+			continue
+		}
+
+		arg := &graphql.ArgumentConfig{Description: param.Description, Type: graphql.String}
+		switch {
+		case param.Type == util.BoolField:
+			arg.Type = graphql.Boolean
+		case param.Type == util.TimeField:
+			arg.Description = fmt.Sprint(param.Description, ", as a unix time or an RFC 3339 time")
+		case param.Repeated:
+			arg.Type = graphql.NewList(graphql.NewNonNull(graphql.String))
+			arg.Description = "matches any of the values"
+		}
+		args[param.Name] = arg
+	}
+
+	args["offset"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0,
+		Description: "the page of results"}
+	return args
+}
+
+// parseQuery reads a list query from the arguments of a list field, as the
+// list endpoint reads it from its query parameters.
+func parseQuery(schema *util.QuerySchema, args map[string]interface{}) (*util.Query, error) {
+	values := url.Values{}
+	for name, value := range args {
+		switch typed := value.(type) {
+		case []interface{}:
+			for _, item := range typed {
+				values.Add(name, fmt.Sprint(item))
+			}
+		default:
+			values.Set(name, fmt.Sprint(typed))
+		}
+	}
+	return schema.Parse(values)
+}
+
+// sourceOf returns the entity a field belongs to by pointer, entities of
+// list fields are resolved by value.
+func sourceOf(params graphql.ResolveParams) interface{} {
+	value := reflect.ValueOf(params.Source)
+	if value.Kind() != reflect.Struct {
+		return params.Source
+	}
+
+	pointer := reflect.New(value.Type())
+	pointer.Elem().Set(value)
+	return pointer.Interface()
+}
+
+// contains asserts a value is one of the provided options.
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newSchema generates the graphql schema of the entities. Entity fields are
+// generated from the entity types, relations are resolved with the entity
+// getters and list queries.
+func (service *Service) newSchema() (*graphql.Schema, error) {
+	user := objectOf("User", "A user of the service.", entity.User{}, "password")
+	invite := objectOf("Invite", "An invitation to use the service.", entity.Invite{})
+	feedback := objectOf("Feedback", "Feedback submitted about the service.", entity.Feedback{})
+	requestLog := objectOf("RequestLog", "A logged request to the service.", entity.RequestLog{})
+
+	// related resolves a relation of the source entity.
+	related := func(relations entity.Relations, name string) graphql.FieldResolveFn {
+		return func(params graphql.ResolveParams) (interface{}, error) {
+			resolved, err := relations.Resolve(service.storeOf(params.Context), []string{name}, []interface{}{sourceOf(params)})
+			if err != nil {
+				return nil, err
+			}
+			return resolved[0][name], nil
+		}
+	}
+
+	listUsers := func(params graphql.ResolveParams) (interface{}, error) {
+		query, err := parseQuery(entity.UserQuery, params.Args)
+		if err != nil {
+			return nil, err
+		}
+		return entity.QueryUsers(service.storeOf(params.Context), service.Cfg.PageLimit, query)
+	}
+
+	listInvites := func(params graphql.ResolveParams) (interface{}, error) {
+		if source, ok := sourceOf(params).(*entity.User); ok {
+			params.Args["invitedBy"] = []interface{}{source.Uuid}
+		}
+
+		query, err := parseQuery(entity.InviteQuery, params.Args)
+		if err != nil {
+			return nil, err
+		}
+		return entity.QueryInvites(service.storeOf(params.Context), service.Cfg.PageLimit, query)
+	}
+
+	listFeedback := func(params graphql.ResolveParams) (interface{}, error) {
+		if source, ok := sourceOf(params).(*entity.User); ok {
+			params.Args["user"] = []interface{}{source.Uuid}
+		}
+
+		query, err := parseQuery(entity.FeedbackQuery, params.Args)
+		if err != nil {
+			return nil, err
+		}
+		return entity.QueryFeedback(service.storeOf(params.Context), service.Cfg.PageLimit, query)
+	}
+
+	user.AddFieldConfig("registeredWith", &graphql.Field{Type: invite,
+		Description: "The invite the user registered with.",
+		Resolve:     authorized(related(entity.UserRelations, "invite"), util.Admin)})
+	user.AddFieldConfig("invitesSent", &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(invite)),
+		Description: "The invites sent by the user.",
+		Args:        listArgs(entity.InviteQuery, "invitedBy"),
+		Resolve:     authorized(listInvites, util.Admin)})
+	user.AddFieldConfig("feedback", &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(feedback)),
+		Description: "The feedback submitted by the user.",
+		Args:        listArgs(entity.FeedbackQuery, "user"),
+		Resolve:     authorized(listFeedback, util.Admin)})
+
+	invite.AddFieldConfig("inviter", &graphql.Field{Type: user,
+		Description: "The user who sent the invite.",
+		Resolve:     authorized(related(entity.InviteRelations, "invitedBy"), util.Admin)})
+
+	feedback.AddFieldConfig("author", &graphql.Field{Type: user,
+		Description: "The user who submitted the feedback.",
+		Resolve:     authorized(related(entity.FeedbackRelations, "user"), util.Admin)})
+
+	// Fields of the query other than me are nullable, fields which fail to
+	// resolve are responded as null alongside the other fields.
+	idArg := graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}}
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"me": {Type: graphql.NewNonNull(user),
+			Description: "The user of the session.",
+			Resolve: described(func(params graphql.ResolveParams) (interface{}, error) {
+				session, _ := params.Context.Value(sessionKey).(entity.Session)
+				found, err := entity.GetUser([]byte(session.User), service.storeOf(params.Context))
+				if err != nil {
+					return nil, err
+				}
+
+				found.Sanitize()
+				return found, nil
+			})},
+		"user": {Type: user, Args: idArg,
+			Resolve: authorized(func(params graphql.ResolveParams) (interface{}, error) {
+				found, err := entity.GetUser([]byte(params.Args["id"].(string)), service.storeOf(params.Context))
+				if err != nil {
+					return nil, err
+				}
+
+				found.Sanitize()
+				return found, nil
+			}, util.Admin)},
+		"users": {Type: graphql.NewList(graphql.NewNonNull(user)),
+			Args:    listArgs(entity.UserQuery),
+			Resolve: authorized(listUsers, util.Admin)},
+		"invite": {Type: invite, Args: idArg,
+			Resolve: authorized(func(params graphql.ResolveParams) (interface{}, error) {
+				return entity.GetInvite([]byte(params.Args["id"].(string)), service.storeOf(params.Context))
+			}, util.Admin)},
+		"invites": {Type: graphql.NewList(graphql.NewNonNull(invite)),
+			Args:    listArgs(entity.InviteQuery),
+			Resolve: authorized(listInvites, util.Admin)},
+		"feedback": {Type: feedback, Args: idArg,
+			Resolve: authorized(func(params graphql.ResolveParams) (interface{}, error) {
+				return entity.GetFeedback([]byte(params.Args["id"].(string)), service.storeOf(params.Context))
+			}, util.Admin)},
+		"feedbackList": {Type: graphql.NewList(graphql.NewNonNull(feedback)),
+			Args:    listArgs(entity.FeedbackQuery),
+			Resolve: authorized(listFeedback, util.Admin)},
+		"requestLogs": {Type: graphql.NewList(graphql.NewNonNull(requestLog)),
+			Description: "The requests made in a range of unix times, in the order they were made in.",
+			Args: graphql.FieldConfigArgument{
+				"from":     {Type: graphql.NewNonNull(graphql.Int), Description: "the unix time of the start of the range"},
+				"to":       {Type: graphql.Int, DefaultValue: 0, Description: "the unix time of the end of the range, defaults to now"},
+				"user":     {Type: graphql.String, DefaultValue: "", Description: "the id of the requestor"},
+				"email":    {Type: graphql.String, DefaultValue: "", Description: "the email of the requestor"},
+				"route":    {Type: graphql.String, DefaultValue: "", Description: "the path or route template"},
+				"method":   {Type: graphql.String, DefaultValue: "", Description: "the request method"},
+				"statuses": {Type: graphql.NewList(graphql.NewNonNull(graphql.Int)), Description: "the response statuses"},
+				"offset":   {Type: graphql.Int, DefaultValue: 0, Description: "the page of results"},
+			},
+			Resolve: authorized(func(params graphql.ResolveParams) (interface{}, error) {
+				offset := params.Args["offset"].(int)
+				if offset < 0 {
+					return nil, util.ValidationErrors{{Field: "offset", Message: "must be of type integer"}}
+				}
+
+				statuses := []int{}
+				if values, ok := params.Args["statuses"].([]interface{}); ok {
+					for _, value := range values {
+						statuses = append(statuses, value.(int))
+					}
+				}
+
+				query, err := requestLogQuery(int64(params.Args["from"].(int)), int64(params.Args["to"].(int)),
+					params.Args["user"].(string), params.Args["email"].(string), params.Args["route"].(string),
+					params.Args["method"].(string), statuses)
+				if err != nil {
+					return nil, err
+				}
+
+				return entity.ListRequestLog(service.storeOf(params.Context), service.Cfg.PageLimit, query, uint32(offset))
+			}, util.Admin)},
+	}})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// queryCost measures the operations of a validated query document against
+// the limits of the schema.
+type queryCost struct {
+	doc       *ast.Document
+	fragments map[string]*ast.FragmentDefinition
+	// listSize is how many times the selections of list fields are counted.
+	listSize int
+}
+
+// newQueryCost creates the cost of a validated query document.
+func newQueryCost(doc *ast.Document, listSize int) *queryCost {
+	cost := &queryCost{doc: doc, fragments: map[string]*ast.FragmentDefinition{}, listSize: listSize}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return cost
+}
+
+// operation returns the operation of the document to execute, by name when
+// the document has several, nil when there is no such operation.
+func (cost *queryCost) operation(name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range cost.doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+			continue
+		}
+
+		if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// measure returns the complexity of a selection set of an object and the
+// depth of its deepest selection. Fields are counted once, selections of
+// list fields listSize times. Selections of introspection fields, which are
+// not fields of the object, are only measured for depth.
+func (cost *queryCost) measure(object *graphql.Object, selectionSet *ast.SelectionSet, depth int) (int, int) {
+	complexity, deepest := 0, depth
+	if selectionSet == nil {
+		return complexity, deepest
+	}
+
+	for _, selection := range selectionSet.Selections {
+		innerComplexity, innerDepth := 0, depth
+		switch selected := selection.(type) {
+		case *ast.Field:
+			complexity++
+			if selected.SelectionSet == nil {
+				continue
+			}
+
+			var inner *graphql.Object
+			list := false
+			if object != nil {
+				if definition, ok := object.Fields()[selected.Name.Value]; ok {
+					inner, _ = graphql.GetNamed(definition.Type).(*graphql.Object)
+					_, list = graphql.GetNullable(definition.Type).(*graphql.List)
+				}
+			}
+
+			innerComplexity, innerDepth = cost.measure(inner, selected.SelectionSet, depth+1)
+			if list && cost.listSize > 0 {
+				innerComplexity *= cost.listSize
+			}
+		case *ast.FragmentSpread:
+			fragment, ok := cost.fragments[selected.Name.Value]
+			if !ok {
+				continue
+			}
+			innerComplexity, innerDepth = cost.measure(object, fragment.SelectionSet, depth)
+		case *ast.InlineFragment:
+			innerComplexity, innerDepth = cost.measure(object, selected.SelectionSet, depth)
+		}
+
+		complexity += innerComplexity
+		if innerDepth > deepest {
+			deepest = innerDepth
+		}
+	}
+	return complexity, deepest
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// graphqlJSON holds arbitrary json values.
+var graphqlJSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "An arbitrary json value.",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: jsonLiteral,
+})
+
+// builtInScalars are the scalars of every schema, left out of the schema
+// definition language.
+var builtInScalars = []string{"String", "Int", "Float", "Boolean", "ID"}
+
+// jsonLiteral reads a json value from a literal of a query.
+func jsonLiteral(value ast.Value) interface{} {
+	switch typed := value.(type) {
+	case *ast.IntValue:
+		number, _ := strconv.ParseInt(typed.Value, 10, 64)
+		return number
+	case *ast.FloatValue:
+		number, _ := strconv.ParseFloat(typed.Value, 64)
+		return number
+	case *ast.ListValue:
+		list := make([]interface{}, len(typed.Values))
+		for idx, item := range typed.Values {
+			list[idx] = jsonLiteral(item)
+		}
+		return list
+	case *ast.ObjectValue:
+		object := map[string]interface{}{}
+		for _, field := range typed.Fields {
+			object[field.Name.Value] = jsonLiteral(field.Value)
+		}
+		return object
+	case *ast.StringValue, *ast.BooleanValue, *ast.EnumValue:
+		return typed.GetValue()
+	}
+	return nil
+}
+
+// objectOf generates an object type from the exported fields of a struct,
+// named by their json tags. Omitted fields are left out of the type, fields
+// named uuid are ids.
+func objectOf(name string, description string, value interface{}, omit ...string) *graphql.Object {
+	fields := graphql.Fields{}
+	structType := reflect.TypeOf(value)
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	for idx := 0; idx < structType.NumField(); idx++ {
+		field := structType.Field(idx)
+		fieldName := jsonName(field)
+		if fieldName == "" || contains(omit, fieldName) {
+			continue
+		}
+
+		fieldType := graphqlTypeOf(field.Type)
+		if fieldType == nil {
+			continue
+		}
+
+		if fieldName == "uuid" && fieldType.String() == "String!" {
+			fieldType = graphql.NewNonNull(graphql.ID)
+		}
+		fields[fieldName] = &graphql.Field{Type: fieldType}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{Name: name, Description: description, Fields: fields})
+}
+
+// graphqlTypeOf maps a go type to a scalar type, nil when it has none.
+func graphqlTypeOf(goType reflect.Type) graphql.Output {
+	switch goType.Kind() {
+	case reflect.Ptr:
+		inner := graphqlTypeOf(goType.Elem())
+		if nonNull, ok := inner.(*graphql.NonNull); ok {
+			return nonNull.OfType.(graphql.Output)
+		}
+		return inner
+	case reflect.String:
+		return graphql.NewNonNull(graphql.String)
+	case reflect.Bool:
+		return graphql.NewNonNull(graphql.Boolean)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return graphql.NewNonNull(graphql.Int)
+	case reflect.Float32, reflect.Float64:
+		return graphql.NewNonNull(graphql.Float)
+	case reflect.Map, reflect.Interface:
+		return graphqlJSON
+	case reflect.Slice, reflect.Array:
+		if goType.Elem().Kind() == reflect.Uint8 {
+			return graphql.String
+		}
+
+		inner := graphqlTypeOf(goType.Elem())
+		if inner == nil {
+			return nil
+		}
+		return graphql.NewList(inner)
+	}
+	return nil
+}
+
+// jsonName returns the json name of a struct field, an empty string if the
+// field is not serialized.
+func jsonName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return tag
+}
+
+// schemaSDL describes a schema in the schema definition language, the query
+// type first and the other types by name.
+func schemaSDL(schema *graphql.Schema) string {
+	query := schema.QueryType()
+	names := []string{}
+	for name := range schema.TypeMap() {
+		if strings.HasPrefix(name, "__") || contains(builtInScalars, name) || name == query.Name() {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append([]string{query.Name()}, names...)
+
+	sdl := strings.Builder{}
+	fmt.Fprintf(&sdl, "schema {\n  query: %s\n}\n", query.Name())
+	for _, name := range names {
+		sdl.WriteString("\n")
+		switch definedType := schema.Type(name).(type) {
+		case *graphql.Scalar:
+			writeDescription(&sdl, "", definedType.Description())
+			fmt.Fprintf(&sdl, "scalar %s\n", definedType.Name())
+		case *graphql.Object:
+			writeDescription(&sdl, "", definedType.Description())
+			fmt.Fprintf(&sdl, "type %s {\n", definedType.Name())
+			fields := definedType.Fields()
+			fieldNames := []string{}
+			for fieldName := range fields {
+				fieldNames = append(fieldNames, fieldName)
+			}
+			sort.Strings(fieldNames)
+
+			for _, fieldName := range fieldNames {
+				field := fields[fieldName]
+				writeDescription(&sdl, "  ", field.Description)
+				sdl.WriteString("  " + field.Name)
+				if len(field.Args) > 0 {
+					// Arguments are declared as a map, they are described by
+					// name.
+					defined := append([]*graphql.Argument{}, field.Args...)
+					sort.Slice(defined, func(i, j int) bool {
+						return defined[i].Name() < defined[j].Name()
+					})
+
+					args := make([]string, len(defined))
+					for idx, arg := range defined {
+						args[idx] = fmt.Sprint(arg.Name(), ": ", arg.Type)
+						if arg.DefaultValue != nil {
+							args[idx] += fmt.Sprintf(" = %#v", arg.DefaultValue)
+						}
+					}
+					fmt.Fprintf(&sdl, "(%s)", strings.Join(args, ", "))
+				}
+				fmt.Fprintf(&sdl, ": %s\n", field.Type)
+			}
+			sdl.WriteString("}\n")
+		}
+	}
+	return sdl.String()
+}
+
+// writeDescription writes a description as a block string.
+func writeDescription(sdl *strings.Builder, indent string, description string) {
+	if description == "" {
+		return
+	}
+	fmt.Fprintf(sdl, "%s\"\"\"%s\"\"\"\n", indent, description)
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"einheit/boltkit/util"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+)
+
+// Build metadata, injected at link time with
+// -ldflags "-X einheit/boltkit/service.Version=<version> ...".
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+const (
+	// checkTimeout is how long a dependency check may take before the
+	// dependency is considered down.
+	checkTimeout = 3 * time.Second
+
+	// Dependency check states.
+	checkUp      = "up"
+	checkDown    = "down"
+	checkSkipped = "skipped"
+
+	// Readiness states, a degraded service has a non-critical dependency
+	// down and keeps serving.
+	readyStatus       = "ready"
+	degradedStatus    = "degraded"
+	unavailableStatus = "unavailable"
+)
+
+// probeRoutes are the routes probed by orchestrators and scraped by
+// monitoring, exempt from rate limits.
+var probeRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// dependencyCheck checks a dependency of the service. Critical dependencies
+// fail readiness and startup, others only degrade readiness.
+type dependencyCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+// checkResult is the outcome of a dependency check.
+type checkResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+// readinessResponse is the response of a readiness probe.
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// versionResponse is the build metadata of the running server.
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+func CreateHealthRoutes(router *mux.Router) {
+	router.HandleFunc("/healthz", App.GetHealth).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", App.GetReadiness).Methods(http.MethodGet)
+	router.HandleFunc("/version", App.GetVersion).Methods(http.MethodGet)
+}
+
+// GetHealth responds once the server is able to handle requests.
+func (service *Service) GetHealth(writer http.ResponseWriter, req *http.Request) {
+	util.RespondWithJSON(writer, http.StatusOK, map[string]string{"status": "ok"})
+	return
+}
+
+// GetReadiness responds with the state of every dependency, with a service
+// unavailable status when a critical dependency is down. The probe is public,
+// the errors of dependencies down are logged rather than responded with.
+func (service *Service) GetReadiness(writer http.ResponseWriter, req *http.Request) {
+	response := service.Readiness(req.Context(), false)
+	for name, result := range response.Checks {
+		if result.Error == "" {
+			continue
+		}
+
+		requestLog(req).Warnf("Readiness check %s is down: %s", name, result.Error)
+		result.Error = ""
+		response.Checks[name] = result
+	}
+
+	status := http.StatusOK
+	if response.Status == unavailableStatus {
+		status = http.StatusServiceUnavailable
+	}
+
+	util.RespondWithJSON(writer, status, response)
+	return
+}
+
+// GetVersion responds with the build metadata of the server.
+func (service *Service) GetVersion(writer http.ResponseWriter, req *http.Request) {
+	util.RespondWithJSON(writer, http.StatusOK, versionResponse{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	})
+	return
+}
+
+// AddCheck adds a dependency checked for readiness.
+func (service *Service) AddCheck(name string, critical bool, check func(ctx context.Context) error) {
+	service.checks = append(service.checks, dependencyCheck{Name: name, Critical: critical, Check: check})
+}
+
+// CheckStartup runs the critical dependency checks, failing with the first
+// dependency down.
+func (service *Service) CheckStartup(ctx context.Context) error {
+	response := service.Readiness(ctx, true)
+	for _, check := range service.checks {
+		result, ok := response.Checks[check.Name]
+		if ok && result.Status == checkDown {
+			return fmt.Errorf("%s is down: %s", check.Name, result.Error)
+		}
+	}
+	return nil
+}
+
+// Readiness runs the dependency checks concurrently, or only the critical
+// checks when asked to.
+func (service *Service) Readiness(ctx context.Context, criticalOnly bool) readinessResponse {
+	type outcome struct {
+		name   string
+		result checkResult
+	}
+
+	outcomes := make(chan outcome, len(service.checks))
+	for _, check := range service.checks {
+		if criticalOnly && !check.Critical {
+			outcomes <- outcome{check.Name, checkResult{Status: checkSkipped, Critical: check.Critical}}
+			continue
+		}
+
+		go func(check dependencyCheck) {
+			result := checkResult{Status: checkUp, Critical: check.Critical}
+			err := runCheck(ctx, check.Check)
+			if err == errCheckSkipped {
+				result.Status = checkSkipped
+			} else if err != nil {
+				result.Status = checkDown
+				result.Error = err.Error()
+			}
+			outcomes <- outcome{check.Name, result}
+		}(check)
+	}
+
+	response := readinessResponse{Status: readyStatus, Checks: map[string]checkResult{}}
+	for range service.checks {
+		current := <-outcomes
+		response.Checks[current.name] = current.result
+		if current.result.Status != checkDown {
+			continue
+		}
+
+		if current.result.Critical {
+			response.Status = unavailableStatus
+		} else if response.Status == readyStatus {
+			response.Status = degradedStatus
+		}
+	}
+	return response
+}
+
+// errCheckSkipped is returned by checks of dependencies not in use.
+var errCheckSkipped = errors.New("check skipped")
+
+// runCheck runs a dependency check, failing it once it times out.
+func runCheck(ctx context.Context, check func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.New("check timed out")
+	}
+}
+
+// addDependencyChecks adds the checks of the dependencies the service
+// connects to.
+func (service *Service) addDependencyChecks() {
+	service.AddCheck("bolt", true, func(ctx context.Context) error {
+		return service.Bolt.View(func(tx *bolt.Tx) error {
+			if tx.Bucket(util.CacheBucket) == nil {
+				return errors.New("missing storage buckets")
+			}
+			return nil
+		})
+	})
+
+	service.AddCheck("storage", true, func(ctx context.Context) error {
+		return service.S3.Ping(ctx, service.Cfg.AWSBucket)
+	})
+
+	// Emails are not sent in debug mode.
+	service.AddCheck("mailer", false, func(ctx context.Context) error {
+		if service.Cfg.Debug {
+			return errCheckSkipped
+		}
+		return util.PingMailer(service.MailGun, service.Cfg.MailgunDomain)
+	})
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func CreateHistoryRoutes(router *mux.Router) {
+	router.HandleFunc("/users/{id}/history", App.Authorize(App.ListUserHistory, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/history/{revision}", App.Authorize(App.GetUserRevision, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/at/{timestamp}", App.Authorize(App.GetUserAt, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}/restore", App.Authorize(App.RestoreUser, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/invites/{id}/history", App.Authorize(App.ListInviteHistory, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/{id}/history/{revision}", App.Authorize(App.GetInviteRevision, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/{id}/at/{timestamp}", App.Authorize(App.GetInviteAt, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/{id}/restore", App.Authorize(App.RestoreInvite, util.Admin)).Methods(http.MethodPut)
+}
+
+// restoreRequest is the payload of a revision restore.
+type restoreRequest struct {
+	Revision *uint64 `json:"revision" validate:"required,min=1"`
+}
+
+func (service *Service) ListUserHistory(writer http.ResponseWriter, req *http.Request) {
+	service.listHistory(util.UserBucket, writer, req)
+}
+
+func (service *Service) ListInviteHistory(writer http.ResponseWriter, req *http.Request) {
+	service.listHistory(util.InviteBucket, writer, req)
+}
+
+func (service *Service) GetUserRevision(writer http.ResponseWriter, req *http.Request) {
+	service.getRevision(util.UserBucket, writer, req)
+}
+
+func (service *Service) GetInviteRevision(writer http.ResponseWriter, req *http.Request) {
+	service.getRevision(util.InviteBucket, writer, req)
+}
+
+func (service *Service) GetUserAt(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	timestamp, err := strconv.ParseInt(params["timestamp"], 10, 64)
+	if err != nil {
+		util.RespondWithError(writer, util.ErrInvalidParameter("timestamp"))
+		return
+	}
+
+	user, err := entity.GetUserAt([]byte(params["id"]), timestamp, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
+}
+
+func (service *Service) GetInviteAt(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	timestamp, err := strconv.ParseInt(params["timestamp"], 10, 64)
+	if err != nil {
+		util.RespondWithError(writer, util.ErrInvalidParameter("timestamp"))
+		return
+	}
+
+	invite, err := entity.GetInviteAt([]byte(params["id"]), timestamp, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, invite)
+	return
+}
+
+func (service *Service) RestoreUser(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	user, err := entity.GetUser([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	payload := restoreRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	err = user.Restore(*payload.Revision, service.requestor(req), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
+}
+
+func (service *Service) RestoreInvite(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	invite, err := entity.GetInvite([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	payload := restoreRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	err = invite.Restore(*payload.Revision, service.requestor(req), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, invite)
+	return
+}
+
+// listHistory responds with the revisions of the requested entity.
+func (service *Service) listHistory(entityBucket []byte, writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	revisions, err := entity.ListRevisions(entityBucket, []byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	for idx := range *revisions {
+		(*revisions)[idx].Sanitize()
+	}
+
+	meta := map[string]interface{}{}
+	meta["count"] = len(*revisions)
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = revisions
+	util.RespondWithJSON(writer, http.StatusOK, response)
+	return
+}
+
+// getRevision responds with a single revision of the requested entity.
+func (service *Service) getRevision(entityBucket []byte, writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	revisionId, err := strconv.ParseUint(params["revision"], 10, 64)
+	if err != nil {
+		util.RespondWithError(writer, util.ErrInvalidParameter("revision"))
+		return
+	}
+
+	revision, err := entity.GetRevision(entityBucket, []byte(params["id"]), revisionId, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	revision.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, revision)
+	return
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// idempotencyKeyHeader carries the client generated key identifying
+	// retries of a request.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayedHeader marks a response replayed for a retry.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// defaultIdempotencyTTL is how long, in hours, responses are kept for
+	// retries when not configured.
+	defaultIdempotencyTTL = 24
+
+	// maxIdempotencyKeyLength is the longest idempotency key accepted.
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyLease is how long, in seconds, a key is held by a request
+	// being processed. Retries of requests that never completed, such as
+	// requests of a crashed process, are processed again once it elapses.
+	idempotencyLease = 60
+)
+
+// idempotent replays the stored response of a POST request retried with the
+// same idempotency key. Keys are scoped to the user of the session, or the
+// ip of anonymous clients, retries must send the same payload to the same
+// route.
+func (service *Service) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(idempotencyKeyHeader)
+		if req.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			util.RespondWithError(writer, util.ErrInvalidParameter(idempotencyKeyHeader))
+			return
+		}
+
+		fingerprint, err := requestFingerprint(req)
+		if err != nil {
+			util.RespondWithError(writer, err)
+			return
+		}
+
+		ttl := service.Cfg.IdempotencyTTL
+		if ttl == 0 {
+			ttl = defaultIdempotencyTTL
+		}
+
+		now := time.Now()
+		record := &entity.IdempotencyRecord{
+			Key:         service.idempotencyScope(req, key),
+			Fingerprint: fingerprint,
+			CreatedOn:   now.Unix(),
+			Expiry:      util.GetFutureTime(now, 0, 0, 0, idempotencyLease).Unix(),
+		}
+
+		existing, err := entity.ReserveIdempotencyKey(record, service.Bolt)
+		if err != nil {
+			util.RespondWithError(writer, err)
+			return
+		}
+
+		if existing != nil {
+			replay(writer, existing, fingerprint)
+			return
+		}
+
+		// Release the key of a request that panics for it to be retried.
+		defer func() {
+			if value := recover(); value != nil {
+				record.Purge(service.Bolt)
+				panic(value)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		// Failed requests are not replayed, a retry is processed again.
+		if recorder.status >= http.StatusInternalServerError {
+			err = record.Purge(service.Bolt)
+		} else {
+			record.Completed = true
+			record.Expiry = util.GetFutureTime(now, 0, time.Duration(ttl), 0, 0).Unix()
+			record.Status = recorder.status
+			record.ContentType = recorder.Header().Get("Content-Type")
+			record.Body = recorder.body.Bytes()
+			err = record.Update(service.Bolt)
+		}
+		if err != nil {
+			requestLog(req).Errorf("failed to store idempotent response %s: %v", RequestID(req), err)
+		}
+	})
+}
+
+// replay responds with the stored response of a request retried with the
+// same idempotency key.
+func replay(writer http.ResponseWriter, record *entity.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		util.RespondWithError(writer, util.ErrIdempotencyKeyReused)
+		return
+	}
+
+	if !record.Completed {
+		util.RespondWithError(writer, util.ErrIdempotencyInProgress)
+		return
+	}
+
+	if record.ContentType != "" {
+		writer.Header().Set("Content-Type", record.ContentType)
+	}
+	writer.Header().Set(idempotentReplayedHeader, "true")
+	writer.WriteHeader(record.Status)
+	writer.Write(record.Body)
+}
+
+// idempotencyScope scopes an idempotency key to the user of the session a
+// request is made with, or to the ip of the client making it, as read by
+// clientIP so clients behind a proxy can not claim the scope of others.
+func (service *Service) idempotencyScope(req *http.Request, key string) string {
+	session, ok := SessionFrom(req)
+	if !ok {
+		return fmt.Sprint("ip:", service.clientIP(req), "|", key)
+	}
+	return fmt.Sprint("user:", session.User, "|", key)
+}
+
+// requestFingerprint hashes the route and payload of a request, restoring
+// the body for the handler to read.
+func requestFingerprint(req *http.Request) (string, error) {
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", util.ErrReadBody
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.Path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// bodyRecorder captures the status and body a handler responds with.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the response status.
+func (recorder *bodyRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response body.
+func (recorder *bodyRecorder) Write(data []byte) (int, error) {
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
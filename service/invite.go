@@ -4,9 +4,7 @@ import (
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -17,325 +15,281 @@ import (
 )
 
 func CreateInviteRoutes(router *mux.Router) {
-	router.HandleFunc("/invites/{id}", App.GetInvite).Methods(http.MethodGet)
-	router.HandleFunc("/invites", App.CreateInvite).Methods(http.MethodPost)
-	router.HandleFunc("/invites/{id}", App.UpdateInvite).Methods(http.MethodPut)
-	router.HandleFunc("/invites/{id}", App.DeleteInvite).Methods(http.MethodDelete)
-	router.HandleFunc("/invites/list", App.ListInvites).Methods(http.MethodPost)
+	router.HandleFunc("/invites/{id}", App.Authorize(App.GetInvite, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/invites", App.Authorize(App.CreateInvite, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/invites", App.Authorize(App.QueryInvites, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/{id}", App.Authorize(App.UpdateInvite, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/invites/{id}", App.Authorize(App.DeleteInvite, util.Admin)).Methods(http.MethodDelete)
+	router.HandleFunc("/invites/list", App.Authorize(App.ListInvites, util.Admin)).Methods(http.MethodPost)
+}
+
+// createInviteRequest is the payload of an invite creation.
+type createInviteRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"required,oneof=admin management finance"`
+	InvitedBy string `json:"invitedBy" validate:"required,nonempty"`
+}
+
+// updateInviteRequest is the payload of an invite update, absent fields are
+// left unchanged.
+type updateInviteRequest struct {
+	Role   string `json:"role" validate:"oneof=admin management finance"`
+	Email  string `json:"email" validate:"email"`
+	Status string `json:"status" validate:"oneof=pending cancelled accepted"`
 }
 
 func (service *Service) GetInvite(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	view, err := util.ParseView(req.URL.Query(), entity.Invite{}, entity.InviteRelations.Names())
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		vars := mux.Vars(req)
-		invite, err := entity.GetInvite([]byte(vars["id"]), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	vars := mux.Vars(req)
+	invite, err := entity.GetInvite([]byte(vars["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		util.RespondWithJSON(writer, http.StatusOK, invite)
+	rendered, err := service.render(service.store(req), view, entity.InviteRelations, invite)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	util.RespondWithJSON(writer, http.StatusOK, rendered[0])
+	return
 }
 
 func (service *Service) CreateInvite(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	payload := createInviteRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		email, ok := payload["email"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("email"))
-			return
-		}
-
-		role, ok := payload["role"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				util.ErrKeyNotFound("role"))
-			return
-		}
+	email := payload.Email
+	role := payload.Role
+	invitedBy := payload.InvitedBy
 
-		// Assert the account inviting the user is valid and has adequate
-		// privileges to create an invite.
-		invitedBy, ok := payload["invitedBy"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				util.ErrKeyNotFound("invitedBy"))
-			return
-		}
+	// Assert the account inviting the user is valid and has adequate
+	// privileges to create an invite.
+	_, err = entity.GetUser([]byte(invitedBy), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, util.ErrKeyNotFound("invitedBy"))
+		return
+	}
 
-		_, err = entity.GetUser([]byte(invitedBy), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				util.ErrKeyNotFound("invitedBy"))
-			return
-		}
+	currInvite := new(entity.Invite)
+	match := false
+	// Assert the email of the invited is not already in the system.
+	err = service.store(req).View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.InviteBucket)
+		cursor := bucket.Cursor()
+
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			err := json.Unmarshal(v, currInvite)
+			if err != nil {
+				return util.ErrStorage
+			}
 
-		currInvite := new(entity.Invite)
-		match := false
-		// Assert the email of the invited is not already in the system.
-		err = service.Bolt.View(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(util.InviteBucket)
-			cursor := bucket.Cursor()
-
-			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-				err := json.Unmarshal(v, currInvite)
-				if err != nil {
-					return util.ErrMalformedJSON
-				}
-
-				if currInvite.Email == email {
-					match = true
-					break
-				}
+			if currInvite.Email == email {
+				match = true
+				break
 			}
+		}
 
-			return nil
-		})
+		return nil
+	})
 
-		if match {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				errors.New("invite already exists for provided email."))
-			return
-		}
+	if match {
+		util.RespondWithError(writer, util.ErrInviteExists)
+		return
+	}
 
-		now := time.Now()
-		invite := entity.Invite{
-			Uuid:         ksuid.New().String(),
-			LastModified: 0,
-			CreatedOn:    now.Unix(),
-			Expiry:       util.GetFutureTime(now, 0, 7, 0, 0).Unix(),
-			Deleted:      false,
-			Email:        email,
-			Status:       entity.Pending,
-			Role:         role,
-			InvitedBy:    invitedBy,
-		}
+	now := time.Now()
+	invite := entity.Invite{
+		Uuid:         ksuid.New().String(),
+		LastModified: 0,
+		CreatedOn:    now.Unix(),
+		Expiry:       util.GetFutureTime(now, 0, 7, 0, 0).Unix(),
+		Deleted:      false,
+		Email:        email,
+		Status:       entity.Pending,
+		Role:         role,
+		InvitedBy:    invitedBy,
+		ModifiedBy:   service.requestor(req),
+	}
 
-		err = invite.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	err = invite.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
+	service.afterCommit(req, func() {
 		// Send invite.
 		if !service.Cfg.Debug {
 			inviteURL := fmt.Sprintf(service.Cfg.Frontend, "/#!/register/", invite.Uuid)
 			template := strings.Replace(entity.InviteTemplate, "[invite]", inviteURL, -1)
 			template = strings.Replace(template, "[service]", service.Cfg.Server, -1)
-			util.SendEmail(service.MailGun, service.Cfg.InviteEmail, service.Cfg.InviteEmail,
+			util.SendEmail(req.Context(), service.MailGun, service.Cfg.InviteEmail, service.Cfg.InviteEmail,
 				"You've been invited!", template, email)
 		}
 
-		util.RespondWithJSON(writer, http.StatusCreated, invite)
-		return
-	}
+		service.Notify(entity.InviteCreatedEvent, invite)
+	})
+	util.RespondWithJSON(writer, http.StatusCreated, invite)
+	return
 }
 
 func (service *Service) UpdateInvite(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	invite, err := entity.GetInvite([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		invite, err := entity.GetInvite([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
+	payload := updateInviteRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		role, _ := payload["role"].(string)
-		email, _ := payload["email"].(string)
-		status, _ := payload["email"].(string)
+	role := payload.Role
+	email := payload.Email
+	status := payload.Status
 
-		if role == "" && email == "" && status == "" {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrNoUpdate)
-			return
-		}
+	if role == "" && email == "" && status == "" {
+		util.RespondWithError(writer, util.ErrNoUpdate)
+		return
+	}
 
-		now := time.Now()
-		invite.LastModified = now.Unix()
+	now := time.Now()
+	invite.LastModified = now.Unix()
+	invite.ModifiedBy = service.requestor(req)
 
-		if role != "" {
-			invite.Role = role
-		}
+	if role != "" {
+		invite.Role = role
+	}
 
-		if email != "" {
-			invite.Email = email
-			// Resend invite.
+	if email != "" {
+		invite.Email = email
+		// Resend invite.
+		service.afterCommit(req, func() {
 			if !service.Cfg.Debug {
 				inviteURL := fmt.Sprintf(service.Cfg.Frontend, "/#!/register/", invite.Uuid)
 				template := strings.Replace(entity.InviteTemplate, "[invite]", inviteURL, -1)
 				template = strings.Replace(template, "[service]", service.Cfg.Server, -1)
-				util.SendEmail(service.MailGun, service.Cfg.InviteEmail, service.Cfg.InviteEmail,
+				util.SendEmail(req.Context(), service.MailGun, service.Cfg.InviteEmail, service.Cfg.InviteEmail,
 					"You've been invited!", template, invite.Email)
 			}
-		}
+		})
+	}
 
-		if status != "" {
-			invite.Status = status
+	event := ""
+	if status != "" && status != invite.Status {
+		switch status {
+		case entity.Accepted:
+			event = entity.InviteAcceptedEvent
+		case entity.Cancelled:
+			event = entity.InviteCancelledEvent
 		}
-		err = invite.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		util.RespondWithJSON(writer, http.StatusOK, invite)
+		invite.Status = status
+	}
+	err = invite.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	if event != "" {
+		service.afterCommit(req, func() {
+			service.Notify(event, invite)
+		})
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, invite)
+	return
 }
 
 func (service *Service) DeleteInvite(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	invite, err := entity.GetInvite([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		invite, err := entity.GetInvite([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
+	payload := deleteRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
+	invite.ModifiedBy = service.requestor(req)
+	err = invite.Delete(*payload.Deleted, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		deleted, ok := payload["deleted"].(bool)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("deleted"))
-			return
-		}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNoContent)
+	return
+}
 
-		err = invite.Delete(deleted, service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+func (service *Service) QueryInvites(writer http.ResponseWriter, req *http.Request) {
+	query, err := entity.InviteQuery.Parse(req.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		writer.Header().Set("Content-Type", "application/json")
-		writer.WriteHeader(http.StatusNoContent)
+	view, err := util.ParseView(req.URL.Query(), entity.Invite{}, entity.InviteRelations.Names())
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithInvites(writer, req, query, view)
+	return
 }
 
+// ListInvites is the search term only variant of QueryInvites.
 func (service *Service) ListInvites(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	payload := listRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		term, ok := payload["term"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("term"))
-			return
-		}
-
-		offset, ok := payload["offset"].(float64)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("offset"))
-			return
-		}
+	service.respondWithInvites(writer, req, entity.InviteQuery.NewQuery(payload.Term, *payload.Offset), nil)
+	return
+}
 
-		invites, err := entity.ListInvites(service.Bolt, service.Cfg.PageLimit, term, uint32(offset))
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+// respondWithInvites responds with the page of invites matching the query, shaped
+// by the view.
+func (service *Service) respondWithInvites(writer http.ResponseWriter, req *http.Request, query *util.Query, view *util.View) {
+	invites, err := entity.QueryInvites(service.store(req), service.Cfg.PageLimit, query)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		meta := map[string]interface{}{}
-		meta["count"] = len(*invites)
-		meta["offset"] = uint32(offset)
-		meta["pagesize"] = service.Cfg.PageLimit
-		response := map[string]interface{}{}
-		response["meta"] = meta
-		response["results"] = invites
-		util.RespondWithJSON(writer, http.StatusOK, response)
+	results, err := service.renderList(service.store(req), view, entity.InviteRelations, invites)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithPage(writer, results, len(*invites), query.Offset)
 }
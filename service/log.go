@@ -5,7 +5,12 @@
 
 package service
 
-import "github.com/btcsuite/btclog"
+import (
+	"einheit/boltkit/util"
+	"net/http"
+
+	"github.com/btcsuite/btclog"
+)
 
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
@@ -29,3 +34,9 @@ func DisableLog() {
 func UseLogger(logger btclog.Logger) {
 	log = logger
 }
+
+// requestLog returns the logger of a request, logging with the log fields of
+// the request.
+func requestLog(req *http.Request) btclog.Logger {
+	return util.ContextLogger(log, req.Context())
+}
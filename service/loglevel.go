@@ -0,0 +1,73 @@
+package service
+
+import (
+	"einheit/boltkit/util"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// setLogLevelRequest is the payload of a log level change, applied to every
+// subsystem when none is provided. Changes with a revertAfter duration, in
+// seconds, are undone once it elapses.
+type setLogLevelRequest struct {
+	Subsystem   string `json:"subsystem"`
+	Level       string `json:"level" validate:"required,oneof=trace debug info warn error critical off"`
+	RevertAfter int64  `json:"revertAfter" validate:"min=0"`
+}
+
+// logLevelsResponse lists the log level of every subsystem, along with the
+// unix time temporary changes revert at if any.
+type logLevelsResponse struct {
+	Subsystems map[string]string `json:"subsystems"`
+	RevertAt   int64             `json:"revertAt,omitempty"`
+}
+
+func CreateLogLevelRoutes(router *mux.Router) {
+	router.HandleFunc("/loglevels", App.Authorize(App.GetLogLevels, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/loglevels", App.Authorize(App.SetLogLevel, util.Admin)).Methods(http.MethodPut)
+}
+
+// GetLogLevels responds with the log level of every subsystem.
+func (service *Service) GetLogLevels(writer http.ResponseWriter, req *http.Request) {
+	util.RespondWithJSON(writer, http.StatusOK, service.logLevels())
+	return
+}
+
+// SetLogLevel sets the log level of a subsystem, or of every subsystem,
+// responding with the resulting levels.
+func (service *Service) SetLogLevel(writer http.ResponseWriter, req *http.Request) {
+	payload := setLogLevelRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	revertAfter := time.Duration(payload.RevertAfter) * time.Second
+	err = service.LogLevels.Set(payload.Subsystem, payload.Level, revertAfter)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	subsystem := payload.Subsystem
+	if subsystem == "" {
+		subsystem = "every subsystem"
+	}
+	requestLog(req).Infof("Log level of %s set to %s by %s.", subsystem, payload.Level, service.requestor(req))
+
+	util.RespondWithJSON(writer, http.StatusOK, service.logLevels())
+	return
+}
+
+// logLevels lists the log level of every subsystem.
+func (service *Service) logLevels() logLevelsResponse {
+	levels, revertAt := service.LogLevels.Levels()
+	response := logLevelsResponse{Subsystems: levels}
+	if !revertAt.IsZero() {
+		response.RevertAt = revertAt.Unix()
+	}
+	return response
+}
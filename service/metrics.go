@@ -0,0 +1,94 @@
+package service
+
+import (
+	"einheit/boltkit/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Metrics of the api, exposed at /metrics along with the gauges registered
+// by registerGauges.
+var (
+	httpRequests = metrics.NewCounter("boltkit_http_requests_total",
+		"HTTP requests handled, by method, route and status.", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("boltkit_http_request_duration_seconds",
+		"Latency of HTTP requests, by method and route.", metrics.DefaultBuckets, "method", "route")
+	boltDuration = metrics.NewHistogram("boltkit_bolt_transaction_duration_seconds",
+		"Duration of bolt transactions, by kind.", metrics.DefaultBuckets, "kind")
+	logins = metrics.NewCounter("boltkit_logins_total",
+		"Sign in attempts, by outcome.", "outcome")
+)
+
+// Login outcomes.
+const (
+	loginSuccess = "success"
+	loginFailure = "failure"
+)
+
+func CreateMetricsRoutes(router *mux.Router) {
+	router.HandleFunc("/metrics", App.GetMetrics).Methods(http.MethodGet)
+}
+
+// GetMetrics responds with the metrics in the prometheus text format.
+func (service *Service) GetMetrics(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	err := metrics.Default.WriteText(writer)
+	if err != nil {
+		log.Errorf("failed to write metrics: %v", err)
+	}
+	return
+}
+
+// instrument counts requests and records their latency, labelled by the
+// template of the matched route.
+func (service *Service) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		route := routeOf(req)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		httpRequests.Inc(req.Method, route, strconv.Itoa(recorder.status))
+		httpDuration.Observe(time.Since(start).Seconds(), req.Method, route)
+	})
+}
+
+// observeTransaction records the duration of a bolt transaction.
+func observeTransaction(kind string, duration time.Duration) {
+	boltDuration.Observe(duration.Seconds(), kind)
+}
+
+// registerGauges registers the gauges read from the service when metrics
+// are scraped.
+func (service *Service) registerGauges() {
+	metrics.NewGauge("boltkit_sessions_active", "Sessions held in memory.", func() float64 {
+		return float64(service.SessionMap.Count())
+	})
+	metrics.NewGauge("boltkit_event_streams_active", "Open event streams.", func() float64 {
+		return float64(service.Events.Subscribers())
+	})
+
+	metrics.NewGauge("boltkit_bolt_free_pages", "Free pages of the bolt freelist.", func() float64 {
+		return float64(service.Bolt.Stats().FreePageN)
+	})
+	metrics.NewGauge("boltkit_bolt_pending_pages", "Pending pages of the bolt freelist.", func() float64 {
+		return float64(service.Bolt.Stats().PendingPageN)
+	})
+	metrics.NewGauge("boltkit_bolt_free_alloc_bytes", "Bytes allocated in free pages.", func() float64 {
+		return float64(service.Bolt.Stats().FreeAlloc)
+	})
+	metrics.NewGauge("boltkit_bolt_freelist_inuse_bytes", "Bytes used by the bolt freelist.", func() float64 {
+		return float64(service.Bolt.Stats().FreelistInuse)
+	})
+	metrics.NewGauge("boltkit_bolt_open_read_transactions", "Open bolt read transactions.", func() float64 {
+		return float64(service.Bolt.Stats().OpenTxN)
+	})
+	metrics.NewGauge("boltkit_bolt_read_transactions", "Bolt read transactions started.", func() float64 {
+		return float64(service.Bolt.Stats().TxN)
+	})
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// requestIDHeader carries the id of a request, it is set on every
+	// response and reused when supplied by the client.
+	requestIDHeader = "X-Request-Id"
+
+	// redacted replaces the values of sensitive fields in the request log.
+	redacted = "[redacted]"
+)
+
+// sensitiveFields are the substrings of the payload fields and query
+// parameters left out of the request log, such as newPassword or apiToken.
+var sensitiveFields = []string{"password", "secret", "token"}
+
+// contextKey namespaces the request context values set by the middleware.
+type contextKey string
+
+const (
+	requestIDKey contextKey = "requestId"
+	sessionKey   contextKey = "session"
+	authErrKey   contextKey = "authErr"
+	batchKey     contextKey = "batch"
+)
+
+// useMiddleware wires up the middleware every request passes through, in the
+// order they are applied.
+func (service *Service) useMiddleware() {
+	service.Router.Use(service.requestID)
+	service.Router.Use(service.traceRequest)
+	service.Router.Use(service.recoverPanic)
+	service.Router.Use(service.limitBody)
+	service.Router.Use(service.authenticate)
+	service.Router.Use(service.accessLog)
+	service.Router.Use(service.instrument)
+	service.Router.Use(service.rateLimit)
+	service.Router.Use(service.readOnlyGuard)
+	service.Router.Use(service.idempotent)
+}
+
+// requestID tags a request with an id, kept in its context and echoed in
+// the response.
+func (service *Service) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = ksuid.New().String()
+		}
+
+		writer.Header().Set(requestIDHeader, id)
+		ctx := context.WithValue(req.Context(), requestIDKey, id)
+		next.ServeHTTP(writer, req.WithContext(ctx))
+	})
+}
+
+// recoverPanic responds with an internal error when a handler panics
+// instead of dropping the connection.
+func (service *Service) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if value := recover(); value != nil {
+				log.Errorf("request %s panicked: %v\n%s", RequestID(req), value, debug.Stack())
+				util.RespondWithError(writer, util.ErrInternal)
+			}
+		}()
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// limitBody rejects request bodies larger than the configured limit.
+func (service *Service) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		limit := service.Decoder.MaxBodySize
+		if req.ContentLength > limit {
+			util.RespondWithError(writer, util.ErrPayloadTooLarge(limit))
+			return
+		}
+
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(writer, req.Body, limit)
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// authenticate resolves the session of a request from its authorization
+// header and keeps it in the request context. Requests without a valid
+// session continue unauthenticated, the reason is kept for authorization.
+func (service *Service) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		session, err := service.resolveSession(req)
+		if err != nil {
+			ctx = context.WithValue(ctx, authErrKey, err)
+		} else {
+			ctx = context.WithValue(ctx, sessionKey, *session)
+		}
+
+		next.ServeHTTP(writer, req.WithContext(ctx))
+	})
+}
+
+// resolveSession fetches the unexpired session of a request.
+func (service *Service) resolveSession(req *http.Request) (*entity.Session, error) {
+	token, err := util.GetSessionToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := service.SessionMap.Get(token)
+	if !ok {
+		return nil, util.ErrUnauthorizedAccess
+	}
+
+	session := entry.(entity.Session)
+	if time.Now().Unix() > session.Expiry {
+		service.SessionMap.Remove(token)
+		return nil, util.ErrExpiredSession
+	}
+
+	return &session, nil
+}
+
+// accessLog logs every request once handled and records the requests made
+// with a session to the request log.
+func (service *Service) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		session, authenticated := SessionFrom(req)
+		req = req.WithContext(util.WithLogFields(req.Context(), util.LogFields{
+			RequestID: RequestID(req),
+			UserID:    session.User,
+			Route:     routeOf(req),
+		}))
+
+		// Sub-requests of a batch are recorded with the batch.
+		_, batched := req.Context().Value(batchKey).(*batchScope)
+		record := authenticated && !batched
+		var payload map[string]interface{}
+		var err error
+		if record {
+			payload, err = readPayload(req)
+			if err != nil {
+				record = false
+				requestLog(req).Errorf("failed to record request %s: %v", RequestID(req), err)
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		latency := time.Since(start)
+		requestLog(req).Infof("%s %s %s %d %v", RequestID(req), req.Method, req.URL.Path,
+			recorder.status, latency)
+
+		if record {
+			err = service.recordRequest(req, session.User, payload, recorder.status, latency)
+			if err != nil {
+				requestLog(req).Errorf("failed to record request %s: %v", RequestID(req), err)
+			}
+		}
+	})
+}
+
+// readPayload reads the json object payload of a request for the request
+// log, leaving the body for the handler. Payloads that are not json objects
+// are read empty, and sensitive fields are redacted.
+func readPayload(req *http.Request) (map[string]interface{}, error) {
+	payload := map[string]interface{}{}
+	if req.Body == nil {
+		return payload, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+
+	// Restore the io.ReadCloser to its original state, ie. put back the
+	// bytes read ahead of any read failure for the handler to see.
+	req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewBuffer(body), req.Body))
+	if err != nil {
+		return nil, util.ErrReadBody
+	}
+
+	json.Unmarshal(body, &payload)
+	redact(payload)
+	return payload, nil
+}
+
+// sensitive asserts a payload field or query parameter holds a credential.
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact replaces the values of the sensitive fields of a json value, and of
+// the objects it holds, in place.
+func redact(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if sensitive(key) {
+				value[key] = redacted
+				continue
+			}
+			redact(field)
+		}
+	case []interface{}:
+		for _, item := range value {
+			redact(item)
+		}
+	}
+}
+
+// redactedQuery encodes the query parameters of a request for the request
+// log, redacting sensitive parameters.
+func redactedQuery(req *http.Request) string {
+	params := req.URL.Query()
+	for key := range params {
+		if sensitive(key) {
+			params[key] = []string{redacted}
+		}
+	}
+	return params.Encode()
+}
+
+// recordRequest persists a handled request to the request log, indexed by
+// the time it was made at and the user it was made by.
+func (service *Service) recordRequest(req *http.Request, user string, payload map[string]interface{},
+	status int, latency time.Duration) error {
+	reqLog := entity.RequestLog{
+		RequestID:   RequestID(req),
+		Origin:      req.RemoteAddr,
+		Requestor:   user,
+		RequestType: req.Method,
+		Route:       req.URL.Path,
+		Template:    routeOf(req),
+		QueryParams: redactedQuery(req),
+		Payload:     payload,
+		Status:      status,
+		Latency:     float64(latency) / float64(time.Millisecond),
+		CreatedOn:   time.Now().Add(-latency).Unix(),
+	}
+
+	return reqLog.Append(service.Bolt.WithContext(req.Context()))
+}
+
+// Authorize restricts a handler to requests with a session holding one of
+// the provided roles, admins are always granted. The session expiry is
+// extended by one minute for every authorized request.
+func (service *Service) Authorize(handler http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		session, ok := SessionFrom(req)
+		if !ok {
+			err, _ := req.Context().Value(authErrKey).(error)
+			if err == nil {
+				err = util.ErrAuthorizationNotFound
+			}
+			util.RespondWithError(writer, err)
+			return
+		}
+
+		if !granted(session, roles...) {
+			util.RespondWithError(writer, util.ErrForbidden)
+			return
+		}
+
+		curExpiry := time.Unix(session.Expiry, 0)
+		session.Expiry = util.GetFutureTime(curExpiry, 0, 0, 1, 0).Unix()
+		service.SessionMap.Set(session.Token, session)
+
+		handler(writer, req)
+	}
+}
+
+// granted asserts a session has one of the provided roles, the admin is
+// granted every role.
+func granted(session entity.Session, roles ...string) bool {
+	if session.Access == util.Admin {
+		return true
+	}
+
+	for _, role := range roles {
+		if role == session.Access {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionFrom returns the session a request was authenticated with.
+func SessionFrom(req *http.Request) (entity.Session, bool) {
+	session, ok := req.Context().Value(sessionKey).(entity.Session)
+	return session, ok
+}
+
+// routeOf returns the template of the route matching a request, labelling
+// requests by route rather than path.
+func routeOf(req *http.Request) string {
+	if current := mux.CurrentRoute(req); current != nil {
+		template, err := current.GetPathTemplate()
+		if err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
+// RequestID returns the id a request was tagged with.
+func RequestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey).(string)
+	return id
+}
+
+// statusRecorder captures the status a handler responds with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the response status.
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Flush flushes the underlying writer when it supports it, keeping streamed
+// responses working through the recorder.
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
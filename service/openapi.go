@@ -0,0 +1,490 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"embed"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+)
+
+// Security schemes of the api specification.
+const (
+	sessionAuth     = "sessionToken"
+	replicationAuth = "replicationToken"
+)
+
+var pathParamRegex = regexp.MustCompile(`{([^}:]+)(:[^}]+)?}`)
+
+// docsAssets are the swagger-ui 5.18.2 assets rendering the docs page, served
+// by the api so the page loads no third-party scripts.
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var docsAssets embed.FS
+
+// operation describes an api endpoint for the api specification.
+type operation struct {
+	Summary  string
+	Security string
+	Query    []queryParam
+	// Filters describes the list query parameters of the endpoint.
+	Filters *util.QuerySchema
+	// Relations are the relations of the responded entities, the endpoint
+	// accepts the fields and include view parameters when set.
+	Relations entity.Relations
+	Request   interface{}
+	Status    int
+	Response  interface{}
+	// Meta wraps the response in a list envelope, the response describing
+	// a single result.
+	Meta interface{}
+	// Content overrides the json media type of the response.
+	Content string
+	// Unversioned routes are served at the root rather than under the
+	// prefix of an api version.
+	Unversioned bool
+}
+
+// queryParam describes a query string parameter of an api endpoint.
+type queryParam struct {
+	Name        string
+	Description string
+	// Schema describes the values of the parameter, array schemas are
+	// sent as comma separated values.
+	Schema map[string]interface{}
+}
+
+// pageMeta is the meta of a paginated list response.
+type pageMeta struct {
+	Count    int    `json:"count"`
+	Offset   uint32 `json:"offset"`
+	PageSize uint32 `json:"pagesize"`
+}
+
+// historyMeta is the meta of a revision list response.
+type historyMeta struct {
+	Count int `json:"count"`
+}
+
+// changeMeta is the meta of a change feed response.
+type changeMeta struct {
+	Count int    `json:"count"`
+	After uint64 `json:"after"`
+	Last  uint64 `json:"last"`
+}
+
+// replicationMeta is the meta of a replication change feed response.
+type replicationMeta struct {
+	Count int    `json:"count"`
+	After uint64 `json:"after"`
+	Head  uint64 `json:"head"`
+}
+
+// replicationStatus is the replication status of a service, the replica
+// fields are only reported by replicas.
+type replicationStatus struct {
+	Role            string `json:"role"`
+	HeadSequence    uint64 `json:"headSequence"`
+	Primary         string `json:"primary"`
+	AppliedSequence uint64 `json:"appliedSequence"`
+	PrimarySequence uint64 `json:"primarySequence"`
+	Lag             uint64 `json:"lag"`
+	LastSync        int64  `json:"lastSync"`
+	LagSeconds      int64  `json:"lagSeconds"`
+}
+
+// Schemas of query string parameters.
+var (
+	uintSchema     = map[string]interface{}{"type": "integer", "minimum": 0}
+	unixTimeSchema = map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	stringSchema   = map[string]interface{}{"type": "string"}
+)
+
+var (
+	afterQuery = queryParam{"after", "the sequence number to resume after, defaults to 0", uintSchema}
+	limitQuery = queryParam{"limit", "the maximum number of changes returned", uintSchema}
+	waitQuery  = queryParam{"wait", "the seconds to wait for a change when there are none", uintSchema}
+
+	offsetQuery = queryParam{"offset", "the page to return, defaults to 0", uintSchema}
+	eventsQuery = queryParam{"events", "the comma separated events to stream, defaults to all", map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string", "enum": entity.WebhookEvents},
+	}}
+
+	fromQuery     = queryParam{"from", "the unix time to export the requests made from", unixTimeSchema}
+	toQuery       = queryParam{"to", "the unix time to export the requests made until, defaults to now", unixTimeSchema}
+	userQuery     = queryParam{"user", "the id of the user to export the requests of", stringSchema}
+	emailQuery    = queryParam{"email", "the email of the user to export the requests of", map[string]interface{}{"type": "string", "format": "email"}}
+	routeQuery    = queryParam{"route", "the path or route template of the requests to export", stringSchema}
+	methodQuery   = queryParam{"method", "the method of the requests to export", stringSchema}
+	statusesQuery = queryParam{"statuses", "the comma separated response statuses of the requests to export", map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "integer"},
+	}}
+)
+
+// operations describes every api endpoint, keyed by method and unversioned
+// path template.
+var operations = map[string]operation{
+	"GET /invites/{id}":    {Summary: "Get an invite", Security: sessionAuth, Relations: entity.InviteRelations, Response: entity.Invite{}},
+	"POST /invites":        {Summary: "Create an invite", Security: sessionAuth, Request: createInviteRequest{}, Status: http.StatusCreated, Response: entity.Invite{}},
+	"PUT /invites/{id}":    {Summary: "Update an invite", Security: sessionAuth, Request: updateInviteRequest{}, Response: entity.Invite{}},
+	"DELETE /invites/{id}": {Summary: "Delete or undelete an invite", Security: sessionAuth, Request: deleteRequest{}, Status: http.StatusNoContent},
+	"GET /invites":         {Summary: "List invites", Security: sessionAuth, Query: []queryParam{offsetQuery}, Filters: entity.InviteQuery, Relations: entity.InviteRelations, Response: entity.Invite{}, Meta: pageMeta{}},
+	"POST /invites/list":   {Summary: "Search invites", Security: sessionAuth, Request: listRequest{}, Response: entity.Invite{}, Meta: pageMeta{}},
+
+	"GET /users/{id}":                    {Summary: "Get a user", Security: sessionAuth, Relations: entity.UserRelations, Response: entity.User{}},
+	"POST /users":                        {Summary: "Create a user from an invite", Request: createUserRequest{}, Status: http.StatusCreated, Response: entity.User{}},
+	"PUT /users/{id}":                    {Summary: "Update the details of a user", Security: sessionAuth, Request: updateUserRequest{}, Response: entity.User{}},
+	"PUT /users/{id}/resetpassword":      {Summary: "Reset the password of a user", Security: sessionAuth, Request: resetPasswordRequest{}, Response: entity.User{}},
+	"PUT /users/{id}/role":               {Summary: "Update the role of a user", Security: sessionAuth, Request: updateRoleRequest{}, Response: entity.User{}},
+	"DELETE /users/{id}":                 {Summary: "Delete or undelete a user", Security: sessionAuth, Request: deleteRequest{}, Status: http.StatusNoContent},
+	"GET /users":                         {Summary: "List users", Security: sessionAuth, Query: []queryParam{offsetQuery}, Filters: entity.UserQuery, Relations: entity.UserRelations, Response: entity.User{}, Meta: pageMeta{}},
+	"POST /users/list":                   {Summary: "Search users", Security: sessionAuth, Request: listRequest{}, Response: entity.User{}, Meta: pageMeta{}},
+	"GET /users/{id}/history":            {Summary: "List the revisions of a user", Security: sessionAuth, Response: entity.Revision{}, Meta: historyMeta{}},
+	"GET /users/{id}/history/{revision}": {Summary: "Get a revision of a user", Security: sessionAuth, Response: entity.Revision{}},
+	"GET /users/{id}/at/{timestamp}":     {Summary: "Get a user as it was at a unix time", Security: sessionAuth, Response: entity.User{}},
+	"PUT /users/{id}/restore":            {Summary: "Restore a user to a revision", Security: sessionAuth, Request: restoreRequest{}, Response: entity.User{}},
+
+	"GET /invites/{id}/history":            {Summary: "List the revisions of an invite", Security: sessionAuth, Response: entity.Revision{}, Meta: historyMeta{}},
+	"GET /invites/{id}/history/{revision}": {Summary: "Get a revision of an invite", Security: sessionAuth, Response: entity.Revision{}},
+	"GET /invites/{id}/at/{timestamp}":     {Summary: "Get an invite as it was at a unix time", Security: sessionAuth, Response: entity.Invite{}},
+	"PUT /invites/{id}/restore":            {Summary: "Restore an invite to a revision", Security: sessionAuth, Request: restoreRequest{}, Response: entity.Invite{}},
+
+	"GET /feedback/{id}":  {Summary: "Get feedback", Security: sessionAuth, Relations: entity.FeedbackRelations, Response: entity.Feedback{}},
+	"POST /feedback":      {Summary: "Submit feedback", Request: createFeedbackRequest{}, Status: http.StatusCreated, Response: entity.Feedback{}},
+	"PUT /feedback/{id}":  {Summary: "Update the status of feedback", Security: sessionAuth, Request: updateFeedbackRequest{}, Response: entity.Feedback{}},
+	"GET /feedback":       {Summary: "List feedback", Security: sessionAuth, Query: []queryParam{offsetQuery}, Filters: entity.FeedbackQuery, Relations: entity.FeedbackRelations, Response: entity.Feedback{}, Meta: pageMeta{}},
+	"POST /feedback/list": {Summary: "Search feedback", Security: sessionAuth, Request: listRequest{}, Response: entity.Feedback{}, Meta: pageMeta{}},
+
+	"GET /resets/{id}": {Summary: "Get a password reset", Response: entity.PassReset{}},
+	"GET /resets":      {Summary: "List password resets", Security: sessionAuth, Query: []queryParam{offsetQuery}, Filters: entity.PassResetQuery, Relations: entity.PassResetRelations, Response: entity.PassReset{}, Meta: pageMeta{}},
+	"POST /resets":     {Summary: "Request a password reset", Request: createResetRequest{}, Status: http.StatusCreated, Response: entity.PassReset{}},
+	"PUT /resets/{id}": {Summary: "Mark a password reset used", Security: sessionAuth, Response: entity.PassReset{}},
+
+	"POST /batch": {Summary: "Run a batch of requests", Security: sessionAuth, Request: batchRequest{}, Response: batchResponse{}},
+
+	"POST /graphql":       {Summary: "Run a graphql query", Security: sessionAuth, Request: graphqlRequest{}, Response: graphql.Result{}},
+	"GET /graphql/schema": {Summary: "Get the graphql schema", Security: sessionAuth, Content: "text/plain"},
+
+	"GET /events": {Summary: "Stream notifications as server-sent events", Security: sessionAuth, Query: []queryParam{eventsQuery}, Content: "text/event-stream"},
+
+	"GET /loglevels": {Summary: "List the log levels of the subsystems", Security: sessionAuth, Response: logLevelsResponse{}},
+	"PUT /loglevels": {Summary: "Set the log level of a subsystem or of every subsystem", Security: sessionAuth, Request: setLogLevelRequest{}, Response: logLevelsResponse{}},
+
+	"GET /sessions/{id}": {Summary: "Get a session", Response: entity.Session{}},
+	"POST /sessions":     {Summary: "Sign in", Request: createSessionRequest{}, Status: http.StatusCreated, Response: entity.Session{}},
+
+	"POST /trash/users":          {Summary: "List deleted users", Security: sessionAuth, Request: pageRequest{}, Response: entity.User{}, Meta: pageMeta{}},
+	"PUT /trash/users/{id}":      {Summary: "Restore a deleted user", Security: sessionAuth, Response: entity.User{}},
+	"DELETE /trash/users/{id}":   {Summary: "Purge a deleted user", Security: sessionAuth, Status: http.StatusNoContent},
+	"POST /trash/invites":        {Summary: "List deleted invites", Security: sessionAuth, Request: pageRequest{}, Response: entity.Invite{}, Meta: pageMeta{}},
+	"PUT /trash/invites/{id}":    {Summary: "Restore a deleted invite", Security: sessionAuth, Response: entity.Invite{}},
+	"DELETE /trash/invites/{id}": {Summary: "Purge a deleted invite", Security: sessionAuth, Status: http.StatusNoContent},
+
+	"GET /changes":        {Summary: "List changes", Security: sessionAuth, Query: []queryParam{afterQuery, limitQuery, waitQuery}, Response: entity.Change{}, Meta: changeMeta{}},
+	"GET /changes/stream": {Summary: "Stream changes as newline delimited json", Security: sessionAuth, Query: []queryParam{afterQuery, limitQuery}, Response: entity.Change{}, Content: "application/x-ndjson"},
+
+	"GET /webhooks/{id}":                                  {Summary: "Get a webhook", Security: sessionAuth, Response: entity.Webhook{}},
+	"POST /webhooks":                                      {Summary: "Create a webhook", Security: sessionAuth, Request: createWebhookRequest{}, Status: http.StatusCreated, Response: entity.Webhook{}},
+	"PUT /webhooks/{id}":                                  {Summary: "Update a webhook", Security: sessionAuth, Request: updateWebhookRequest{}, Response: entity.Webhook{}},
+	"DELETE /webhooks/{id}":                               {Summary: "Delete a webhook", Security: sessionAuth, Status: http.StatusNoContent},
+	"POST /webhooks/list":                                 {Summary: "List webhooks", Security: sessionAuth, Request: listRequest{}, Response: entity.Webhook{}, Meta: pageMeta{}},
+	"POST /webhooks/{id}/deliveries":                      {Summary: "List the deliveries of a webhook", Security: sessionAuth, Request: pageRequest{}, Response: entity.Delivery{}, Meta: pageMeta{}},
+	"POST /webhooks/{id}/deliveries/{delivery}/redeliver": {Summary: "Redeliver a delivery", Security: sessionAuth, Response: entity.Delivery{}},
+
+	"POST /logs/list":  {Summary: "Search the request log", Security: sessionAuth, Request: listRequestLogRequest{}, Response: entity.RequestLog{}, Meta: pageMeta{}},
+	"GET /logs/export": {Summary: "Export the request log as csv", Security: sessionAuth, Query: []queryParam{fromQuery, toQuery, userQuery, emailQuery, routeQuery, methodQuery, statusesQuery}, Content: "text/csv"},
+
+	"GET /replication/snapshot": {Summary: "Fetch a snapshot of the database", Security: replicationAuth, Content: "application/octet-stream"},
+	"GET /replication/changes":  {Summary: "Fetch changes to replicate", Security: replicationAuth, Query: []queryParam{afterQuery, limitQuery, waitQuery}, Response: entity.Change{}, Meta: replicationMeta{}},
+	"GET /replication/status":   {Summary: "Get the replication status", Security: sessionAuth, Response: replicationStatus{}},
+	"POST /replication/promote": {Summary: "Promote a replica to primary", Security: sessionAuth, Response: replicationStatus{}},
+
+	"GET /openapi.json": {Summary: "Get the api specification", Response: map[string]interface{}{}, Unversioned: true},
+	"GET /docs":         {Summary: "Browse the api documentation", Content: "text/html", Unversioned: true},
+
+	"GET /docs/swagger-ui.css":       {Summary: "Get the stylesheet of the api documentation", Content: "text/css", Unversioned: true},
+	"GET /docs/swagger-ui-bundle.js": {Summary: "Get the script of the api documentation", Content: "text/javascript", Unversioned: true},
+
+	"GET /healthz": {Summary: "Probe the liveness of the server", Response: map[string]string{}, Unversioned: true},
+	"GET /readyz":  {Summary: "Probe the readiness of the server and its dependencies", Response: readinessResponse{}, Unversioned: true},
+	"GET /version": {Summary: "Get the build metadata of the server", Response: versionResponse{}, Unversioned: true},
+	"GET /metrics": {Summary: "Scrape the prometheus metrics of the server", Content: "text/plain", Unversioned: true},
+}
+
+func CreateOpenAPIRoutes(router *mux.Router) {
+	router.HandleFunc("/openapi.json", App.GetOpenAPI).Methods(http.MethodGet)
+	router.HandleFunc("/docs", App.GetDocs).Methods(http.MethodGet)
+	router.HandleFunc("/docs/swagger-ui.css", App.GetDocsAsset).Methods(http.MethodGet)
+	router.HandleFunc("/docs/swagger-ui-bundle.js", App.GetDocsAsset).Methods(http.MethodGet)
+}
+
+// GetOpenAPI responds with the OpenAPI 3 specification of the api.
+func (service *Service) GetOpenAPI(writer http.ResponseWriter, req *http.Request) {
+	spec, err := service.OpenAPI()
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, spec)
+	return
+}
+
+// GetDocs serves a page rendering the api specification.
+func (service *Service) GetDocs(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write([]byte(docsPage))
+}
+
+// GetDocsAsset serves the embedded swagger-ui asset named by the request
+// path.
+func (service *Service) GetDocsAsset(writer http.ResponseWriter, req *http.Request) {
+	name := path.Base(req.URL.Path)
+	asset, err := docsAssets.ReadFile(path.Join("swagger-ui", name))
+	if err != nil {
+		util.RespondWithError(writer, util.ErrRouteNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(asset)
+}
+
+// OpenAPI generates the OpenAPI 3 specification of the routes registered
+// with the router. Routes without a description in operations are left out.
+func (service *Service) OpenAPI() (map[string]interface{}, error) {
+	schemas := util.Schemas{}
+	problem := map[string]interface{}{
+		"description": "Problem details of a failed request.",
+		"content": map[string]interface{}{
+			"application/problem+json": map[string]interface{}{
+				"schema": schemas.SchemaOf(util.Problem{}),
+			},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	err := service.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		for _, method := range methods {
+			op, ok := operations[method+" "+unversioned(template)]
+			if !ok {
+				continue
+			}
+
+			path := pathParamRegex.ReplaceAllString(template, "{$1}")
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				item = map[string]interface{}{}
+				paths[path] = item
+			}
+			described := op.describe(template, schemas, problem)
+			if !op.Unversioned && unversioned(template) == template {
+				// Root aliases of a versioned route.
+				described["deprecated"] = true
+			}
+
+			if method == http.MethodPost {
+				described["parameters"] = append(described["parameters"].([]interface{}), map[string]interface{}{
+					"name":        idempotencyKeyHeader,
+					"in":          "header",
+					"description": "a key identifying retries of the request, retries replay the first response",
+					"schema":      map[string]interface{}{"type": "string", "maxLength": maxIdempotencyKeyLength},
+				})
+			}
+			item[strings.ToLower(method)] = described
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   service.Cfg.Server,
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				sessionAuth: map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "A session token, sent as 'Token sessiontoken'.",
+				},
+				replicationAuth: map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "The replication token, sent as 'Token replicationtoken'.",
+				},
+			},
+		},
+	}
+	return spec, nil
+}
+
+// describe generates the OpenAPI operation object of an endpoint.
+func (op operation) describe(template string, schemas util.Schemas, problem map[string]interface{}) map[string]interface{} {
+	params := []interface{}{}
+	for _, match := range pathParamRegex.FindAllStringSubmatch(template, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	for _, query := range op.Query {
+		param := map[string]interface{}{
+			"name":        query.Name,
+			"in":          "query",
+			"description": query.Description,
+			"schema":      query.Schema,
+		}
+		if query.Schema["type"] == "array" {
+			param["style"] = "form"
+			param["explode"] = false
+		}
+		params = append(params, param)
+	}
+
+	if op.Filters != nil {
+		for _, filter := range op.Filters.Params() {
+			schema := map[string]interface{}{"type": "string"}
+			switch {
+			case filter.Type == util.BoolField:
+				schema["type"] = "boolean"
+			case filter.Type == util.TimeField:
+				filter.Description = fmt.Sprint(filter.Description, ", as a unix time or an RFC 3339 time")
+			case len(filter.Options) > 0:
+				schema["enum"] = filter.Options
+			}
+
+			params = append(params, map[string]interface{}{
+				"name":        filter.Name,
+				"in":          "query",
+				"description": filter.Description,
+				"schema":      schema,
+			})
+		}
+	}
+
+	if op.Relations != nil {
+		params = append(params, map[string]interface{}{
+			"name":        "fields",
+			"in":          "query",
+			"description": "comma separated fields to respond with, defaults to all",
+			"schema":      map[string]interface{}{"type": "string"},
+		}, map[string]interface{}{
+			"name":        "include",
+			"in":          "query",
+			"description": fmt.Sprintf("comma separated relations to embed, of '%s'", strings.Join(op.Relations.Names(), ", ")),
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := map[string]interface{}{"description": http.StatusText(status)}
+	if status != http.StatusNoContent {
+		var schema map[string]interface{}
+		switch {
+		case op.Meta != nil:
+			schema = map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"meta":    schemas.SchemaOf(op.Meta),
+					"results": map[string]interface{}{"type": "array", "items": schemas.SchemaOf(op.Response)},
+				},
+			}
+		case op.Response != nil:
+			schema = schemas.SchemaOf(op.Response)
+		case op.Content == "application/octet-stream":
+			schema = map[string]interface{}{"type": "string", "format": "binary"}
+		default:
+			schema = map[string]interface{}{"type": "string"}
+		}
+
+		content := op.Content
+		if content == "" {
+			content = "application/json"
+		}
+		response["content"] = map[string]interface{}{
+			content: map[string]interface{}{"schema": schema},
+		}
+	}
+
+	described := map[string]interface{}{
+		"summary":    op.Summary,
+		"parameters": params,
+		"responses": map[string]interface{}{
+			strconv.Itoa(status): response,
+			"default":            problem,
+		},
+	}
+
+	if op.Request != nil {
+		described["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.SchemaOf(op.Request)},
+			},
+		}
+	}
+
+	if op.Security != "" {
+		described["security"] = []interface{}{
+			map[string]interface{}{op.Security: []string{}},
+		}
+	}
+
+	return described
+}
+
+// docsPage renders the api specification served at /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>API Documentation</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="docs"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({ url: "/openapi.json", dom_id: "#docs" });
+  </script>
+</body>
+</html>
+`
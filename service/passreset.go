@@ -3,9 +3,7 @@ package service
 import (
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -17,14 +15,21 @@ import (
 func CreatePassResetRoutes(router *mux.Router) {
 	router.HandleFunc("/resets/{id}", App.GetReset).Methods(http.MethodGet)
 	router.HandleFunc("/resets", App.CreateReset).Methods(http.MethodPost)
-	router.HandleFunc("/resets/{id}", App.UpdateResetState).Methods(http.MethodPut)
+	router.HandleFunc("/resets", App.Authorize(App.QueryResets, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/resets/{id}", App.Authorize(App.UpdateResetState, util.Admin)).Methods(http.MethodPut)
+}
+
+// createResetRequest is the payload of a password reset request.
+type createResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	User  string `json:"user" validate:"required,nonempty"`
 }
 
 func (service *Service) GetReset(writer http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
-	reset, err := entity.GetPassReset([]byte(vars["id"]), App.Bolt)
+	reset, err := entity.GetPassReset([]byte(vars["id"]), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
@@ -33,34 +38,15 @@ func (service *Service) GetReset(writer http.ResponseWriter, req *http.Request)
 }
 
 func (service *Service) CreateReset(writer http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-		return
-	}
-	if len(body) == 0 {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-		return
-	}
-
-	payload := map[string]interface{}{}
-	err = json.Unmarshal(body, &payload)
+	payload := createResetRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	email, ok := payload["email"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("email"))
-		return
-	}
-
-	user, ok := payload["user"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("user"))
-		return
-	}
+	email := payload.Email
+	user := payload.User
 
 	// Create the password reset, setting an expiry of 5 days.
 	now := time.Now()
@@ -76,18 +62,19 @@ func (service *Service) CreateReset(writer http.ResponseWriter, req *http.Reques
 		Expiry:    util.GetFutureTime(time.Now(), 0, 5, 0, 0).Unix(),
 	}
 
-	err = reset.Update(service.Bolt)
+	err = reset.Update(service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 	reset.Sanitize()
+	service.Notify(entity.ResetRequestedEvent, reset)
 
 	// Send reset email.
 	if !service.Cfg.Debug {
 		template := strings.Replace(entity.ResetTemplate, "[reset]", resetURL, -1)
 		template = strings.Replace(template, "[service]", service.Cfg.Server, -1)
-		util.SendEmail(service.MailGun, service.Cfg.ResetEmail, service.Cfg.ResetEmail,
+		util.SendEmail(req.Context(), service.MailGun, service.Cfg.ResetEmail, service.Cfg.ResetEmail,
 			"Reset your password.", template, email)
 	}
 
@@ -96,28 +83,49 @@ func (service *Service) CreateReset(writer http.ResponseWriter, req *http.Reques
 }
 
 func (service *Service) UpdateResetState(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	vars := mux.Vars(req)
+	reset, err := entity.GetPassReset([]byte(vars["id"]), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		vars := mux.Vars(req)
-		reset, err := entity.GetPassReset([]byte(vars["id"]), App.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		reset.Used = true
-		err = reset.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-		reset.Sanitize()
-		util.RespondWithJSON(writer, http.StatusOK, reset)
+	reset.Used = true
+	err = reset.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+	reset.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, reset)
+	return
+}
+
+func (service *Service) QueryResets(writer http.ResponseWriter, req *http.Request) {
+	query, err := entity.PassResetQuery.Parse(req.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	view, err := util.ParseView(req.URL.Query(), entity.PassReset{}, entity.PassResetRelations.Names())
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	resets, err := entity.QueryPassResets(service.store(req), service.Cfg.PageLimit, query)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	results, err := service.renderList(service.store(req), view, entity.PassResetRelations, resets)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	service.respondWithPage(writer, results, len(*resets), query.Offset)
+	return
 }
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"einheit/boltkit/util"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultRateLimit is the rate limit applied to routes without a limit
+	// of their own.
+	defaultRateLimit = "default"
+
+	// defaultTrustedProxies is the number of proxies in front of the
+	// service when trusted proxies are not configured.
+	defaultTrustedProxies = 1
+)
+
+// rateLimit applies the configured rate limit of the requested route. Rate
+// limits are looked up by method and route, then by route group, the first
+// segment of the route, and last the default limit.
+func (service *Service) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		name, limit, ok := service.rateLimitOf(req)
+		if !ok {
+			next.ServeHTTP(writer, req)
+			return
+		}
+
+		key := fmt.Sprint(name, "|", service.rateLimitKey(req, limit))
+		status := service.RateLimiter.Take(key, limit, time.Now())
+		writer.Header().Set("RateLimit-Limit", strconv.FormatUint(uint64(status.Limit), 10))
+		writer.Header().Set("RateLimit-Remaining", strconv.FormatUint(uint64(status.Remaining), 10))
+		writer.Header().Set("RateLimit-Reset", strconv.FormatInt(seconds(status.Reset), 10))
+		if !status.Allowed {
+			retryAfter := seconds(status.RetryAfter)
+			writer.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+			util.RespondWithError(writer, util.ErrRateLimited(retryAfter))
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// rateLimitOf returns the name and configuration of the rate limit applied
+// to a request.
+func (service *Service) rateLimitOf(req *http.Request) (string, util.RateLimit, bool) {
+	limits := service.Cfg.RateLimits
+	if len(limits) == 0 {
+		return "", util.RateLimit{}, false
+	}
+
+	names := []string{}
+	route := mux.CurrentRoute(req)
+	if route != nil {
+		template, err := route.GetPathTemplate()
+		if err == nil {
+			if probeRoutes[template] {
+				return "", util.RateLimit{}, false
+			}
+
+			template = unversioned(template)
+			group := strings.SplitN(strings.TrimPrefix(template, "/"), "/", 2)[0]
+			names = append(names, fmt.Sprint(req.Method, " ", template), group)
+		}
+	}
+	names = append(names, defaultRateLimit)
+
+	for _, name := range names {
+		limit, ok := limits[name]
+		if ok {
+			return name, limit, limit.Rate > 0
+		}
+	}
+
+	return "", util.RateLimit{}, false
+}
+
+// rateLimitKey identifies the client of a request a rate limit is tracked
+// for.
+func (service *Service) rateLimitKey(req *http.Request, limit util.RateLimit) string {
+	if limit.By == util.RateLimitByUser {
+		session, ok := SessionFrom(req)
+		if ok {
+			return fmt.Sprint("user:", session.User)
+		}
+	}
+
+	return fmt.Sprint("ip:", service.clientIP(req))
+}
+
+// clientIP returns the address of the client of a request. Behind trusted
+// proxies it is the address the outermost of them appended to the
+// X-Forwarded-For header, counted from the right, since the addresses left
+// of it are sent by the client and can not be trusted.
+func (service *Service) clientIP(req *http.Request) string {
+	if service.Cfg.TrustProxy {
+		proxies := service.Cfg.TrustedProxies
+		if proxies == 0 {
+			proxies = defaultTrustedProxies
+		}
+
+		forwarded := []string{}
+		for _, header := range req.Header.Values("X-Forwarded-For") {
+			for _, addr := range strings.Split(header, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(addr))
+			}
+		}
+
+		if uint32(len(forwarded)) >= proxies {
+			addr := forwarded[len(forwarded)-int(proxies)]
+			if net.ParseIP(addr) != nil {
+				return addr
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds a duration up to whole seconds.
+func seconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
+
+// validateRateLimits asserts the configured rate limits are keyed by a
+// supported client identifier, limits are keyed by ip when unspecified.
+func validateRateLimits(limits map[string]util.RateLimit) error {
+	for name, limit := range limits {
+		switch limit.By {
+		case "", util.RateLimitByIP, util.RateLimitByUser:
+		default:
+			return util.ErrInvalidParameterOption(fmt.Sprint("ratelimits.", name, ".by"),
+				limit.By, []string{util.RateLimitByIP, util.RateLimitByUser})
+		}
+	}
+	return nil
+}
+
+// SaveRateLimits persists the tracked rate limit buckets.
+func (service *Service) SaveRateLimits() error {
+	buckets := service.RateLimiter.Buckets()
+	err := service.Bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.RateLimitBucket)
+		for key, tokenBucket := range buckets {
+			bucketBytes, err := json.Marshal(tokenBucket)
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put([]byte(key), bucketBytes)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// LoadRateLimits restores the persisted rate limit buckets and empties the
+// rate limit storage.
+func (service *Service) LoadRateLimits() error {
+	err := service.Bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(util.RateLimitBucket)
+		err := bucket.ForEach(func(k, v []byte) error {
+			tokenBucket := util.TokenBucket{}
+			err := json.Unmarshal(v, &tokenBucket)
+			if err != nil {
+				return err
+			}
+
+			service.RateLimiter.Restore(string(k), tokenBucket)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Recreate the bucket rather than deleting keys while iterating.
+		err = tx.DeleteBucket(util.RateLimitBucket)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucket(util.RateLimitBucket)
+		return err
+	})
+	return err
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/trace"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// replicationSequenceHeader carries the change sequence a snapshot of
+	// the primary was taken at.
+	replicationSequenceHeader = "X-Replication-Sequence"
+
+	// replicationWait is how long, in seconds, a replica long-polls its
+	// primary for changes. It must stay below replicationTimeout.
+	replicationWait = 5
+
+	// replicationRetryDelay is how long a replica waits before retrying
+	// after failing to reach its primary.
+	replicationRetryDelay = time.Second * 5
+
+	// replicationTimeout is how long a replica waits for a poll of its
+	// primary to complete.
+	replicationTimeout = time.Second * 10
+)
+
+// replicaReadableRoutes are the non-GET routes a read-only replica serves,
+// they are list queries, graphql queries, logins and runtime settings which
+// do not write entities.
+var replicaReadableRoutes = map[string]bool{
+	"/graphql":                  true,
+	"/sessions":                 true,
+	"/users/list":               true,
+	"/invites/list":             true,
+	"/feedback/list":            true,
+	"/logs/list":                true,
+	"/webhooks/list":            true,
+	"/webhooks/{id}/deliveries": true,
+	"/trash/users":              true,
+	"/trash/invites":            true,
+	"/replication/promote":      true,
+	"/loglevels":                true,
+}
+
+// Replica tracks the replication state of a service following a primary.
+type Replica struct {
+	Primary   string
+	mtx       sync.RWMutex
+	following bool
+	applied   uint64
+	head      uint64
+	lastSync  int64
+	quit      chan struct{}
+	client    *http.Client
+}
+
+func CreateReplicationRoutes(router *mux.Router) {
+	router.HandleFunc("/replication/snapshot", App.ReplicationSnapshot).Methods(http.MethodGet)
+	router.HandleFunc("/replication/changes", App.ReplicationChanges).Methods(http.MethodGet)
+	router.HandleFunc("/replication/status", App.Authorize(App.ReplicationStatus, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/replication/promote", App.Authorize(App.PromoteReplica, util.Admin)).Methods(http.MethodPost)
+}
+
+// ReplicationSnapshot streams a consistent copy of the bolt database to a
+// bootstrapping replica, along with the change sequence it was taken at.
+func (service *Service) ReplicationSnapshot(writer http.ResponseWriter, req *http.Request) {
+	err := service.validateReplication(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	err = service.Bolt.View(func(tx *bolt.Tx) error {
+		seq := tx.Bucket(util.ChangeBucket).Sequence()
+		writer.Header().Set("Content-Type", "application/octet-stream")
+		writer.Header().Set("Content-Length", strconv.FormatInt(tx.Size(), 10))
+		writer.Header().Set(replicationSequenceHeader, strconv.FormatUint(seq, 10))
+		_, err := tx.WriteTo(writer)
+		return err
+	})
+	if err != nil {
+		log.Errorf("failed to write replication snapshot: %v", err)
+	}
+}
+
+// ReplicationChanges responds with unsanitized changes for a replica to
+// apply, holding the request open until changes are available or the
+// requested wait elapses.
+func (service *Service) ReplicationChanges(writer http.ResponseWriter, req *http.Request) {
+	err := service.validateReplication(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	after, limit, err := service.readChangeQuery(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	wait, err := readChangeWait(req)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	changes, err := service.awaitChanges(req, after, limit, wait)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	head, err := entity.HeadSequence(service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	meta := map[string]interface{}{}
+	meta["count"] = len(*changes)
+	meta["after"] = after
+	meta["head"] = head
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = changes
+	util.RespondWithJSON(writer, http.StatusOK, response)
+}
+
+// ReplicationStatus reports the replication role of the service and, for a
+// replica, how far it lags behind its primary.
+func (service *Service) ReplicationStatus(writer http.ResponseWriter, req *http.Request) {
+	status := map[string]interface{}{}
+	if !service.ReadOnly() {
+		head, err := entity.HeadSequence(service.Bolt)
+		if err != nil {
+			util.RespondWithError(writer, err)
+			return
+		}
+
+		status["role"] = "primary"
+		status["headSequence"] = head
+		util.RespondWithJSON(writer, http.StatusOK, status)
+		return
+	}
+
+	replica := service.Replica
+	replica.mtx.RLock()
+	status["role"] = "replica"
+	status["primary"] = replica.Primary
+	status["appliedSequence"] = replica.applied
+	status["primarySequence"] = replica.head
+	status["lag"] = replica.head - replica.applied
+	status["lastSync"] = replica.lastSync
+	status["lagSeconds"] = time.Now().Unix() - replica.lastSync
+	replica.mtx.RUnlock()
+	util.RespondWithJSON(writer, http.StatusOK, status)
+	return
+}
+
+// PromoteReplica stops a replica following its primary and makes it accept
+// writes. The promotion is persisted so the service does not resume following
+// on restart.
+func (service *Service) PromoteReplica(writer http.ResponseWriter, req *http.Request) {
+	if !service.ReadOnly() {
+		util.RespondWithError(writer, util.ErrNotReplica)
+		return
+	}
+
+	err := service.CachePut(util.PromotedKey, []byte(strconv.FormatInt(time.Now().Unix(), 10)))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	service.Replica.stop()
+	log.Infof("Promoted replica of %s to primary.", service.Replica.Primary)
+	util.RespondWithJSON(writer, http.StatusOK, map[string]interface{}{"role": "primary"})
+	return
+}
+
+// ReadOnly asserts the service is a replica following a primary.
+func (service *Service) ReadOnly() bool {
+	if service.Replica == nil {
+		return false
+	}
+
+	service.Replica.mtx.RLock()
+	defer service.Replica.mtx.RUnlock()
+	return service.Replica.following
+}
+
+// Follow tails the change log of the primary and applies it until the
+// replica is promoted.
+func (service *Service) Follow() {
+	replica := service.Replica
+	for service.ReadOnly() {
+		replica.mtx.RLock()
+		after := replica.applied
+		replica.mtx.RUnlock()
+
+		changes, head, err := service.fetchChanges(after)
+		if err == nil {
+			err = entity.ApplyChanges(service.Bolt, changes)
+		}
+
+		if err != nil {
+			if err == util.ErrChangesCompacted {
+				log.Errorf("replica is behind the changes kept by %s, remove %s and restart to bootstrap it again",
+					replica.Primary, service.Cfg.Storage)
+			} else {
+				log.Errorf("failed to replicate from %s: %v", replica.Primary, err)
+			}
+
+			select {
+			case <-replica.quit:
+			case <-time.After(replicationRetryDelay):
+			}
+			continue
+		}
+
+		replica.mtx.Lock()
+		if len(changes) > 0 {
+			replica.applied = changes[len(changes)-1].Sequence
+		}
+		replica.head = head
+		replica.lastSync = time.Now().Unix()
+		replica.mtx.Unlock()
+	}
+}
+
+// readOnlyGuard rejects requests that would write to a read-only replica.
+func (service *Service) readOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if service.ReadOnly() && !replicaReadable(req) && !service.readOnlyBatch(req) {
+			util.RespondWithError(writer, util.ErrReadOnlyReplica)
+			return
+		}
+
+		next.ServeHTTP(writer, req)
+	})
+}
+
+// replicaReadable asserts a request can be served by a read-only replica.
+func replicaReadable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return false
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+
+	return replicaReadableRoutes[unversioned(template)]
+}
+
+// readOnlyBatch asserts a request is a batch which is not atomic and only
+// made of GET sub-requests, they are guarded again as they are dispatched.
+// The read body is restored for the batch handler.
+func (service *Service) readOnlyBatch(req *http.Request) bool {
+	route := mux.CurrentRoute(req)
+	if route == nil || req.Method != http.MethodPost {
+		return false
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil || unversioned(template) != "/batch" {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, service.Decoder.MaxBodySize+1))
+	req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	if err != nil {
+		return false
+	}
+
+	payload := batchRequest{}
+	err = json.Unmarshal(body, &payload)
+	if err != nil || payload.Atomic || len(payload.Requests) == 0 {
+		return false
+	}
+
+	for _, item := range payload.Requests {
+		if item.Method != http.MethodGet {
+			return false
+		}
+	}
+	return true
+}
+
+// newReplica creates the replication state of a service following the
+// configured primary, resuming from the last applied change.
+func (service *Service) newReplica() (*Replica, error) {
+	applied, err := entity.AppliedSequence(service.Bolt)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := replicationClient(service.Cfg, replicationTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	replica := &Replica{
+		Primary:   service.Cfg.ReplicaOf,
+		following: true,
+		applied:   applied,
+		head:      applied,
+		lastSync:  time.Now().Unix(),
+		quit:      make(chan struct{}),
+		client:    client,
+	}
+	return replica, nil
+}
+
+// stop ends replication, the replica starts accepting writes.
+func (replica *Replica) stop() {
+	replica.mtx.Lock()
+	defer replica.mtx.Unlock()
+	if replica.following {
+		replica.following = false
+		close(replica.quit)
+	}
+}
+
+// validateReplication asserts a request carries the replication token.
+func (service *Service) validateReplication(req *http.Request) error {
+	token, err := util.GetSessionToken(req)
+	if err != nil {
+		return err
+	}
+
+	if service.Cfg.ReplicationToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(service.Cfg.ReplicationToken)) != 1 {
+		return util.ErrUnauthorizedAccess
+	}
+
+	return nil
+}
+
+// fetchChanges long-polls the primary for changes after the provided
+// sequence, returning them along with the primary's head sequence.
+func (service *Service) fetchChanges(after uint64) ([]entity.Change, uint64, error) {
+	changesURL := fmt.Sprintf("%s%s/replication/changes?after=%d&wait=%d",
+		service.Cfg.ReplicaOf, APIVersion, after, replicationWait)
+	req, err := http.NewRequest(http.MethodGet, changesURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", fmt.Sprint("Token ", service.Cfg.ReplicationToken))
+
+	ctx, span := trace.Start(context.Background(), "replication.fetch", trace.KindClient)
+	defer span.Finish()
+	span.SetAttribute("replication.after", int64(after))
+	trace.Inject(ctx, req.Header)
+
+	resp, err := service.Replica.client.Do(req.WithContext(ctx))
+	if err != nil {
+		span.SetError(err)
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, 0, util.ErrChangesCompacted
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, util.ErrDeliveryRejected(resp.StatusCode)
+	}
+
+	response := struct {
+		Meta struct {
+			Head uint64 `json:"head"`
+		} `json:"meta"`
+		Results []entity.Change `json:"results"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, 0, util.ErrMalformedJSON
+	}
+
+	return response.Results, response.Meta.Head, nil
+}
+
+// replicationClient creates the http client a replica reaches its primary
+// with. The certificate of the primary is verified against the certificates
+// of the configured replicationca file, the system roots when unset, so the
+// replication token and the changes sent back are not exposed.
+func replicationClient(cfg *util.Config, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.ReplicationCA != "" {
+		pem, err := ioutil.ReadFile(cfg.ReplicationCA)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, util.ErrInvalidParameter("replicationca")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConnsPerHost: 2,
+			TLSClientConfig:     tlsConfig,
+		},
+		Timeout: timeout,
+	}
+	return client, nil
+}
+
+// bootstrapReplica copies a snapshot of the primary to the configured storage
+// path, returning the change sequence the snapshot was taken at. An existing
+// storage file is kept, the replica resumes from it instead.
+func bootstrapReplica(cfg *util.Config) (uint64, bool, error) {
+	_, err := os.Stat(cfg.Storage)
+	if err == nil {
+		return 0, false, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return 0, false, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprint(cfg.ReplicaOf, APIVersion, "/replication/snapshot"), nil)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Authorization", fmt.Sprint("Token ", cfg.ReplicationToken))
+
+	// The snapshot is as large as the database, its download is not timed
+	// out.
+	client, err := replicationClient(cfg, 0)
+	if err != nil {
+		return 0, false, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, util.ErrDeliveryRejected(resp.StatusCode)
+	}
+
+	seq, err := strconv.ParseUint(resp.Header.Get(replicationSequenceHeader), 10, 64)
+	if err != nil {
+		return 0, false, util.ErrInvalidParameter(replicationSequenceHeader)
+	}
+
+	// Write to a temporary file first so an interrupted download is not
+	// mistaken for a complete snapshot.
+	tmpPath := fmt.Sprint(cfg.Storage, ".snapshot")
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, false, err
+	}
+
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		file.Close()
+		return 0, false, err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = os.Rename(tmpPath, cfg.Storage)
+	if err != nil {
+		return 0, false, err
+	}
+
+	log.Infof("Bootstrapped replica from %s at sequence %d.", cfg.ReplicaOf, seq)
+	return seq, true, nil
+}
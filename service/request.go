@@ -0,0 +1,41 @@
+package service
+
+import (
+	"einheit/boltkit/util"
+	"net/http"
+)
+
+// listRequest is the payload of a paginated list query.
+type listRequest struct {
+	Term   string  `json:"term" validate:"required"`
+	Offset *uint32 `json:"offset" validate:"required"`
+}
+
+// pageRequest is the payload of a paginated query with no search term.
+type pageRequest struct {
+	Offset *uint32 `json:"offset" validate:"required"`
+}
+
+// deleteRequest is the payload of a soft delete.
+type deleteRequest struct {
+	Deleted *bool `json:"deleted" validate:"required"`
+}
+
+// decode reads and validates the payload of a request into the provided
+// request struct pointer.
+func (service *Service) decode(req *http.Request, dst interface{}) error {
+	return service.Decoder.Decode(req, dst)
+}
+
+// respondWithPage responds with a page of list results wrapped in the list
+// envelope.
+func (service *Service) respondWithPage(writer http.ResponseWriter, results interface{}, count int, offset uint32) {
+	meta := map[string]interface{}{}
+	meta["count"] = count
+	meta["offset"] = offset
+	meta["pagesize"] = service.Cfg.PageLimit
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = results
+	util.RespondWithJSON(writer, http.StatusOK, response)
+}
@@ -1,82 +1,186 @@
 package service
 
 import (
+	"einheit/boltkit/base58"
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
+	"encoding/csv"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// requestLogColumns are the columns of request log exports.
+var requestLogColumns = []string{"id", "time", "requestId", "requestor", "origin", "method", "route",
+	"template", "queryParams", "status", "latency", "payload"}
+
 func CreateRequestLogRoutes(router *mux.Router) {
-	router.HandleFunc("/logs/list", App.ListRequestLog).Methods(http.MethodPost)
+	router.HandleFunc("/logs/list", App.Authorize(App.ListRequestLog, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/logs/export", App.Authorize(App.ExportRequestLog, util.Admin)).Methods(http.MethodGet)
+}
+
+// listRequestLogRequest is the payload of a request log query. Requests are
+// matched from and to unix times, to defaulting to now, and optionally by
+// the id or email of their requestor, their path or route template, method
+// and response statuses.
+type listRequestLogRequest struct {
+	From     int64   `json:"from" validate:"required,min=0"`
+	To       int64   `json:"to" validate:"min=0"`
+	User     string  `json:"user"`
+	Email    string  `json:"email" validate:"email"`
+	Route    string  `json:"route"`
+	Method   string  `json:"method"`
+	Statuses []int   `json:"statuses"`
+	Offset   *uint32 `json:"offset" validate:"required"`
 }
 
+// ListRequestLog responds with a page of the requests matching a query, in
+// the order they were made in.
 func (service *Service) ListRequestLog(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	payload := listRequestLogRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
+	query, err := requestLogQuery(payload.From, payload.To, payload.User, payload.Email,
+		payload.Route, payload.Method, payload.Statuses)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
+	offset := *payload.Offset
+	requestLogs, err := entity.ListRequestLog(service.store(req), service.Cfg.PageLimit, query, offset)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		date, ok := payload["date"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("date"))
-			return
-		}
+	service.respondWithPage(writer, requestLogs, len(*requestLogs), offset)
+	return
+}
 
-		email, ok := payload["email"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("email"))
-			return
-		}
+// ExportRequestLog streams every request matching a query as csv, for
+// compliance reviews. The query is read from the parameters of the url, the
+// statuses as a comma separated list.
+func (service *Service) ExportRequestLog(writer http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	if params.Get("from") == "" {
+		util.RespondWithError(writer, util.ValidationErrors{{Field: "from", Message: "is required"}})
+		return
+	}
 
-		requestType, ok := payload["requestType"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("requestType"))
-			return
-		}
+	from, err := strconv.ParseInt(params.Get("from"), 10, 64)
+	if err != nil || from < 0 {
+		util.RespondWithError(writer, util.ErrInvalidParameter("from"))
+		return
+	}
 
-		offset, ok := payload["offset"].(float64)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("offset"))
+	var to int64
+	if params.Get("to") != "" {
+		to, err = strconv.ParseInt(params.Get("to"), 10, 64)
+		if err != nil || to < 0 {
+			util.RespondWithError(writer, util.ErrInvalidParameter("to"))
 			return
 		}
+	}
 
-		requestLogs, err := entity.ListRequestLog(service.Bolt, service.Cfg.PageLimit, date, email, requestType, uint32(offset))
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
+	statuses := []int{}
+	if params.Get("statuses") != "" {
+		for _, value := range strings.Split(params.Get("statuses"), ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				util.RespondWithError(writer, util.ErrInvalidParameter("statuses"))
+				return
+			}
+			statuses = append(statuses, status)
 		}
+	}
 
-		meta := map[string]interface{}{}
-		meta["count"] = len(*requestLogs)
-		meta["offset"] = uint32(offset)
-		meta["pagesize"] = service.Cfg.PageLimit
-		response := map[string]interface{}{}
-		response["meta"] = meta
-		response["results"] = requestLogs
-		util.RespondWithJSON(writer, http.StatusOK, response)
+	query, err := requestLogQuery(from, to, params.Get("user"), params.Get("email"),
+		params.Get("route"), params.Get("method"), statuses)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"requests-%d-%d.csv\"", query.From, query.To))
+	writer.WriteHeader(http.StatusOK)
+
+	encoder := csv.NewWriter(writer)
+	encoder.Write(requestLogColumns)
+	err = entity.ScanRequestLog(service.store(req), query, func(entry *entity.RequestLog) error {
+		payload, _ := json.Marshal(entry.Payload)
+		return encoder.Write(csvCells(
+			entry.ID,
+			time.Unix(entry.CreatedOn, 0).UTC().Format(time.RFC3339),
+			entry.RequestID,
+			entry.Requestor,
+			entry.Origin,
+			entry.RequestType,
+			entry.Route,
+			entry.Template,
+			entry.QueryParams,
+			strconv.Itoa(entry.Status),
+			strconv.FormatFloat(entry.Latency, 'f', 3, 64),
+			string(payload),
+		))
+	})
+	encoder.Flush()
+	if err == nil {
+		err = encoder.Error()
+	}
+
+	// The response has started, failures can only be logged.
+	if err != nil {
+		requestLog(req).Errorf("failed to export the request log: %v", err)
+	}
+	return
+}
+
+// csvCells escapes the cells of a csv row, cells spreadsheets would read as
+// formulas are prefixed with a quote.
+func csvCells(values ...string) []string {
+	for idx, value := range values {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			values[idx] = "'" + value
+		}
+	}
+	return values
+}
+
+// requestLogQuery creates a request log query, matching the requests of the
+// user of the provided email when no user id is provided.
+func requestLogQuery(from int64, to int64, user string, email string, route string,
+	method string, statuses []int) (entity.RequestLogQuery, error) {
+	if to == 0 {
+		// The end of the range is exclusive, include the current second.
+		to = time.Now().Unix() + 1
+	}
+
+	if to <= from {
+		return entity.RequestLogQuery{}, util.ValidationErrors{{Field: "to", Message: "must be after from"}}
+	}
+
+	if user == "" && email != "" {
+		user = base58.Encode([]byte(email))
+	}
+
+	return entity.RequestLogQuery{
+		From:     from,
+		To:       to,
+		User:     user,
+		Route:    route,
+		Method:   method,
+		Statuses: statuses,
+	}, nil
 }
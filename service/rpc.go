@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"einheit/boltkit/rpc"
+	"einheit/boltkit/util"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// rpcMetadata is the metadata forwarded from a call to its api request.
+var rpcMetadata = []string{"Authorization", "X-Forwarded-For", "Idempotency-Key", "Traceparent"}
+
+// rpcRoute is the api route a grpc method runs.
+type rpcRoute struct {
+	Method string
+	Route  string
+}
+
+// rpcRoutes are the api routes of the grpc methods, keyed by full method
+// name.
+var rpcRoutes = map[string]rpcRoute{
+	rpc.SessionService_CreateSession_FullMethodName:     {http.MethodPost, "/sessions"},
+	rpc.SessionService_GetSession_FullMethodName:        {http.MethodGet, "/sessions/{id}"},
+	rpc.UserService_GetUser_FullMethodName:              {http.MethodGet, "/users/{id}"},
+	rpc.UserService_ListUsers_FullMethodName:            {http.MethodGet, "/users"},
+	rpc.UserService_CreateUser_FullMethodName:           {http.MethodPost, "/users"},
+	rpc.UserService_UpdateUser_FullMethodName:           {http.MethodPut, "/users/{id}"},
+	rpc.UserService_UpdateUserRole_FullMethodName:       {http.MethodPut, "/users/{id}/role"},
+	rpc.UserService_DeleteUser_FullMethodName:           {http.MethodDelete, "/users/{id}"},
+	rpc.InviteService_GetInvite_FullMethodName:          {http.MethodGet, "/invites/{id}"},
+	rpc.InviteService_ListInvites_FullMethodName:        {http.MethodGet, "/invites"},
+	rpc.InviteService_CreateInvite_FullMethodName:       {http.MethodPost, "/invites"},
+	rpc.InviteService_UpdateInvite_FullMethodName:       {http.MethodPut, "/invites/{id}"},
+	rpc.InviteService_DeleteInvite_FullMethodName:       {http.MethodDelete, "/invites/{id}"},
+	rpc.FeedbackService_GetFeedback_FullMethodName:      {http.MethodGet, "/feedback/{id}"},
+	rpc.FeedbackService_ListFeedback_FullMethodName:     {http.MethodGet, "/feedback"},
+	rpc.FeedbackService_CreateFeedback_FullMethodName:   {http.MethodPost, "/feedback"},
+	rpc.FeedbackService_UpdateFeedback_FullMethodName:   {http.MethodPut, "/feedback/{id}"},
+	rpc.PassResetService_GetPassReset_FullMethodName:    {http.MethodGet, "/resets/{id}"},
+	rpc.PassResetService_ListPassResets_FullMethodName:  {http.MethodGet, "/resets"},
+	rpc.PassResetService_CreatePassReset_FullMethodName: {http.MethodPost, "/resets"},
+	rpc.PassResetService_UsePassReset_FullMethodName:    {http.MethodPut, "/resets/{id}"},
+}
+
+// rpcServer implements the grpc services generated from proto/boltkit.proto,
+// each method runs the api route of rpcRoutes.
+type rpcServer struct {
+	rpc.UnimplementedSessionServiceServer
+	rpc.UnimplementedUserServiceServer
+	rpc.UnimplementedInviteServiceServer
+	rpc.UnimplementedFeedbackServiceServer
+	rpc.UnimplementedPassResetServiceServer
+	service *Service
+}
+
+// newRPCServer creates the grpc server of the api, serving tls when https is
+// enabled.
+func (service *Service) newRPCServer() (*grpc.Server, error) {
+	options := []grpc.ServerOption{}
+	if service.Cfg.MaxBodySize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(int(service.Cfg.MaxBodySize)))
+	}
+
+	if service.Cfg.HTTPS && service.Cfg.GRPCPort != "" {
+		creds, err := credentials.NewServerTLSFromFile("cert.pem", "privkey.pem")
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(options...)
+	handler := &rpcServer{service: service}
+	rpc.RegisterSessionServiceServer(server, handler)
+	rpc.RegisterUserServiceServer(server, handler)
+	rpc.RegisterInviteServiceServer(server, handler)
+	rpc.RegisterFeedbackServiceServer(server, handler)
+	rpc.RegisterPassResetServiceServer(server, handler)
+	return server, nil
+}
+
+// rpcCode maps an http status to the grpc status code of the same failure.
+func rpcCode(httpStatus int) codes.Code {
+	switch {
+	case httpStatus < http.StatusBadRequest:
+		return codes.OK
+	case httpStatus == http.StatusBadRequest, httpStatus == http.StatusUnprocessableEntity,
+		httpStatus == http.StatusRequestEntityTooLarge, httpStatus == http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case httpStatus == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case httpStatus == http.StatusForbidden:
+		return codes.PermissionDenied
+	case httpStatus == http.StatusNotFound, httpStatus == http.StatusMethodNotAllowed:
+		return codes.NotFound
+	case httpStatus == http.StatusConflict:
+		return codes.Aborted
+	case httpStatus == http.StatusGone, httpStatus == http.StatusPreconditionFailed,
+		httpStatus == http.StatusFailedDependency:
+		return codes.FailedPrecondition
+	case httpStatus == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case httpStatus == http.StatusNotImplemented:
+		return codes.Unimplemented
+	case httpStatus == http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case httpStatus == http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case httpStatus < http.StatusInternalServerError:
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+// listQuery returns the query parameters of a list call.
+func listQuery(request *rpc.ListRequest) url.Values {
+	values := url.Values{}
+	if request.Q != "" {
+		values.Set("q", request.Q)
+	}
+
+	if request.Sort != "" {
+		values.Set("sort", request.Sort)
+	}
+
+	values.Set("offset", fmt.Sprint(request.Offset))
+	for _, filter := range request.Filters {
+		for _, value := range filter.Values {
+			values.Add(filter.Name, value)
+		}
+	}
+	return values
+}
+
+// rpcPayload returns the json payload of a request message. The id is sent
+// in the path, and empty strings are left out as proto3 does not tell them
+// from unset fields.
+func rpcPayload(request proto.Message) ([]byte, error) {
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{}
+	err = json.Unmarshal(data, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(payload, "id")
+	for name, value := range payload {
+		if value == "" {
+			delete(payload, name)
+		}
+	}
+	return json.Marshal(payload)
+}
+
+// call runs a call as a request to the api route of its method, with the
+// credentials of its metadata. The request message is sent as the json
+// payload, or as query parameters of list calls, and the json response is
+// read into the response message. Failed requests fail the call with the
+// grpc status of their http status, their error code is sent in the
+// error-code trailer.
+func (server *rpcServer) call(ctx context.Context, request proto.Message, response proto.Message) error {
+	method, _ := grpc.Method(ctx)
+	route, ok := rpcRoutes[method]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+
+	path := APIVersion + route.Route
+	if identified, ok := request.(interface{ GetId() string }); ok {
+		path = strings.Replace(path, "{id}", url.PathEscape(identified.GetId()), 1)
+	}
+
+	var body []byte
+	var err error
+	if list, ok := request.(*rpc.ListRequest); ok {
+		path = fmt.Sprint(path, "?", listQuery(list).Encode())
+	} else if route.Method != http.MethodGet {
+		body, err = rpcPayload(request)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "malformed request message")
+		}
+	}
+
+	req, err := http.NewRequest(route.Method, path, bytes.NewReader(body))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "malformed id")
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if caller, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = caller.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range rpcMetadata {
+		if values := md.Get(header); len(values) > 0 {
+			req.Header.Set(header, values[0])
+		}
+	}
+
+	code, data := server.service.roundTrip(req)
+	if code >= http.StatusBadRequest {
+		problem := util.Problem{Detail: http.StatusText(code)}
+		json.Unmarshal(data, &problem)
+		if problem.Code != "" {
+			grpc.SetTrailer(ctx, metadata.Pairs("error-code", problem.Code))
+		}
+		return status.Error(rpcCode(code), problem.Detail)
+	}
+
+	if len(data) > 0 {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, response)
+		if err != nil {
+			requestLog(req).Errorf("Failed to read the response of %s: %v", method, err)
+			return status.Error(codes.Internal, util.ErrInternal.Message)
+		}
+	}
+	return nil
+}
+
+// CreateSession runs POST /sessions.
+func (server *rpcServer) CreateSession(ctx context.Context, request *rpc.CreateSessionRequest) (*rpc.Session, error) {
+	response := new(rpc.Session)
+	return response, server.call(ctx, request, response)
+}
+
+// GetSession runs GET /sessions/{id}.
+func (server *rpcServer) GetSession(ctx context.Context, request *rpc.IdRequest) (*rpc.Session, error) {
+	response := new(rpc.Session)
+	return response, server.call(ctx, request, response)
+}
+
+// GetUser runs GET /users/{id}.
+func (server *rpcServer) GetUser(ctx context.Context, request *rpc.IdRequest) (*rpc.User, error) {
+	response := new(rpc.User)
+	return response, server.call(ctx, request, response)
+}
+
+// ListUsers runs GET /users.
+func (server *rpcServer) ListUsers(ctx context.Context, request *rpc.ListRequest) (*rpc.UserList, error) {
+	response := new(rpc.UserList)
+	return response, server.call(ctx, request, response)
+}
+
+// CreateUser runs POST /users.
+func (server *rpcServer) CreateUser(ctx context.Context, request *rpc.CreateUserRequest) (*rpc.User, error) {
+	response := new(rpc.User)
+	return response, server.call(ctx, request, response)
+}
+
+// UpdateUser runs PUT /users/{id}.
+func (server *rpcServer) UpdateUser(ctx context.Context, request *rpc.UpdateUserRequest) (*rpc.User, error) {
+	response := new(rpc.User)
+	return response, server.call(ctx, request, response)
+}
+
+// UpdateUserRole runs PUT /users/{id}/role.
+func (server *rpcServer) UpdateUserRole(ctx context.Context, request *rpc.UpdateRoleRequest) (*rpc.User, error) {
+	response := new(rpc.User)
+	return response, server.call(ctx, request, response)
+}
+
+// DeleteUser runs DELETE /users/{id}.
+func (server *rpcServer) DeleteUser(ctx context.Context, request *rpc.DeleteRequest) (*rpc.Empty, error) {
+	response := new(rpc.Empty)
+	return response, server.call(ctx, request, response)
+}
+
+// GetInvite runs GET /invites/{id}.
+func (server *rpcServer) GetInvite(ctx context.Context, request *rpc.IdRequest) (*rpc.Invite, error) {
+	response := new(rpc.Invite)
+	return response, server.call(ctx, request, response)
+}
+
+// ListInvites runs GET /invites.
+func (server *rpcServer) ListInvites(ctx context.Context, request *rpc.ListRequest) (*rpc.InviteList, error) {
+	response := new(rpc.InviteList)
+	return response, server.call(ctx, request, response)
+}
+
+// CreateInvite runs POST /invites.
+func (server *rpcServer) CreateInvite(ctx context.Context, request *rpc.CreateInviteRequest) (*rpc.Invite, error) {
+	response := new(rpc.Invite)
+	return response, server.call(ctx, request, response)
+}
+
+// UpdateInvite runs PUT /invites/{id}.
+func (server *rpcServer) UpdateInvite(ctx context.Context, request *rpc.UpdateInviteRequest) (*rpc.Invite, error) {
+	response := new(rpc.Invite)
+	return response, server.call(ctx, request, response)
+}
+
+// DeleteInvite runs DELETE /invites/{id}.
+func (server *rpcServer) DeleteInvite(ctx context.Context, request *rpc.DeleteRequest) (*rpc.Empty, error) {
+	response := new(rpc.Empty)
+	return response, server.call(ctx, request, response)
+}
+
+// GetFeedback runs GET /feedback/{id}.
+func (server *rpcServer) GetFeedback(ctx context.Context, request *rpc.IdRequest) (*rpc.Feedback, error) {
+	response := new(rpc.Feedback)
+	return response, server.call(ctx, request, response)
+}
+
+// ListFeedback runs GET /feedback.
+func (server *rpcServer) ListFeedback(ctx context.Context, request *rpc.ListRequest) (*rpc.FeedbackList, error) {
+	response := new(rpc.FeedbackList)
+	return response, server.call(ctx, request, response)
+}
+
+// CreateFeedback runs POST /feedback.
+func (server *rpcServer) CreateFeedback(ctx context.Context, request *rpc.CreateFeedbackRequest) (*rpc.Feedback, error) {
+	response := new(rpc.Feedback)
+	return response, server.call(ctx, request, response)
+}
+
+// UpdateFeedback runs PUT /feedback/{id}.
+func (server *rpcServer) UpdateFeedback(ctx context.Context, request *rpc.UpdateFeedbackRequest) (*rpc.Feedback, error) {
+	response := new(rpc.Feedback)
+	return response, server.call(ctx, request, response)
+}
+
+// GetPassReset runs GET /resets/{id}.
+func (server *rpcServer) GetPassReset(ctx context.Context, request *rpc.IdRequest) (*rpc.PassReset, error) {
+	response := new(rpc.PassReset)
+	return response, server.call(ctx, request, response)
+}
+
+// ListPassResets runs GET /resets.
+func (server *rpcServer) ListPassResets(ctx context.Context, request *rpc.ListRequest) (*rpc.PassResetList, error) {
+	response := new(rpc.PassResetList)
+	return response, server.call(ctx, request, response)
+}
+
+// CreatePassReset runs POST /resets.
+func (server *rpcServer) CreatePassReset(ctx context.Context, request *rpc.CreatePassResetRequest) (*rpc.PassReset, error) {
+	response := new(rpc.PassReset)
+	return response, server.call(ctx, request, response)
+}
+
+// UsePassReset runs PUT /resets/{id}.
+func (server *rpcServer) UsePassReset(ctx context.Context, request *rpc.IdRequest) (*rpc.PassReset, error) {
+	response := new(rpc.PassReset)
+	return response, server.call(ctx, request, response)
+}
@@ -1,21 +1,20 @@
 package service
 
 import (
-	"bytes"
-	"crypto/tls"
 	"encoding/json"
-	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
 	mailgun "github.com/mailgun/mailgun-go"
-	"github.com/metakeule/fmtdate"
 	cmap "github.com/orcaman/concurrent-map"
+	"google.golang.org/grpc"
 
 	"einheit/boltkit/base58"
 	"einheit/boltkit/entity"
+	"einheit/boltkit/trace"
 	"einheit/boltkit/util"
 )
 
@@ -28,32 +27,64 @@ var (
 
 // Service represents the application.
 type Service struct {
-	Bolt       *bolt.DB
-	Cfg        *util.Config
-	SessionMap cmap.ConcurrentMap
-	MailGun    mailgun.Mailgun
-	HTTPClient *http.Client
-	Router     *mux.Router
-	S3         *util.S3Connection
+	Bolt         *entity.DB
+	Cfg          *util.Config
+	SessionMap   cmap.ConcurrentMap
+	MailGun      mailgun.Mailgun
+	HTTPClient   *http.Client
+	Router       *mux.Router
+	S3           *util.S3Connection
+	Replica      *Replica
+	Decoder      *util.Decoder
+	LegacySunset time.Time
+	RateLimiter  *util.RateLimiter
+	Schema       *graphql.Schema
+	RPC          *grpc.Server
+	Events       *util.EventBus
+	LogLevels    *util.LogLevels
+	checks       []dependencyCheck
 }
 
-// NewService initialises the service object. It also establishes all
-// component connections.
+// NewService initialises the service object from the configuration file at
+// the provided path. It also establishes all component connections.
 func NewService(configPath string) (*Service, error) {
+	cfg, err := util.NewConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServiceFromConfig(cfg)
+}
+
+// NewServiceFromConfig initialises the service object from a loaded
+// configuration. It also establishes all component connections.
+func NewServiceFromConfig(cfg *util.Config) (*Service, error) {
 	service := new(Service)
+	service.Cfg = cfg
 	var err error
 
-	// Load the configuration.
-	service.Cfg, err = util.NewConfig(configPath)
+	// Read when the unversioned root aliases are retired.
+	service.LegacySunset, err = parseSunset(service.Cfg.LegacyRouteSunset)
 	if err != nil {
 		return nil, err
 	}
 
+	// Bootstrap a replica from a snapshot of its primary.
+	var snapshotSeq uint64
+	var bootstrapped bool
+	if service.Cfg.ReplicaOf != "" {
+		snapshotSeq, bootstrapped, err = bootstrapReplica(service.Cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Connect to the kv storage.
-	service.Bolt, err = bolt.Open(service.Cfg.Storage, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	db, err := bolt.Open(service.Cfg.Storage, 0600, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
 		return nil, err
 	}
+	service.Bolt = &entity.DB{DB: db, Observe: observeTransaction}
 
 	// Create storage buckets.
 	err = service.createBuckets()
@@ -61,6 +92,35 @@ func NewService(configPath string) (*Service, error) {
 		return nil, err
 	}
 
+	// Index the sortable fields of entities stored before sort indexes.
+	err = entity.BuildSortIndexes(service.Bolt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resume replication unless the replica has been promoted.
+	if service.Cfg.ReplicaOf != "" {
+		if bootstrapped {
+			err = service.CachePut(util.ReplicationKey, util.EncodeSequence(snapshotSeq))
+			if err != nil {
+				return nil, err
+			}
+
+			err = service.Delete(util.CacheBucket, util.PromotedKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = service.CacheGet(util.PromotedKey)
+		if err != nil {
+			service.Replica, err = service.newReplica()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Create the server admin.
 	_, err = service.CacheGet(util.AdminKey)
 	if err != nil {
@@ -90,11 +150,12 @@ func NewService(configPath string) (*Service, error) {
 		return nil, err
 	}
 
-	// Create the http client.
+	// Create the http client of webhook deliveries, it verifies the
+	// certificates of receivers so signed payloads are not intercepted.
 	service.HTTPClient = &http.Client{
 		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
 			MaxIdleConnsPerHost: 2,
-			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
 		},
 		Timeout: time.Second * 10,
 	}
@@ -103,131 +164,93 @@ func NewService(configPath string) (*Service, error) {
 	service.MailGun = mailgun.NewMailgun(service.Cfg.MailgunDomain,
 		service.Cfg.MailgunAPIKey, service.Cfg.MailgunPublicAPIKey)
 
-	// Create the session map.
-	service.SessionMap = cmap.New()
-
-	// Create the router.
-	service.Router = new(mux.Router)
-
-	// Load sessions into memory.
-	err = service.LoadSessions()
+	// Create the tracer.
+	trace.Default, err = newTracer(service.Cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// Empty the session storage.
-	err = service.ClearSessions()
-	if err != nil {
-		return nil, err
-	}
+	// Check the dependencies for readiness.
+	service.addDependencyChecks()
 
-	return service, nil
-}
+	// Create the request decoder.
+	service.Decoder = util.NewDecoder(service.Cfg)
 
-// ValidateRequest asserts the authenticity and requested privileges of a request.
-func (service *Service) ValidateRequest(allowedRoles []string, req *http.Request) (bool, error) {
-	// Get the session token
-	token, err := util.GetSessionToken(req)
+	// Create the graphql schema.
+	service.Schema, err = service.newSchema()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// Assert the session token is valid
-	ok := service.SessionMap.Has(token)
-	if !ok {
-		return false, util.ErrUnauthorizedAccess
+	// Create the grpc server.
+	service.RPC, err = service.newRPCServer()
+	if err != nil {
+		return nil, err
 	}
 
-	// Retrieve the session and assert it has not expired.
-	entry, _ := service.SessionMap.Get(token)
-	session := entry.(entity.Session)
-	if time.Now().Unix() > session.Expiry {
-		service.SessionMap.Remove(token)
-		return false, util.ErrExpiredSession
+	// Create the event bus.
+	history := service.Cfg.EventHistory
+	if history == 0 {
+		history = defaultEventHistory
 	}
+	service.Events = util.NewEventBus(int(history))
 
-	payload := map[string]interface{}{}
+	// Create the log level control, the server hands it the subsystem
+	// loggers.
+	service.LogLevels = util.NewLogLevels(nil)
 
-	// Parse the request
-	req.ParseForm()
-	if req.Body != nil {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			return false, util.ErrMalformedRequest
-		}
+	// Create the session map.
+	service.SessionMap = cmap.New()
 
-		if body != nil {
-			err = json.Unmarshal(body, &payload)
-			if err != nil {
-				return false, util.ErrMalformedPayload
-			}
-		}
+	// Expose the state of the service as metrics.
+	service.registerGauges()
 
-		// Restore the io.ReadCloser to its original state, ie. put back
-		// the bytes read
-		req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	// Create the rate limiter.
+	err = validateRateLimits(service.Cfg.RateLimits)
+	if err != nil {
+		return nil, err
 	}
+	service.RateLimiter = util.NewRateLimiter()
 
-	origin := req.RemoteAddr
-	route := req.URL.Path
-	requestType := req.Method
-	reqLog := entity.RequestLog{
-		Origin:      origin,
-		Requestor:   token,
-		RequestType: requestType,
-		Route:       route,
-		QueryParams: req.Form.Encode(),
-		Payload:     payload,
-	}
+	// Create the router.
+	service.Router = new(mux.Router)
+	service.useMiddleware()
+	service.Router.NotFoundHandler = http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		util.RespondWithError(writer, util.ErrRouteNotFound)
+	})
 
-	logBytes, err := json.Marshal(reqLog)
+	// Load sessions into memory.
+	err = service.LoadSessions()
 	if err != nil {
-		return false, util.ErrMalformedPayload
+		return nil, err
 	}
 
-	// Log the request.
-	err = service.Bolt.Update(func(tx *bolt.Tx) error {
-		dateStr := fmtdate.Format(util.DateFormat, time.Now())
-		logBucket := tx.Bucket(util.LogBucket)
-		dayBucket, err := logBucket.CreateBucketIfNotExists([]byte(dateStr))
-		if err != nil {
-			return err
-		}
+	// Empty the session storage.
+	err = service.ClearSessions()
+	if err != nil {
+		return nil, err
+	}
 
-		tokenBucket, err := dayBucket.CreateBucketIfNotExists([]byte(token))
+	// Resume the rate limits persisted on shutdown.
+	if service.Cfg.PersistRateLimits {
+		err = service.LoadRateLimits()
 		if err != nil {
-			return err
-		}
-
-		err = tokenBucket.Put(logBytes, nil)
-		return err
-	})
-
-	// Assert the calling user has the required access for the endpoint.
-	granted := false
-	switch session.Access {
-	case util.Admin:
-		granted = true
-	default:
-		for _, role := range allowedRoles {
-			if role == session.Access {
-				granted = true
-				break
-			}
-		}
-		if !granted {
-			err = util.ErrUnauthorizedAccess
+			return nil, err
 		}
 	}
 
-	// Extend session expiry by one minute for every successful validation.
-	if granted {
-		curExpiry := time.Unix(session.Expiry, 0)
-		session.Expiry = util.GetFutureTime(curExpiry, 0, 0, 1, 0).Unix()
-		service.SessionMap.Set(session.Token, session)
+	return service, nil
+}
+
+// requestor returns the id of the user making the request, or an empty string
+// if the request has no valid session.
+func (service *Service) requestor(req *http.Request) string {
+	session, ok := SessionFrom(req)
+	if !ok {
+		return ""
 	}
 
-	return granted, err
+	return session.User
 }
 
 // ClearSessions deletes all kv entries in the session bucket.
@@ -357,6 +380,48 @@ func (service *Service) createBuckets() error {
 			return err
 		}
 
+		_, err = tx.CreateBucketIfNotExists(util.HistoryBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.HistoryBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.ChangeBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.ChangeBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.WebhookBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.WebhookBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.DeliveryBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.DeliveryBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.RateLimitBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.RateLimitBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.IdempotencyBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.IdempotencyBucket))
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(util.IndexBucket)
+		if err != nil {
+			log.Errorf("failed to create bucket %s", string(util.IndexBucket))
+			return err
+		}
+
 		return err
 	})
 	return err
@@ -401,6 +466,7 @@ func (service *Service) createAdmin(payload map[string]interface{}) (*entity.Use
 		Email:        email,
 		Role:         util.Admin,
 		Invite:       "-",
+		ModifiedBy:   util.System,
 	}
 
 	err = user.Update(service.Bolt)
@@ -440,11 +506,13 @@ func (service *Service) CacheGet(id []byte) ([]byte, error) {
 	var v []byte
 	err := service.Bolt.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(util.CacheBucket)
-		v = bucket.Get(id)
-		if v == nil {
+		value := bucket.Get(id)
+		if value == nil {
 			return util.ErrKeyNotFound(string(id))
 		}
 
+		// Copy the value, it is only valid for the life of the transaction.
+		v = append([]byte{}, value...)
 		return nil
 	})
 	return v, err
@@ -461,9 +529,8 @@ func (service *Service) Delete(bucket, key []byte) error {
 
 // Route wires up all API endpoints with their respective handlers.
 func (service *Service) SetupRoutes() {
-	CreateInviteRoutes(service.Router)
-	CreateUserRoutes(service.Router)
-	CreateFeedbackRoutes(service.Router)
-	CreatePassResetRoutes(service.Router)
-	CreateSessionRoutes(service.Router)
+	CreateOpenAPIRoutes(service.Router)
+	CreateHealthRoutes(service.Router)
+	CreateMetricsRoutes(service.Router)
+	service.mountVersions()
 }
@@ -4,8 +4,6 @@ import (
 	"einheit/access/base58"
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
-	"encoding/json"
-	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -19,11 +17,17 @@ func CreateSessionRoutes(router *mux.Router) {
 	router.HandleFunc("/sessions", App.CreateSession).Methods(http.MethodPost)
 }
 
+// createSessionRequest is the payload of a login.
+type createSessionRequest struct {
+	Email    string `json:"email" validate:"required,nonempty"`
+	Password string `json:"password" validate:"required,nonempty"`
+}
+
 func (service *Service) GetSession(writer http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	session, err := entity.GetSession(vars["id"], App.SessionMap)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
@@ -32,45 +36,28 @@ func (service *Service) GetSession(writer http.ResponseWriter, req *http.Request
 }
 
 func (service *Service) CreateSession(writer http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-		return
-	}
-	if len(body) == 0 {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-		return
-	}
-
-	payload := map[string]interface{}{}
-	err = json.Unmarshal(body, &payload)
+	payload := createSessionRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	email, ok := payload["email"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("email"))
-		return
-	}
-
-	password, ok := payload["password"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("password"))
-		return
-	}
+	email := payload.Email
+	password := payload.Password
 
 	// Assert the requesting user exists and the supplied password matches.
 	emailB58 := base58.Encode([]byte(email))
-	user, err := entity.GetUser([]byte(emailB58), service.Bolt)
+	user, err := entity.GetUser([]byte(emailB58), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		logins.Inc(loginFailure)
+		util.RespondWithError(writer, err)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrUnauthorizedAccess)
+		logins.Inc(loginFailure)
+		util.RespondWithError(writer, util.ErrUnauthorizedAccess)
 		return
 	}
 
@@ -84,9 +71,15 @@ func (service *Service) CreateSession(writer http.ResponseWriter, req *http.Requ
 		CreatedOn: now.Unix(),
 		Expiry:    util.GetFutureTime(time.Now(), 0, 2, 0, 0).Unix(),
 	}
-	user.LastLogin = now.Unix()
 	session.Update(App.SessionMap)
-	user.Update(service.Bolt)
+	logins.Inc(loginSuccess)
+	// A read-only replica does not record logins, its users are replicated.
+	if !service.ReadOnly() {
+		err = user.TouchLogin(now.Unix(), service.store(req))
+		if err != nil {
+			requestLog(req).Errorf("failed to record login: %v", err)
+		}
+	}
 	util.RespondWithJSON(writer, http.StatusCreated, session)
 	return
 }
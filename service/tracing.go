@@ -0,0 +1,84 @@
+package service
+
+import (
+	"einheit/boltkit/trace"
+	"einheit/boltkit/util"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// Trace exporters.
+	stdoutExporter = "stdout"
+	fileExporter   = "file"
+	otlpExporter   = "otlp"
+
+	// defaultTraceEndpoint is the otlp/http traces endpoint of a local
+	// collector.
+	defaultTraceEndpoint = "http://localhost:4318/v1/traces"
+
+	// traceExportTimeout is how long an otlp export may take.
+	traceExportTimeout = 10 * time.Second
+)
+
+// newTracer creates the tracer of the configured exporter, the default
+// tracer when tracing is not configured.
+func newTracer(cfg *util.Config) (*trace.Tracer, error) {
+	var exporter trace.Exporter
+	switch cfg.TraceExporter {
+	case "":
+		return trace.Default, nil
+	case stdoutExporter:
+		// Wrapped so shutting down the exporter leaves stdout open.
+		exporter = trace.NewWriterExporter(struct{ io.Writer }{os.Stdout})
+	case fileExporter:
+		file, err := os.OpenFile(cfg.TraceEndpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		exporter = trace.NewWriterExporter(file)
+	case otlpExporter:
+		endpoint := cfg.TraceEndpoint
+		if endpoint == "" {
+			endpoint = defaultTraceEndpoint
+		}
+		exporter = trace.NewOTLPExporter(endpoint, &http.Client{Timeout: traceExportTimeout})
+	default:
+		return nil, util.ErrInvalidParameterOption("traceexporter", cfg.TraceExporter,
+			fmt.Sprintf("%s, %s or %s", stdoutExporter, fileExporter, otlpExporter))
+	}
+
+	sampleRate := cfg.TraceSampleRate
+	if sampleRate <= 0 {
+		sampleRate = 1
+	}
+	return trace.NewTracer(cfg.Server, exporter, sampleRate), nil
+}
+
+// traceRequest runs a request in a server span, continuing the trace of its
+// traceparent header if any.
+func (service *Service) traceRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		route := routeOf(req)
+
+		ctx := trace.Extract(req.Context(), req.Header)
+		ctx, span := trace.Start(ctx, fmt.Sprint(req.Method, " ", route), trace.KindServer)
+		defer span.Finish()
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", req.URL.RequestURI())
+		span.SetAttribute("request.id", RequestID(req))
+
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", recorder.status)
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(recorder.status)))
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func CreateTrashRoutes(router *mux.Router) {
+	router.HandleFunc("/trash/users", App.Authorize(App.ListDeletedUsers, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/trash/users/{id}", App.Authorize(App.RestoreDeletedUser, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/trash/users/{id}", App.Authorize(App.PurgeUser, util.Admin)).Methods(http.MethodDelete)
+	router.HandleFunc("/trash/invites", App.Authorize(App.ListDeletedInvites, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/trash/invites/{id}", App.Authorize(App.RestoreDeletedInvite, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/trash/invites/{id}", App.Authorize(App.PurgeInvite, util.Admin)).Methods(http.MethodDelete)
+}
+
+func (service *Service) ListDeletedUsers(writer http.ResponseWriter, req *http.Request) {
+	payload := pageRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	offset := *payload.Offset
+
+	users, err := entity.ListDeletedUsers(service.store(req), service.Cfg.PageLimit, offset)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	service.respondWithPage(writer, users, len(*users), offset)
+	return
+}
+
+func (service *Service) RestoreDeletedUser(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	user, err := entity.GetUser([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if !user.Deleted {
+		util.RespondWithError(writer, util.ErrNotDeleted)
+		return
+	}
+
+	user.ModifiedBy = service.requestor(req)
+	err = user.Delete(false, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
+}
+
+func (service *Service) PurgeUser(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	user, err := entity.GetUser([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if !user.Deleted {
+		util.RespondWithError(writer, util.ErrNotDeleted)
+		return
+	}
+
+	err = user.Purge(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNoContent)
+	return
+}
+
+func (service *Service) ListDeletedInvites(writer http.ResponseWriter, req *http.Request) {
+	payload := pageRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	offset := *payload.Offset
+
+	invites, err := entity.ListDeletedInvites(service.store(req), service.Cfg.PageLimit, offset)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	service.respondWithPage(writer, invites, len(*invites), offset)
+	return
+}
+
+func (service *Service) RestoreDeletedInvite(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	invite, err := entity.GetInvite([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if !invite.Deleted {
+		util.RespondWithError(writer, util.ErrNotDeleted)
+		return
+	}
+
+	invite.ModifiedBy = service.requestor(req)
+	err = invite.Delete(false, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, invite)
+	return
+}
+
+func (service *Service) PurgeInvite(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	invite, err := entity.GetInvite([]byte(params["id"]), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	if !invite.Deleted {
+		util.RespondWithError(writer, util.ErrNotDeleted)
+		return
+	}
+
+	err = invite.Purge(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNoContent)
+	return
+}
@@ -4,9 +4,6 @@ import (
 	"einheit/access/base58"
 	"einheit/boltkit/entity"
 	"einheit/boltkit/util"
-	"encoding/json"
-	"errors"
-	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -15,112 +12,106 @@ import (
 )
 
 func CreateUserRoutes(router *mux.Router) {
-	router.HandleFunc("/users/{id}", App.GetUser).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}", App.Authorize(App.GetUser, util.Admin)).Methods(http.MethodGet)
 	router.HandleFunc("/users", App.CreateUser).Methods(http.MethodPost)
-	router.HandleFunc("/users/{id}", App.UpdateUserDetails).Methods(http.MethodPut)
-	router.HandleFunc("/users/{id}/resetpassword", App.ResetUserPassword).Methods(http.MethodPut)
-	router.HandleFunc("/users/{id}/role", App.UpdateUserRole).Methods(http.MethodPut)
-	router.HandleFunc("/users/{id}", App.DeleteUser).Methods(http.MethodDelete)
-	router.HandleFunc("/users/list", App.ListUsers).Methods(http.MethodPost)
+	router.HandleFunc("/users", App.Authorize(App.QueryUsers, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}", App.Authorize(App.UpdateUserDetails, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}/resetpassword", App.Authorize(App.ResetUserPassword, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}/role", App.Authorize(App.UpdateUserRole, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/users/{id}", App.Authorize(App.DeleteUser, util.Admin)).Methods(http.MethodDelete)
+	router.HandleFunc("/users/list", App.Authorize(App.ListUsers, util.Admin)).Methods(http.MethodPost)
 }
 
-func (service *Service) GetUser(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
-	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
-		return
-	}
+// createUserRequest is the payload of a user registration from an invite.
+type createUserRequest struct {
+	Invite    string `json:"invite" validate:"required,nonempty"`
+	FirstName string `json:"firstName" validate:"required,nonempty"`
+	LastName  string `json:"lastName" validate:"required,nonempty"`
+	Password  string `json:"password" validate:"required,nonempty"`
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"required,oneof=admin management finance"`
+}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		user, err := entity.GetUser([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+// resetPasswordRequest is the payload of a password reset.
+type resetPasswordRequest struct {
+	Password string `json:"password" validate:"required,nonempty"`
+	ResetId  string `json:"resetId" validate:"required,nonempty"`
+}
 
-		user.Sanitize()
-		util.RespondWithJSON(writer, http.StatusOK, user)
-		return
-	}
+// updateRoleRequest is the payload of a user role update.
+type updateRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin management finance"`
 }
 
-func (service *Service) CreateUser(writer http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-		return
-	}
-	if len(body) == 0 {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-		return
-	}
+// updateUserRequest is the payload of a user details update, absent fields
+// are left unchanged. A new password is expected with the current password.
+type updateUserRequest struct {
+	FirstName       string  `json:"firstName"`
+	LastName        string  `json:"lastName"`
+	NewPassword     *string `json:"newPassword"`
+	CurrentPassword *string `json:"currentPassword"`
+}
 
-	payload := map[string]interface{}{}
-	err = json.Unmarshal(body, &payload)
+func (service *Service) GetUser(writer http.ResponseWriter, req *http.Request) {
+	view, err := util.ParseView(req.URL.Query(), entity.User{}, entity.UserRelations.Names())
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	inviteRef, ok := payload["invite"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("invite"))
+	params := mux.Vars(req)
+	id := params["id"]
+	user, err := entity.GetUser([]byte(id), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	firstName, ok := payload["firstName"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("firstName"))
+	user.Sanitize()
+	rendered, err := service.render(service.store(req), view, entity.UserRelations, user)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	lastName, ok := payload["lastName"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("lastName"))
-		return
-	}
+	util.RespondWithJSON(writer, http.StatusOK, rendered[0])
+	return
+}
 
-	password, ok := payload["password"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("password"))
+func (service *Service) CreateUser(writer http.ResponseWriter, req *http.Request) {
+	payload := createUserRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	email, ok := payload["email"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("email"))
+	inviteRef := payload.Invite
+	email := payload.Email
+	invite, err := entity.GetInvite([]byte(inviteRef), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	role, ok := payload["role"].(string)
-	if !ok {
-		util.RespondWithError(writer, http.StatusBadRequest,
-			util.ErrKeyNotFound("role"))
+	if invite.Email != email {
+		util.RespondWithError(writer, util.ErrInviteMismatch)
 		return
 	}
 
-	invite, err := entity.GetInvite([]byte(inviteRef), service.Bolt)
-	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
-	}
-
-	if invite.Email != email {
-		util.RespondWithError(writer, http.StatusBadRequest,
-			errors.New("invite not associated with user being created"))
+	if invite.Status != entity.Pending || invite.Deleted {
+		util.RespondWithError(writer, util.ErrInviteUnavailable)
 		return
 	}
 
 	if invite.Expiry < time.Now().Unix() {
-		util.RespondWithError(writer, http.StatusBadRequest,
-			errors.New("invite expired"))
+		util.RespondWithError(writer, util.ErrExpiredInvite)
 		return
 	}
 
-	hashedPassword, err := util.BcryptHash(password)
+	hashedPassword, err := util.BcryptHash(payload.Password)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, util.ErrBcryptHash)
+		util.RespondWithError(writer, util.ErrBcryptHash)
 		return
 	}
 
@@ -131,352 +122,276 @@ func (service *Service) CreateUser(writer http.ResponseWriter, req *http.Request
 		LastModified: 0,
 		CreatedOn:    now.Unix(),
 		Deleted:      false,
-		FirstName:    firstName,
-		LastName:     lastName,
+		FirstName:    payload.FirstName,
+		LastName:     payload.LastName,
 		Password:     hashedPassword,
 		Email:        email,
-		Role:         role,
+		Role:         payload.Role,
 		Invite:       inviteRef,
+		ModifiedBy:   base58.Encode([]byte(email)),
 	}
 
-	err = user.Update(service.Bolt)
+	err = user.Update(service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	// Mark the invite as accepted.
+	invite.Status = entity.Accepted
+	invite.LastModified = now.Unix()
+	invite.ModifiedBy = user.Uuid
+	err = invite.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
 
 	user.Sanitize()
+	service.Notify(entity.InviteAcceptedEvent, invite)
+	service.Notify(entity.UserCreatedEvent, user)
 	util.RespondWithJSON(writer, http.StatusCreated, user)
 	return
 }
 
 func (service *Service) ResetUserPassword(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	user, err := entity.GetUser([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		user, err := entity.GetUser([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		password, ok := payload["password"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("password"))
-			return
-		}
-
-		resetId, ok := payload["resetId"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("resetId"))
-			return
-		}
-
-		reset, err := entity.GetPassReset([]byte(resetId), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	payload := resetPasswordRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		if reset.Used {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrResetUsed)
-			return
-		}
+	reset, err := entity.GetPassReset([]byte(payload.ResetId), service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		if reset.Expiry < time.Now().Unix() {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrExpiredReset)
-			return
-		}
+	if reset.Used {
+		util.RespondWithError(writer, util.ErrResetUsed)
+		return
+	}
 
-		hashedPassword, err := util.BcryptHash(password)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrBcryptHash)
-			return
-		}
+	if reset.Expiry < time.Now().Unix() {
+		util.RespondWithError(writer, util.ErrExpiredReset)
+		return
+	}
 
-		now := time.Now()
-		user.LastModified = now.Unix()
-		user.Password = hashedPassword
+	hashedPassword, err := util.BcryptHash(payload.Password)
+	if err != nil {
+		util.RespondWithError(writer, util.ErrBcryptHash)
+		return
+	}
 
-		err = user.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	now := time.Now()
+	user.LastModified = now.Unix()
+	user.ModifiedBy = service.requestor(req)
+	user.Password = hashedPassword
 
-		user.Sanitize()
-		util.RespondWithJSON(writer, http.StatusOK, user)
+	err = user.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
 }
 
 func (service *Service) UpdateUserRole(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	user, err := entity.GetUser([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		user, err := entity.GetUser([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		role, ok := payload["role"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("role"))
-			return
-		}
-
-		now := time.Now()
-		user.LastModified = now.Unix()
-		user.Role = role
-		err = user.Update(service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	payload := updateRoleRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		user.Sanitize()
-		util.RespondWithJSON(writer, http.StatusOK, user)
+	now := time.Now()
+	user.LastModified = now.Unix()
+	user.ModifiedBy = service.requestor(req)
+	user.Role = payload.Role
+	err = user.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
 }
 
 func (service *Service) UpdateUserDetails(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	user, err := entity.GetUser([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		user, err := entity.GetUser([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
-
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
+	payload := updateUserRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
+	firstName := payload.FirstName
+	lastName := payload.LastName
 
-		firstName, _ := payload["firstName"].(string)
-		lastName, _ := payload["lastName"].(string)
+	newPasswordOk := payload.NewPassword != nil
+	currentPasswordOk := payload.CurrentPassword != nil
+	newPassword, currentPassword := "", ""
+	if newPasswordOk {
+		newPassword = *payload.NewPassword
+	}
+	if currentPasswordOk {
+		currentPassword = *payload.CurrentPassword
+	}
 
-		newPassword, newPasswordOk := payload["newPassword"].(string)
-		currentPassword, currentPasswordOk := payload["currentPassword"].(string)
+	if firstName == "" && lastName == "" && newPassword == "" && currentPassword == "" {
+		util.RespondWithError(writer, util.ErrNoUpdate)
+		return
+	}
 
-		if firstName == "" && lastName == "" && newPassword == "" && currentPassword == "" {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				util.ErrNoUpdate)
-			return
-		}
+	if (newPasswordOk && !currentPasswordOk) || (!newPasswordOk && currentPasswordOk) {
+		util.RespondWithError(writer, util.ErrParameterGroup([]string{"newPassword ", "currentPassword"}))
+		return
+	}
 
-		if (newPasswordOk && !currentPasswordOk) || (!newPasswordOk && currentPasswordOk) {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrParameterGroup([]string{"newPassword ", "currentPassword"}))
-			return
-		}
+	if newPasswordOk && newPassword == "" {
+		util.RespondWithError(writer, util.ErrInvalidParameter("newPassword"))
+		return
+	}
 
-		if newPasswordOk && newPassword == "" {
-			util.RespondWithError(writer, http.StatusBadRequest,
-				util.ErrInvalidParameter("newPassword"))
+	if newPasswordOk && currentPasswordOk {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+			util.RespondWithError(writer, util.ErrPasswordMismatch)
 			return
 		}
+	}
 
-		if newPasswordOk && currentPasswordOk {
-			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
-				util.RespondWithError(writer, http.StatusBadRequest, util.ErrPasswordMismatch)
-				return
-			}
-		}
-
-		now := time.Now()
-		user.LastModified = now.Unix()
+	now := time.Now()
+	user.LastModified = now.Unix()
+	user.ModifiedBy = service.requestor(req)
 
-		if firstName != "" {
-			user.FirstName = firstName
-		}
+	if firstName != "" {
+		user.FirstName = firstName
+	}
 
-		if lastName != "" {
-			user.LastName = lastName
-		}
+	if lastName != "" {
+		user.LastName = lastName
+	}
 
-		if newPassword != "" {
-			user.Password, err = util.BcryptHash(newPassword)
-			if err != nil {
-				util.RespondWithError(writer, http.StatusBadRequest, util.ErrBcryptHash)
-				return
-			}
-		}
-		err = user.Update(service.Bolt)
+	if newPassword != "" {
+		user.Password, err = util.BcryptHash(newPassword)
 		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
+			util.RespondWithError(writer, util.ErrBcryptHash)
 			return
 		}
-
-		user.Sanitize()
-		util.RespondWithJSON(writer, http.StatusOK, user)
+	}
+	err = user.Update(service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	user.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, user)
+	return
 }
 
 func (service *Service) DeleteUser(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	params := mux.Vars(req)
+	id := params["id"]
+	user, err := entity.GetUser([]byte(id), service.store(req))
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		params := mux.Vars(req)
-		id := params["id"]
-		user, err := entity.GetUser([]byte(id), service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+	payload := deleteRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
+	user.ModifiedBy = service.requestor(req)
+	err = user.Delete(*payload.Deleted, service.store(req))
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNoContent)
+	return
+}
 
-		deleted, _ := payload["deleted"].(bool)
-		err = user.Delete(deleted, service.Bolt)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+func (service *Service) QueryUsers(writer http.ResponseWriter, req *http.Request) {
+	query, err := entity.UserQuery.Parse(req.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		writer.Header().Set("Content-Type", "application/json")
-		writer.WriteHeader(http.StatusNoContent)
+	view, err := util.ParseView(req.URL.Query(), entity.User{}, entity.UserRelations.Names())
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithUsers(writer, req, query, view)
+	return
 }
 
+// ListUsers is the search term only variant of QueryUsers.
 func (service *Service) ListUsers(writer http.ResponseWriter, req *http.Request) {
-	granted, err := service.ValidateRequest([]string{util.Admin}, req)
+	payload := listRequest{}
+	err := service.decode(req, &payload)
 	if err != nil {
-		util.RespondWithError(writer, http.StatusBadRequest, err)
+		util.RespondWithError(writer, err)
 		return
 	}
 
-	if granted {
-		body, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrReadBody)
-			return
-		}
-		if len(body) == 0 {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedPayload)
-			return
-		}
-
-		payload := map[string]interface{}{}
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrMalformedJSON)
-			return
-		}
-
-		term, ok := payload["term"].(string)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("term"))
-			return
-		}
-
-		offset, ok := payload["offset"].(float64)
-		if !ok {
-			util.RespondWithError(writer, http.StatusBadRequest, util.ErrKeyNotFound("offset"))
-			return
-		}
+	service.respondWithUsers(writer, req, entity.UserQuery.NewQuery(payload.Term, *payload.Offset), nil)
+	return
+}
 
-		users, err := entity.ListUsers(service.Bolt, service.Cfg.PageLimit, term, uint32(offset))
-		if err != nil {
-			util.RespondWithError(writer, http.StatusBadRequest, err)
-			return
-		}
+// respondWithUsers responds with the page of users matching the query, shaped
+// by the view.
+func (service *Service) respondWithUsers(writer http.ResponseWriter, req *http.Request, query *util.Query, view *util.View) {
+	users, err := entity.QueryUsers(service.store(req), service.Cfg.PageLimit, query)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
 
-		meta := map[string]interface{}{}
-		meta["count"] = len(*users)
-		meta["offset"] = uint32(offset)
-		meta["pagesize"] = service.Cfg.PageLimit
-		response := map[string]interface{}{}
-		response["meta"] = meta
-		response["results"] = users
-		util.RespondWithJSON(writer, http.StatusOK, response)
+	results, err := service.renderList(service.store(req), view, entity.UserRelations, users)
+	if err != nil {
+		util.RespondWithError(writer, err)
 		return
 	}
+
+	service.respondWithPage(writer, results, len(*users), query.Offset)
 }
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"einheit/boltkit/util"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// APIVersion is the path prefix of the current api version.
+	APIVersion = "/v1"
+
+	// sunsetDateFormat is the date format of configured sunsets.
+	sunsetDateFormat = "2006-01-02"
+)
+
+var versionRegex = regexp.MustCompile(`^/v[0-9]+/`)
+
+// apiVersion describes the routes mounted under the prefix of an api version.
+// Versions share handlers, a version lists the route groups it keeps from
+// the version before along with the groups it changes.
+type apiVersion struct {
+	Prefix string
+	Routes []func(router *mux.Router)
+}
+
+// apiVersions are the api versions served, the last being the current
+// version aliased at the root until the configured sunset.
+var apiVersions = []apiVersion{
+	{
+		Prefix: "/v1",
+		Routes: []func(router *mux.Router){
+			CreateInviteRoutes,
+			CreateUserRoutes,
+			CreateFeedbackRoutes,
+			CreatePassResetRoutes,
+			CreateSessionRoutes,
+			CreateRequestLogRoutes,
+			CreateHistoryRoutes,
+			CreateTrashRoutes,
+			CreateChangeRoutes,
+			CreateWebhookRoutes,
+			CreateReplicationRoutes,
+			CreateBatchRoutes,
+			CreateGraphQLRoutes,
+			CreateEventRoutes,
+			CreateLogLevelRoutes,
+		},
+	},
+}
+
+// mountVersions wires up the routes of every api version under its prefix,
+// along with the root aliases of the current version.
+func (service *Service) mountVersions() {
+	for _, version := range apiVersions {
+		router := service.Router.PathPrefix(version.Prefix).Subrouter()
+		for _, createRoutes := range version.Routes {
+			createRoutes(router)
+		}
+	}
+
+	// Root aliases predate versioning, they are deprecated in favour of
+	// their versioned successors.
+	current := apiVersions[len(apiVersions)-1]
+	legacy := service.Router.NewRoute().Subrouter()
+	legacy.Use(service.deprecateAlias(current.Prefix))
+	for _, createRoutes := range current.Routes {
+		createRoutes(legacy)
+	}
+}
+
+// deprecateAlias marks responses of root aliases deprecated, linking to the
+// route of the provided version succeeding them.
+func (service *Service) deprecateAlias(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			successor := prefix + req.URL.Path
+			if req.URL.RawQuery != "" {
+				successor = fmt.Sprint(successor, "?", req.URL.RawQuery)
+			}
+
+			Deprecate(next.ServeHTTP, service.LegacySunset, successor)(writer, req)
+		})
+	}
+}
+
+// Deprecate marks the responses of a handler deprecated, linking to the
+// successor of the route when provided. A non-zero sunset is announced to
+// clients, once it passes the route responds as gone.
+func Deprecate(handler http.HandlerFunc, sunset time.Time, successor string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Set("Deprecation", "true")
+		if successor != "" {
+			writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+
+		if !sunset.IsZero() {
+			writer.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			if time.Now().After(sunset) {
+				util.RespondWithError(writer, util.ErrRouteRetired(successor))
+				return
+			}
+		}
+
+		handler(writer, req)
+	}
+}
+
+// parseSunset reads a configured sunset date, an empty date is no sunset.
+func parseSunset(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	sunset, err := time.Parse(sunsetDateFormat, date)
+	if err != nil {
+		return time.Time{}, util.ErrInvalidParameterOption("legacyroutesunset", date, sunsetDateFormat)
+	}
+	return sunset, nil
+}
+
+// unversioned strips the version prefix of a route template.
+func unversioned(template string) string {
+	return versionRegex.ReplaceAllString(template, "/")
+}
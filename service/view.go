@@ -0,0 +1,45 @@
+package service
+
+import (
+	"einheit/boltkit/entity"
+	"einheit/boltkit/util"
+	"reflect"
+)
+
+// render shapes entities, pointers to entities, by the view of the response
+// and embeds their included relations, read through the store of the
+// request.
+func (service *Service) render(store entity.Store, view *util.View, relations entity.Relations, entities ...interface{}) ([]interface{}, error) {
+	if view.Empty() {
+		return entities, nil
+	}
+
+	related, err := relations.Resolve(store, view.Include, entities)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]interface{}, len(entities))
+	for idx, current := range entities {
+		rendered[idx], err = view.Render(current, related[idx])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// renderList shapes a pointer to a slice of entities by the view of the
+// response.
+func (service *Service) renderList(store entity.Store, view *util.View, relations entity.Relations, list interface{}) (interface{}, error) {
+	if view.Empty() {
+		return list, nil
+	}
+
+	slice := reflect.ValueOf(list).Elem()
+	entities := make([]interface{}, slice.Len())
+	for idx := range entities {
+		entities[idx] = slice.Index(idx).Addr().Interface()
+	}
+	return service.render(store, view, relations, entities...)
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/trace"
+	"einheit/boltkit/util"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// defaultWebhookMaxAttempts is the number of delivery attempts made when
+	// none is configured.
+	defaultWebhookMaxAttempts = 8
+
+	// defaultWebhookRetryDelay is the delay in seconds before the first retry
+	// of a delivery when none is configured, it doubles with every attempt.
+	defaultWebhookRetryDelay = 30
+)
+
+// createWebhookRequest is the payload of a webhook registration.
+type createWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,nonempty"`
+	Secret string   `json:"secret"`
+}
+
+// updateWebhookRequest is the payload of a webhook update, absent fields are
+// left unchanged.
+type updateWebhookRequest struct {
+	URL    string   `json:"url" validate:"url"`
+	Events []string `json:"events" validate:"nonempty"`
+	Secret string   `json:"secret"`
+}
+
+func CreateWebhookRoutes(router *mux.Router) {
+	router.HandleFunc("/webhooks/{id}", App.Authorize(App.GetWebhook, util.Admin)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks", App.Authorize(App.CreateWebhook, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}", App.Authorize(App.UpdateWebhook, util.Admin)).Methods(http.MethodPut)
+	router.HandleFunc("/webhooks/{id}", App.Authorize(App.DeleteWebhook, util.Admin)).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/list", App.Authorize(App.ListWebhooks, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}/deliveries", App.Authorize(App.ListDeliveries, util.Admin)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}/deliveries/{delivery}/redeliver", App.Authorize(App.Redeliver, util.Admin)).Methods(http.MethodPost)
+}
+
+func (service *Service) GetWebhook(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	webhook, err := entity.GetWebhook([]byte(params["id"]), service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	webhook.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, webhook)
+	return
+}
+
+func (service *Service) CreateWebhook(writer http.ResponseWriter, req *http.Request) {
+	payload := createWebhookRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	webhookURL := payload.URL
+	events, err := supportedWebhookEvents(payload.Events)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	// Generate a signing secret if none was supplied.
+	secret := payload.Secret
+	if secret == "" {
+		secret, err = newWebhookSecret()
+		if err != nil {
+			util.RespondWithError(writer, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	webhook := entity.Webhook{
+		Uuid:         ksuid.New().String(),
+		URL:          webhookURL,
+		Events:       events,
+		Secret:       secret,
+		LastModified: 0,
+		ModifiedBy:   service.requestor(req),
+		CreatedOn:    now.Unix(),
+		Deleted:      false,
+	}
+
+	err = webhook.Update(service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	// The secret is only ever returned on creation.
+	util.RespondWithJSON(writer, http.StatusCreated, webhook)
+	return
+}
+
+func (service *Service) UpdateWebhook(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	webhook, err := entity.GetWebhook([]byte(params["id"]), service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	payload := updateWebhookRequest{}
+	err = service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	webhookURL := payload.URL
+	secret := payload.Secret
+	eventsOk := payload.Events != nil
+
+	if webhookURL == "" && secret == "" && !eventsOk {
+		util.RespondWithError(writer, util.ErrNoUpdate)
+		return
+	}
+
+	if webhookURL != "" {
+		webhook.URL = webhookURL
+	}
+
+	if eventsOk {
+		webhook.Events, err = supportedWebhookEvents(payload.Events)
+		if err != nil {
+			util.RespondWithError(writer, err)
+			return
+		}
+	}
+
+	if secret != "" {
+		webhook.Secret = secret
+	}
+
+	webhook.LastModified = time.Now().Unix()
+	webhook.ModifiedBy = service.requestor(req)
+	err = webhook.Update(service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	webhook.Sanitize()
+	util.RespondWithJSON(writer, http.StatusOK, webhook)
+	return
+}
+
+func (service *Service) DeleteWebhook(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	webhook, err := entity.GetWebhook([]byte(params["id"]), service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	webhook.ModifiedBy = service.requestor(req)
+	err = webhook.Delete(true, service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusNoContent)
+	return
+}
+
+func (service *Service) ListWebhooks(writer http.ResponseWriter, req *http.Request) {
+	payload := listRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	term := payload.Term
+	offset := *payload.Offset
+
+	webhooks, err := entity.ListWebhooks(service.Bolt, service.Cfg.PageLimit, term, offset)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	meta := map[string]interface{}{}
+	meta["count"] = len(*webhooks)
+	meta["offset"] = offset
+	meta["pagesize"] = service.Cfg.PageLimit
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = webhooks
+	util.RespondWithJSON(writer, http.StatusOK, response)
+	return
+}
+
+func (service *Service) ListDeliveries(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	payload := pageRequest{}
+	err := service.decode(req, &payload)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	offset := *payload.Offset
+
+	deliveries, err := entity.ListDeliveries(service.Bolt, service.Cfg.PageLimit, params["id"], offset)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	meta := map[string]interface{}{}
+	meta["count"] = len(*deliveries)
+	meta["offset"] = offset
+	meta["pagesize"] = service.Cfg.PageLimit
+	response := map[string]interface{}{}
+	response["meta"] = meta
+	response["results"] = deliveries
+	util.RespondWithJSON(writer, http.StatusOK, response)
+	return
+}
+
+// Redeliver immediately attempts a delivery again, regardless of its state.
+func (service *Service) Redeliver(writer http.ResponseWriter, req *http.Request) {
+	params := mux.Vars(req)
+	delivery, err := entity.GetDelivery([]byte(params["id"]), []byte(params["delivery"]), service.Bolt)
+	if err != nil {
+		util.RespondWithError(writer, err)
+		return
+	}
+
+	// Reset the attempt count so the redelivery gets a fresh set of
+	// retries should it fail.
+	delivery.Status = entity.DeliveryPending
+	delivery.Attempts = 0
+	err = service.Deliver(delivery)
+	if err != nil {
+		log.Errorf("redelivery of %s failed: %v", delivery.Uuid, err)
+	}
+
+	util.RespondWithJSON(writer, http.StatusOK, delivery)
+	return
+}
+
+// Notify publishes the event to the event stream and queues a delivery of it
+// to every webhook subscribed to it. Deliveries are attempted by the
+// scheduler, failures to queue are logged and do not affect the caller.
+func (service *Service) Notify(event string, data interface{}) {
+	service.publish(event, data)
+
+	webhooks, err := entity.ListSubscribedWebhooks(service.Bolt, event)
+	if err != nil {
+		log.Errorf("failed to fetch webhooks for %s: %v", event, err)
+		return
+	}
+
+	now := time.Now()
+	for _, webhook := range *webhooks {
+		deliveryId := ksuid.New().String()
+		payload, err := json.Marshal(map[string]interface{}{
+			"id":        deliveryId,
+			"event":     event,
+			"createdOn": now.Unix(),
+			"data":      data,
+		})
+		if err != nil {
+			log.Errorf("failed to create %s payload: %v", event, err)
+			return
+		}
+
+		delivery := entity.Delivery{
+			Uuid:        deliveryId,
+			Webhook:     webhook.Uuid,
+			Event:       event,
+			Payload:     payload,
+			Status:      entity.DeliveryPending,
+			Attempts:    0,
+			NextAttempt: now.Unix(),
+			CreatedOn:   now.Unix(),
+		}
+
+		err = delivery.Update(service.Bolt)
+		if err != nil {
+			log.Errorf("failed to queue %s delivery for webhook %s: %v",
+				event, webhook.Uuid, err)
+		}
+	}
+}
+
+// Deliver makes a delivery attempt and records its outcome. Failed attempts
+// are rescheduled with exponential backoff until the configured number of
+// attempts is exhausted. Deliveries to deleted webhooks fail without being
+// attempted.
+func (service *Service) Deliver(delivery *entity.Delivery) error {
+	now := time.Now()
+	webhook, err := entity.GetWebhook([]byte(delivery.Webhook), service.Bolt)
+	if err != nil {
+		problem, ok := err.(*util.Error)
+		if !ok || problem.Status != http.StatusNotFound {
+			return err
+		}
+	}
+
+	if err != nil || webhook.Deleted {
+		delivery.Status = entity.DeliveryFailed
+		delivery.LastError = util.ErrWebhookDeleted.Error()
+		delivery.LastModified = now.Unix()
+		return delivery.Update(service.Bolt)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := util.HMACSignature(webhook.Secret,
+		append([]byte(timestamp+"."), delivery.Payload...))
+
+	delivery.Attempts++
+	delivery.LastModified = now.Unix()
+	delivery.ResponseCode = 0
+	delivery.LastError = ""
+
+	ctx, span := trace.Start(context.Background(), "webhook.deliver", trace.KindClient)
+	span.SetAttribute("webhook.id", webhook.Uuid)
+	span.SetAttribute("webhook.event", delivery.Event)
+	span.SetAttribute("webhook.attempt", int(delivery.Attempts))
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err == nil {
+		req = req.WithContext(ctx)
+		trace.Inject(ctx, req.Header)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Webhook-Id", webhook.Uuid)
+		req.Header.Set("X-Webhook-Event", delivery.Event)
+		req.Header.Set("X-Webhook-Delivery", delivery.Uuid)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Signature", fmt.Sprint("sha256=", signature))
+
+		var resp *http.Response
+		resp, err = service.HTTPClient.Do(req)
+		if err == nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			delivery.ResponseCode = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = util.ErrDeliveryRejected(resp.StatusCode)
+			}
+		}
+	}
+	span.SetError(err)
+	span.Finish()
+
+	maxAttempts := service.Cfg.WebhookMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+
+	retryDelay := service.Cfg.WebhookRetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultWebhookRetryDelay
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = entity.DeliveryDelivered
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = entity.DeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = entity.DeliveryPending
+		delivery.LastError = err.Error()
+		backoff := float64(retryDelay) * math.Pow(2, float64(delivery.Attempts-1))
+		delivery.NextAttempt = now.Add(time.Second * time.Duration(backoff)).Unix()
+	}
+
+	updateErr := delivery.Update(service.Bolt)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	return err
+}
+
+// supportedWebhookEvents asserts all events a webhook subscribes to are
+// supported.
+func supportedWebhookEvents(events []string) ([]string, error) {
+	for _, event := range events {
+		supported := false
+		for _, known := range entity.WebhookEvents {
+			if event == known {
+				supported = true
+				break
+			}
+		}
+
+		if !supported {
+			return nil, util.ErrInvalidParameterOption("events", event, entity.WebhookEvents)
+		}
+	}
+
+	return events, nil
+}
+
+// newWebhookSecret generates a random webhook signing secret.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
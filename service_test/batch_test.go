@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestBatch tests running several requests in a batch, atomically or not.
+func TestBatch(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	for _, id := range []string{"batch-invite-a", "batch-invite-b"} {
+		invite := entity.Invite{
+			Uuid:      id,
+			Email:     fmt.Sprint(id, "@einheit.co"),
+			Role:      util.Finance,
+			Status:    entity.Pending,
+			InvitedBy: session.User,
+			CreatedOn: time.Now().Unix(),
+		}
+		err = invite.Update(service.App.Bolt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer service.App.Delete(util.InviteBucket, []byte(id))
+	}
+
+	type result struct {
+		ID     string          `json:"id"`
+		Status int             `json:"status"`
+		Body   json.RawMessage `json:"body"`
+	}
+
+	type batchResponse struct {
+		Committed bool     `json:"committed"`
+		Results   []result `json:"results"`
+	}
+
+	batch := func(atomic bool, requests ...map[string]interface{}) (int, batchResponse) {
+		payloadJSON, err := json.Marshal(map[string]interface{}{"atomic": atomic, "requests": requests})
+		if err != nil {
+			t.Error(err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/v1/batch", bytes.NewBuffer(payloadJSON))
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+
+		response := batchResponse{}
+		if writer.Code == http.StatusOK {
+			err = json.Unmarshal(writer.Body.Bytes(), &response)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		return writer.Code, response
+	}
+
+	cancel := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":     id,
+			"method": http.MethodPut,
+			"path":   fmt.Sprint("/v1/invites/", id),
+			"body":   map[string]interface{}{"status": entity.Cancelled},
+		}
+	}
+
+	status := func(id string) string {
+		invite, err := entity.GetInvite([]byte(id), service.App.Bolt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return invite.Status
+	}
+
+	// Sub-requests of a batch run independently of each other.
+	code, response := batch(false, cancel("batch-invite-a"), cancel("batch-invite-missing"))
+	if code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, code)
+	}
+
+	if len(response.Results) != 2 || response.Results[0].Status != http.StatusOK ||
+		response.Results[1].Status != http.StatusNotFound {
+		t.Fatalf("expected statuses %d and %d, got %v", http.StatusOK, http.StatusNotFound, response.Results)
+	}
+
+	if status("batch-invite-a") != entity.Cancelled {
+		t.Errorf("expected invite %s cancelled", "batch-invite-a")
+	}
+
+	// A failure rolls back every write of an atomic batch.
+	code, response = batch(true, cancel("batch-invite-b"), cancel("batch-invite-missing"), cancel("batch-invite-a"))
+	if code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, code)
+	}
+
+	if response.Committed {
+		t.Errorf("expected the atomic batch rolled back")
+	}
+
+	if len(response.Results) != 3 || response.Results[2].Status != http.StatusFailedDependency {
+		t.Fatalf("expected the sub-request after the failure not to run, got %v", response.Results)
+	}
+
+	if status("batch-invite-b") != entity.Pending {
+		t.Errorf("expected invite %s left pending", "batch-invite-b")
+	}
+
+	// An atomic batch commits when every sub-request succeeds.
+	_, response = batch(true, cancel("batch-invite-b"))
+	if !response.Committed || status("batch-invite-b") != entity.Cancelled {
+		t.Errorf("expected invite %s cancelled by the atomic batch", "batch-invite-b")
+	}
+
+	// Atomic batches only run the routes supporting them.
+	code, _ = batch(true, map[string]interface{}{
+		"method": http.MethodGet,
+		"path":   "/v1/invites/batch-invite-a",
+	})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, code)
+	}
+}
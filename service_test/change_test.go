@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// changeFeed describes a change feed response.
+type changeFeed struct {
+	Meta struct {
+		Count int    `json:"count"`
+		Last  uint64 `json:"last"`
+	} `json:"meta"`
+	Results []entity.Change `json:"results"`
+}
+
+// TestChange tests all change feed api endpoints.
+func TestChange(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateFeedbackRoutes(service.App.Router)
+	service.CreateChangeRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Drain the change feed to find the latest sequence.
+	var last uint64
+	for {
+		listChanges := fmt.Sprint("/changes?after=", last)
+		req, _ = http.NewRequest(http.MethodGet, listChanges, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer = httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+
+		if writer.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+		}
+
+		feed := new(changeFeed)
+		err = json.Unmarshal(writer.Body.Bytes(), feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if feed.Meta.Count == 0 {
+			break
+		}
+		last = feed.Meta.Last
+	}
+
+	// Create feedback.
+	payload = map[string]interface{}{
+		"user":    "change@einheit.co",
+		"details": "change feed test",
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/feedback", bytes.NewBuffer(payloadJSON))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	feedback := new(entity.Feedback)
+	err = json.Unmarshal(writer.Body.Bytes(), feedback)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+
+	// Long-poll for the feedback change.
+	listChanges := fmt.Sprint("/changes?wait=5&after=", last)
+	req, _ = http.NewRequest(http.MethodGet, listChanges, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("list changes response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	feed := new(changeFeed)
+	err = json.Unmarshal(writer.Body.Bytes(), feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Meta.Count != 1 {
+		t.Fatalf("expected %d changes got %d", 1, feed.Meta.Count)
+	}
+
+	change := feed.Results[0]
+	if change.EntityId != feedback.Uuid || change.Operation != entity.PutOperation {
+		t.Fatalf("unexpected change %s %s for feedback %s", change.Operation,
+			change.EntityId, feedback.Uuid)
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"einheit/boltkit/client"
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestClient tests the api client against the service router.
+func TestClient(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	// Fail the first request of every call marked to fail with a
+	// transient error.
+	var failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&failures) > 0 {
+			atomic.AddInt32(&failures, -1)
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		service.App.Router.ServeHTTP(writer, req)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	api := client.New(server.URL)
+	api.Backoff = time.Millisecond
+
+	// Create Session.
+	session, err := api.CreateSession(ctx, service.App.Cfg.AdminEmail, service.App.Cfg.AdminPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { service.App.Delete(util.SessionBucket, []byte(api.Token())) }()
+
+	// Create an invite.
+	invite, err := api.CreateInvite(ctx, "client@einheit.co", util.Management, session.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	// Get the invite, failing the first attempt.
+	atomic.StoreInt32(&failures, 1)
+	fetched, err := api.GetInvite(ctx, invite.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fetched.Email != invite.Email {
+		t.Fatalf("expected invite %s got %s", invite.Email, fetched.Email)
+	}
+
+	// Create an invite, failing the first attempt. The retry is made with
+	// the idempotency key of the first.
+	atomic.StoreInt32(&failures, 1)
+	retried, err := api.CreateInvite(ctx, "client.retry@einheit.co", util.Management, session.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(retried.Uuid))
+
+	// Create a duplicate invite.
+	_, err = api.CreateInvite(ctx, "client@einheit.co", util.Management, session.User)
+	if !isCode(err, util.ErrInviteExists) {
+		t.Fatalf("expected %v got %v", util.ErrInviteExists, err)
+	}
+
+	// List invites.
+	invites, page, err := api.ListInvites(ctx, "client@einheit.co", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(invites) != 1 || page.Count != 1 {
+		t.Fatalf("expected %d invite got %d", 1, len(invites))
+	}
+
+	if _, more := page.Next(); more {
+		t.Fatalf("expected a single page")
+	}
+
+	// Get a non-existent invite.
+	_, err = api.GetInvite(ctx, "missing")
+	apiErr, ok := err.(*client.Error)
+	if !ok || apiErr.Status != http.StatusNotFound || !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a not found error got %v", err)
+	}
+
+	// Expire the session, the client signs in again.
+	expired := api.Token()
+	service.App.SessionMap.Remove(expired)
+	_, _, err = api.ListUsers(ctx, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if api.Token() == expired {
+		t.Fatalf("expected the session to be refreshed")
+	}
+
+	fmt.Println("refreshed session: ", api.Token())
+
+	// Delete the invite.
+	err = api.DeleteInvite(ctx, invite.Uuid, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := entity.GetInvite([]byte(invite.Uuid), service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !deleted.Deleted {
+		t.Fatalf("expected the invite to be deleted")
+	}
+}
+
+// isCode asserts an error is an api error with the code of a service error.
+func isCode(err error, target *util.Error) bool {
+	return client.HasCode(err, target.Code)
+}
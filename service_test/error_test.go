@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// TestError tests error responses are problem details with the http status
+// of the error.
+func TestError(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+
+	// Get an invite without a session.
+	req, _ := http.NewRequest(http.MethodGet, "/invites/missing", nil)
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("unauthorized response: ", writer.Body.String())
+
+	if writer.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d got %d", http.StatusUnauthorized, writer.Code)
+	}
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Get a non-existent invite.
+	req, _ = http.NewRequest(http.MethodGet, "/invites/missing", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("not found response: ", writer.Body.String())
+
+	if writer.Code != http.StatusNotFound {
+		t.Fatalf("expected %d got %d", http.StatusNotFound, writer.Code)
+	}
+
+	if writer.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected a problem details response")
+	}
+
+	problem := new(util.Problem)
+	err = json.Unmarshal(writer.Body.Bytes(), problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problem.Status != http.StatusNotFound || problem.Code != "not_found" {
+		t.Fatalf("expected status %d with code %s got %d with %s",
+			http.StatusNotFound, "not_found", problem.Status, problem.Code)
+	}
+
+	// Get a corrupt invite.
+	err = service.App.Bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(util.InviteBucket).Put([]byte("corrupt"), []byte("{"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte("corrupt"))
+
+	req, _ = http.NewRequest(http.MethodGet, "/invites/corrupt", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	problem = new(util.Problem)
+	json.Unmarshal(writer.Body.Bytes(), problem)
+	if writer.Code != http.StatusInternalServerError || problem.Code != "storage_error" {
+		t.Fatalf("expected status %d with code %s got %d with %s",
+			http.StatusInternalServerError, "storage_error", writer.Code, problem.Code)
+	}
+
+	// Request an unknown route.
+	req, _ = http.NewRequest(http.MethodGet, "/unknown", nil)
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNotFound {
+		t.Fatalf("expected %d got %d", http.StatusNotFound, writer.Code)
+	}
+}
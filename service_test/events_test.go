@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestEvents tests streaming notifications as server-sent events.
+func TestEvents(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	heartbeat := service.App.Cfg.EventHeartbeat
+	service.App.Cfg.EventHeartbeat = 1
+	defer func() { service.App.Cfg.EventHeartbeat = heartbeat }()
+
+	server := httptest.NewServer(service.App.Router)
+	defer server.Close()
+
+	send := func(method string, path string, token string, payload interface{}) *http.Response {
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			t.Error(err)
+		}
+
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewBuffer(payloadJSON))
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// stream opens an event stream, returning the reader of its messages.
+	stream := func(query string, token string, lastID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/events"+query, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	// next reads the next message of a stream, keyed by field.
+	next := func(reader *bufio.Reader) map[string]string {
+		message := map[string]string{}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return message
+			}
+
+			field := strings.SplitN(line, ":", 2)
+			if len(field) == 2 {
+				message[field[0]] = strings.TrimPrefix(field[1], " ")
+			}
+		}
+	}
+
+	// Create Session.
+	resp := send(http.MethodPost, "/v1/sessions", "", map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	})
+	session := new(entity.Session)
+	err = json.NewDecoder(resp.Body).Decode(session)
+	resp.Body.Close()
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Streams require a session.
+	resp = send(http.MethodGet, "/v1/events", "", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Unknown events are rejected.
+	resp = send(http.MethodGet, "/v1/events?events=user.deleted", session.Token, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	events, reader := stream("?events=feedback.created", session.Token, "")
+	defer events.Body.Close()
+	if events.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected an event stream, got %s", events.Header.Get("Content-Type"))
+	}
+
+	if message := next(reader); message["retry"] == "" {
+		t.Errorf("expected a retry delay, got %v", message)
+	}
+
+	// Feedback created while connected is streamed.
+	resp = send(http.MethodPost, "/v1/feedback", "", map[string]interface{}{
+		"user":    session.User,
+		"details": "streamed feedback",
+	})
+	feedback := new(entity.Feedback)
+	json.NewDecoder(resp.Body).Decode(feedback)
+	resp.Body.Close()
+	defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+
+	message := next(reader)
+	if message["event"] != entity.FeedbackCreatedEvent {
+		t.Errorf("expected event %s, got %v", entity.FeedbackCreatedEvent, message)
+	}
+
+	streamed := new(entity.Feedback)
+	err = json.Unmarshal([]byte(message["data"]), streamed)
+	if err != nil || streamed.Uuid != feedback.Uuid {
+		t.Errorf("expected feedback %s, got %s", feedback.Uuid, message["data"])
+	}
+
+	// Heartbeats keep idle streams open.
+	eventID := message["id"]
+	start := time.Now()
+	message = next(reader)
+	if _, ok := message[""]; !ok || time.Since(start) > 3*time.Second {
+		t.Errorf("expected a heartbeat, got %v", message)
+	}
+
+	// Resumed streams are replayed the events following the last event
+	// received.
+	id, err := strconv.ParseUint(eventID, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, reader := stream("?events=feedback.created", session.Token, fmt.Sprint(id-1))
+	defer resumed.Body.Close()
+	next(reader)
+	message = next(reader)
+	if message["id"] != eventID || message["event"] != entity.FeedbackCreatedEvent {
+		t.Errorf("expected event %s to be replayed, got %v", eventID, message)
+	}
+
+	resumed, reader = stream("?events=feedback.created", session.Token, eventID)
+	defer resumed.Body.Close()
+	next(reader)
+	message = next(reader)
+	if _, ok := message[""]; !ok {
+		t.Errorf("expected no events to be replayed, got %v", message)
+	}
+}
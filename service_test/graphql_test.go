@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestGraphQL tests querying the entities and their relations with graphql.
+func TestGraphQL(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	send := func(method string, path string, token string, payload interface{}) *httptest.ResponseRecorder {
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			t.Error(err)
+		}
+
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payloadJSON))
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
+		}
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	signIn := func(email string, password string) *entity.Session {
+		writer := send(http.MethodPost, "/v1/sessions", "", map[string]interface{}{
+			"email":    email,
+			"password": password,
+		})
+		session := new(entity.Session)
+		err := json.Unmarshal(writer.Body.Bytes(), session)
+		if err != nil {
+			t.Error(err)
+		}
+		return session
+	}
+
+	// Create Session.
+	admin := signIn(service.App.Cfg.AdminEmail, service.App.Cfg.AdminPass)
+	defer service.App.Delete(util.SessionBucket, []byte(admin.Token))
+
+	// Create a management user from an invite.
+	writer := send(http.MethodPost, "/v1/invites", admin.Token, map[string]interface{}{
+		"email":     "graphql@einheit.co",
+		"role":      util.Management,
+		"invitedBy": admin.User,
+	})
+	invite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	writer = send(http.MethodPost, "/v1/users", "", map[string]interface{}{
+		"invite":    invite.Uuid,
+		"firstName": "graph",
+		"lastName":  "user",
+		"password":  "boltkit",
+		"email":     "graphql@einheit.co",
+		"role":      util.Management,
+	})
+	user := new(entity.User)
+	err = json.Unmarshal(writer.Body.Bytes(), user)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.UserBucket, []byte(user.Uuid))
+
+	feedback := entity.Feedback{Uuid: "graphql-feedback", User: user.Uuid, Details: "graphql feedback"}
+	err = feedback.Update(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+
+	manager := signIn("graphql@einheit.co", "boltkit")
+	defer service.App.Delete(util.SessionBucket, []byte(manager.Token))
+
+	type response struct {
+		Data   map[string]json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Path       []interface{}          `json:"path"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+
+	query := func(token string, query string, variables map[string]interface{}) (int, response) {
+		writer := send(http.MethodPost, "/v1/graphql", token, map[string]interface{}{
+			"query":     query,
+			"variables": variables,
+		})
+
+		result := response{}
+		err := json.Unmarshal(writer.Body.Bytes(), &result)
+		if err != nil {
+			t.Error(err)
+		}
+		return writer.Code, result
+	}
+
+	// A single query selects users with their feedback and invites.
+	code, result := query(admin.Token, `
+		query Dashboard($email: String!) {
+			users(email: [$email]) {
+				...person
+				feedback { details author { email } }
+				registeredWith { status inviter { email } }
+			}
+		}
+
+		fragment person on User { email role }`, map[string]interface{}{"email": "graphql@einheit.co"})
+	if code != http.StatusOK || len(result.Errors) > 0 {
+		t.Fatalf("expected %d got %d, %v", http.StatusOK, code, result.Errors)
+	}
+
+	users := []struct {
+		Email    string `json:"email"`
+		Role     string `json:"role"`
+		Feedback []struct {
+			Details string `json:"details"`
+			Author  struct {
+				Email string `json:"email"`
+			} `json:"author"`
+		} `json:"feedback"`
+		RegisteredWith struct {
+			Status  string `json:"status"`
+			Inviter struct {
+				Email string `json:"email"`
+			} `json:"inviter"`
+		} `json:"registeredWith"`
+	}{}
+	err = json.Unmarshal(result.Data["users"], &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 1 || users[0].Role != util.Management {
+		t.Fatalf("expected the %s user, got %v", util.Management, users)
+	}
+
+	if len(users[0].Feedback) != 1 || users[0].Feedback[0].Author.Email != "graphql@einheit.co" {
+		t.Errorf("expected the feedback of the user, got %v", users[0].Feedback)
+	}
+
+	if users[0].RegisteredWith.Inviter.Email != service.App.Cfg.AdminEmail {
+		t.Errorf("expected the user invited by %s, got %v", service.App.Cfg.AdminEmail, users[0].RegisteredWith)
+	}
+
+	// Fields are authorized with the roles of their endpoints.
+	code, result = query(manager.Token, `{ me { email } users { email } }`, nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, code)
+	}
+
+	if !strings.Contains(string(result.Data["me"]), "graphql@einheit.co") {
+		t.Errorf("expected the user of the session, got %s", result.Data["me"])
+	}
+
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "forbidden" ||
+		string(result.Data["users"]) != "null" {
+		t.Errorf("expected users forbidden, got %v", result.Errors)
+	}
+
+	// Queries over the limits are rejected before execution.
+	limits := map[string]string{
+		"query_too_deep":    `{ me { registeredWith { inviter { registeredWith { inviter { registeredWith { email } } } } } } }`,
+		"query_too_complex": `{ users { invitesSent { inviter { feedback { details } } } } }`,
+		"syntax_error":      `{ me { email }`,
+		"invalid_query":     `{ me { password } }`,
+	}
+
+	for expected, limited := range limits {
+		code, result = query(admin.Token, limited, nil)
+		if code != http.StatusBadRequest {
+			t.Fatalf("expected %d got %d", http.StatusBadRequest, code)
+		}
+
+		if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != expected {
+			t.Errorf("expected %s, got %v", expected, result.Errors)
+		}
+	}
+
+	// The schema is described in the schema definition language.
+	writer = send(http.MethodGet, "/v1/graphql/schema", manager.Token, nil)
+	if writer.Code != http.StatusOK || !strings.Contains(writer.Body.String(), "type User {") {
+		t.Errorf("expected the schema, got %d %s", writer.Code, writer.Body.String())
+	}
+}
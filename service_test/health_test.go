@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"einheit/boltkit/service"
+)
+
+// TestHealth tests the liveness, readiness and version endpoints.
+func TestHealth(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	get := func(path string, response interface{}) int {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		err := json.Unmarshal(writer.Body.Bytes(), response)
+		if err != nil {
+			t.Error(err)
+		}
+		return writer.Code
+	}
+
+	health := map[string]string{}
+	if status := get("/healthz", &health); status != http.StatusOK || health["status"] != "ok" {
+		t.Errorf("expected a healthy server, got %d %v", status, health)
+	}
+
+	// The mailer is not checked in debug mode.
+	readiness := struct {
+		Status string
+		Checks map[string]struct {
+			Status   string
+			Critical bool
+		}
+	}{}
+	status := get("/readyz", &readiness)
+	if status != http.StatusOK || readiness.Status != "ready" {
+		t.Errorf("expected a ready server, got %d %v", status, readiness)
+	}
+
+	if readiness.Checks["bolt"].Status != "up" || !readiness.Checks["bolt"].Critical {
+		t.Errorf("expected bolt to be up, got %v", readiness.Checks["bolt"])
+	}
+
+	if readiness.Checks["mailer"].Status != "skipped" {
+		t.Errorf("expected the mailer check to be skipped, got %v", readiness.Checks["mailer"])
+	}
+
+	err = service.App.CheckStartup(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Dependency errors are not disclosed.
+	service.App.AddCheck("probe", false, func(ctx context.Context) error {
+		return errors.New("dial tcp storage.internal:9000: connection refused")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusOK || !strings.Contains(writer.Body.String(), `"degraded"`) {
+		t.Errorf("expected a degraded server, got %d %s", writer.Code, writer.Body.String())
+	}
+
+	if strings.Contains(writer.Body.String(), "storage.internal") {
+		t.Errorf("expected the dependency error to be left out, got %s", writer.Body.String())
+	}
+
+	version := map[string]string{}
+	get("/version", &version)
+	if version["version"] != service.Version || version["goVersion"] == "" {
+		t.Errorf("expected the build metadata, got %v", version)
+	}
+}
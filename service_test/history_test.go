@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestHistory tests all entity history api endpoints.
+func TestHistory(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+	service.CreateHistoryRoutes(service.App.Router)
+
+	// Create Session
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	v, err := service.App.CacheGet(util.AdminKey)
+	if err != nil {
+		t.Error(err)
+	}
+
+	payload = map[string]interface{}{
+		"email":     "history@einheit.co",
+		"role":      util.Finance,
+		"invitedBy": string(v),
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Create invite.
+	req, _ = http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	invite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	created := time.Now().Unix()
+	time.Sleep(time.Second * 1)
+
+	// Update invite role.
+	payload = map[string]interface{}{
+		"role": util.Management,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	updateInvite := fmt.Sprint("/invites/", invite.Uuid)
+	req, _ = http.NewRequest(http.MethodPut, updateInvite, bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	// List invite history.
+	listHistory := fmt.Sprint("/invites/", invite.Uuid, "/history")
+	req, _ = http.NewRequest(http.MethodGet, listHistory, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("list invite history response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	// Get the revision recording the role update.
+	getRevision := fmt.Sprint("/invites/", invite.Uuid, "/history/2")
+	req, _ = http.NewRequest(http.MethodGet, getRevision, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("get invite revision response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	revision := new(entity.Revision)
+	err = json.Unmarshal(writer.Body.Bytes(), revision)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if revision.Actor != string(v) {
+		t.Fatalf("expected actor %s got %s", string(v), revision.Actor)
+	}
+
+	// Get invite as it was before the role update.
+	getInviteAt := fmt.Sprint("/invites/", invite.Uuid, "/at/", created)
+	req, _ = http.NewRequest(http.MethodGet, getInviteAt, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("get invite at response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	pastInvite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), pastInvite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if pastInvite.Role != util.Finance {
+		t.Fatalf("expected role %s got %s", util.Finance, pastInvite.Role)
+	}
+
+	// Restore the invite to its state before the role update.
+	payload = map[string]interface{}{
+		"revision": 2,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	restoreInvite := fmt.Sprint("/invites/", invite.Uuid, "/restore")
+	req, _ = http.NewRequest(http.MethodPut, restoreInvite, bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("restore invite response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	restoredInvite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), restoredInvite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if restoredInvite.Role != util.Finance {
+		t.Fatalf("expected role %s got %s", util.Finance, restoredInvite.Role)
+	}
+}
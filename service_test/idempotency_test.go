@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestIdempotency tests retries of a create with an idempotency key replay
+// the response of the first request.
+func TestIdempotency(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+	service.CreateFeedbackRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Create an invite with an idempotency key.
+	payload = map[string]interface{}{
+		"email":     "idempotency@einheit.co",
+		"role":      util.Management,
+		"invitedBy": session.User,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	create := func(body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		req.Header.Set("Idempotency-Key", "create-idempotency-invite")
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	writer = create(payloadJSON)
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	invite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	// Retry the create, the first response is replayed.
+	writer = create(payloadJSON)
+
+	fmt.Println("replayed create invite response: ", writer.Body.String())
+
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	if writer.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a replayed response")
+	}
+
+	replayed := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), replayed)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if replayed.Uuid != invite.Uuid {
+		t.Fatalf("expected invite %s got %s", invite.Uuid, replayed.Uuid)
+	}
+
+	// Reuse the key for a different payload.
+	payload["email"] = "idempotency.reuse@einheit.co"
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	writer = create(payloadJSON)
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+
+	// Expired records are purged.
+	err = entity.PurgeExpiredIdempotencyRecords(time.Now().Add(48*time.Hour).Unix(), service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer = create(payloadJSON)
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	err = json.Unmarshal(writer.Body.Bytes(), replayed)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(replayed.Uuid))
+
+	// Keys of requests that never completed are released once their lease
+	// elapses.
+	payload["email"] = "idempotency.lease@einheit.co"
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	fingerprint := sha256.Sum256(append([]byte("POST /invites\n"), payloadJSON...))
+	now := time.Now()
+	for _, stale := range []struct {
+		expiry int64
+		status int
+	}{
+		{now.Add(time.Minute).Unix(), http.StatusConflict},
+		{now.Add(-time.Second).Unix(), http.StatusCreated},
+	} {
+		record := &entity.IdempotencyRecord{
+			Key:         fmt.Sprint("user:", session.User, "|create-idempotency-invite"),
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			CreatedOn:   now.Add(-time.Hour).Unix(),
+			Expiry:      stale.expiry,
+		}
+		err = record.Update(service.App.Bolt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer = create(payloadJSON)
+		if writer.Code != stale.status {
+			t.Fatalf("expected %d got %d", stale.status, writer.Code)
+		}
+	}
+
+	err = json.Unmarshal(writer.Body.Bytes(), replayed)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(replayed.Uuid))
+
+	// Keys of anonymous requests are scoped to the client ip.
+	for _, client := range []string{"10.0.0.1:4000", "10.0.0.2:4000"} {
+		body := fmt.Sprintf(`{"user": "%s", "details": "idempotent feedback"}`, client)
+		req, _ := http.NewRequest(http.MethodPost, "/feedback", bytes.NewBufferString(body))
+		req.RemoteAddr = client
+		req.Header.Set("Idempotency-Key", "create-idempotency-feedback")
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		if writer.Code != http.StatusCreated || writer.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+		}
+
+		feedback := new(entity.Feedback)
+		json.Unmarshal(writer.Body.Bytes(), feedback)
+		defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+	}
+
+	// Behind a trusted proxy a client can not claim the scope of another
+	// client by sending its address.
+	trustProxy := service.App.Cfg.TrustProxy
+	service.App.Cfg.TrustProxy = true
+	defer func() { service.App.Cfg.TrustProxy = trustProxy }()
+
+	body := `{"user": "10.0.0.3", "details": "idempotent feedback"}`
+	req, _ = http.NewRequest(http.MethodPost, "/feedback", bytes.NewBufferString(body))
+	req.RemoteAddr = "10.0.0.9:4000"
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.3")
+	req.Header.Set("Idempotency-Key", "create-idempotency-feedback")
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusCreated || writer.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	feedback := new(entity.Feedback)
+	json.Unmarshal(writer.Body.Bytes(), feedback)
+	defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+}
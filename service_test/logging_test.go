@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestLogging tests logging requests as json lines with their request, user
+// and route fields.
+func TestLogging(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	logged := new(bytes.Buffer)
+	service.UseLogger(util.NewJSONBackend(logged).Logger("SRVC"))
+	defer service.DisableLog()
+
+	payloadJSON, err := json.Marshal(map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	req, _ = http.NewRequest(http.MethodGet, "/v1/users/"+session.User, nil)
+	req.Header.Set("Authorization", "Token "+session.Token)
+	req.Header.Set("X-Request-Id", "logged-request")
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, writer.Code)
+	}
+
+	type line struct {
+		Time      string `json:"time"`
+		Level     string `json:"level"`
+		Subsystem string `json:"subsystem"`
+		Message   string `json:"message"`
+		RequestID string `json:"requestId"`
+		UserID    string `json:"userId"`
+		Route     string `json:"route"`
+	}
+
+	var found *line
+	for _, raw := range bytes.Split(bytes.TrimSpace(logged.Bytes()), []byte("\n")) {
+		entry := new(line)
+		err = json.Unmarshal(raw, entry)
+		if err != nil {
+			t.Fatalf("expected json log lines, got %s", raw)
+		}
+
+		if entry.RequestID == "logged-request" {
+			found = entry
+		}
+	}
+
+	if found == nil {
+		t.Fatalf("expected a line logged for the request, got %s", logged.String())
+	}
+
+	if found.Level != "info" || found.Subsystem != "SRVC" || found.Time == "" || found.Message == "" {
+		t.Errorf("expected an info line of the service, got %+v", found)
+	}
+
+	if found.UserID != session.User || found.Route != "/v1/users/{id}" {
+		t.Errorf("expected the user and route of the request, got %+v", found)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+
+	"github.com/btcsuite/btclog"
+)
+
+// TestLogLevel tests listing and setting the log levels of the subsystems
+// at runtime.
+func TestLogLevel(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	backend := util.NewJSONBackend(ioutil.Discard)
+	previous := service.App.LogLevels
+	service.App.LogLevels = util.NewLogLevels(map[string]btclog.Logger{
+		"SRVC": backend.Logger("SRVC"),
+		"ENTY": backend.Logger("ENTY"),
+	})
+	defer func() { service.App.LogLevels = previous }()
+
+	payloadJSON, err := json.Marshal(map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	type levels struct {
+		Subsystems map[string]string `json:"subsystems"`
+		RevertAt   int64             `json:"revertAt"`
+	}
+
+	request := func(method string, payload interface{}) (int, levels) {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+
+		req, _ := http.NewRequest(method, "/v1/loglevels", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+
+		response := levels{}
+		json.Unmarshal(writer.Body.Bytes(), &response)
+		return writer.Code, response
+	}
+
+	// List the levels.
+	status, response := request(http.MethodGet, nil)
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if response.Subsystems["SRVC"] != "info" || response.Subsystems["ENTY"] != "info" {
+		t.Errorf("expected the info level, got %v", response.Subsystems)
+	}
+
+	// Enable debug logging of a subsystem for a second.
+	status, response = request(http.MethodPut, map[string]interface{}{
+		"subsystem":   "SRVC",
+		"level":       "debug",
+		"revertAfter": 1,
+	})
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if response.Subsystems["SRVC"] != "debug" || response.Subsystems["ENTY"] != "info" {
+		t.Errorf("expected the debug level for SRVC only, got %v", response.Subsystems)
+	}
+
+	if response.RevertAt == 0 {
+		t.Error("expected the time the change reverts at")
+	}
+
+	// Set the level of every subsystem while the change is pending.
+	status, response = request(http.MethodPut, map[string]interface{}{
+		"level": "warn",
+	})
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if response.Subsystems["SRVC"] != "warn" || response.Subsystems["ENTY"] != "warn" {
+		t.Errorf("expected the warn level, got %v", response.Subsystems)
+	}
+
+	// Temporary changes revert, keeping later changes.
+	status, response = request(http.MethodPut, map[string]interface{}{
+		"subsystem":   "ENTY",
+		"level":       "trace",
+		"revertAfter": 1,
+	})
+	if status != http.StatusOK || response.Subsystems["ENTY"] != "trace" {
+		t.Errorf("expected the trace level for ENTY, got %d %v", status, response.Subsystems)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	_, response = request(http.MethodGet, nil)
+	if response.Subsystems["SRVC"] != "warn" || response.Subsystems["ENTY"] != "warn" {
+		t.Errorf("expected the warn level once reverted, got %v", response.Subsystems)
+	}
+
+	if response.RevertAt != 0 {
+		t.Errorf("expected no pending revert, got %d", response.RevertAt)
+	}
+
+	// Unknown subsystems and levels are rejected.
+	status, _ = request(http.MethodPut, map[string]interface{}{
+		"subsystem": "NOPE",
+		"level":     "debug",
+	})
+	if status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+
+	status, _ = request(http.MethodPut, map[string]interface{}{
+		"level": "verbose",
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, status)
+	}
+}
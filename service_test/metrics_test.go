@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestMetrics tests exposing the metrics in the prometheus text format.
+func TestMetrics(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		payloadJSON, err := json.Marshal(map[string]interface{}{
+			"email":    service.App.Cfg.AdminEmail,
+			"password": password,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	// Sign in, and fail to.
+	writer := signIn(service.App.Cfg.AdminPass)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	signIn("incorrect")
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, writer.Code)
+	}
+
+	if !strings.HasPrefix(writer.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("expected the prometheus text format, got %s", writer.Header().Get("Content-Type"))
+	}
+
+	body := writer.Body.String()
+	for _, sample := range []string{
+		"# TYPE boltkit_http_requests_total counter",
+		`boltkit_http_requests_total{method="POST",route="/v1/sessions",status="201"}`,
+		`boltkit_http_requests_total{method="POST",route="/v1/sessions",status="401"}`,
+		"# TYPE boltkit_http_request_duration_seconds histogram",
+		`boltkit_http_request_duration_seconds_bucket{method="POST",route="/v1/sessions",le="+Inf"}`,
+		`boltkit_bolt_transaction_duration_seconds_count{kind="view"}`,
+		`boltkit_logins_total{outcome="success"}`,
+		`boltkit_logins_total{outcome="failure"}`,
+		"boltkit_sessions_active ",
+		"boltkit_bolt_free_pages ",
+		"boltkit_bolt_open_read_transactions ",
+	} {
+		if !strings.Contains(body, sample) {
+			t.Errorf("expected the metrics to contain %s", sample)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/service"
+)
+
+// TestMiddleware tests the middleware every request passes through.
+func TestMiddleware(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+
+	// Request an authorized route without a session.
+	req, _ := http.NewRequest(http.MethodPost, "/invites/list", nil)
+	req.Header.Set("X-Request-Id", "middleware-test")
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d got %d", http.StatusUnauthorized, writer.Code)
+	}
+
+	if writer.Header().Get("X-Request-Id") != "middleware-test" {
+		t.Fatalf("expected the request id to be echoed")
+	}
+
+	// Request an authorized route with an unknown session.
+	req, _ = http.NewRequest(http.MethodPost, "/invites/list", nil)
+	req.Header.Set("Authorization", "Token unknown")
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d got %d", http.StatusUnauthorized, writer.Code)
+	}
+
+	if writer.Header().Get("X-Request-Id") == "" {
+		t.Fatalf("expected a generated request id")
+	}
+
+	// Send an oversized payload.
+	oversized := bytes.Repeat([]byte(" "), int(service.App.Decoder.MaxBodySize)+1)
+	req, _ = http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(oversized))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected %d got %d", http.StatusRequestEntityTooLarge, writer.Code)
+	}
+}
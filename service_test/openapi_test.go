@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"einheit/boltkit/service"
+)
+
+// TestOpenAPI tests every registered route is described by the api
+// specification.
+func TestOpenAPI(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	// Get the specification.
+	req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	spec := struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document got version %s", spec.OpenAPI)
+	}
+
+	// Every route and method must be described.
+	paramRegex := regexp.MustCompile(`{([^}:]+)(:[^}]+)?}`)
+	err = service.App.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// Skip the mount points of subrouters.
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("route %s is registered without methods", template)
+			return nil
+		}
+
+		path := paramRegex.ReplaceAllString(template, "{$1}")
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("route %s %s is not described", method, template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Request payload keys are described.
+	invite := spec.Paths["/invites"]["post"]
+	body, _ := json.Marshal(invite["requestBody"])
+	fmt.Println("create invite request body: ", string(body))
+
+	var schemas struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+				Required   []string               `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err = json.Unmarshal(writer.Body.Bytes(), &schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createInvite := schemas.Components.Schemas["CreateInviteRequest"]
+	if _, ok := createInvite.Properties["invitedBy"]; !ok || len(createInvite.Required) != 3 {
+		t.Fatalf("expected invitedBy among 3 required invite fields")
+	}
+
+	// Query parameters are described by the type of their values.
+	exportParams := struct {
+		Parameters []struct {
+			Name   string `json:"name"`
+			Schema struct {
+				Type  string `json:"type"`
+				Items struct {
+					Type string `json:"type"`
+				} `json:"items"`
+			} `json:"schema"`
+		} `json:"parameters"`
+	}{}
+	exportJSON, _ := json.Marshal(spec.Paths["/v1/logs/export"]["get"])
+	err = json.Unmarshal(exportJSON, &exportParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]string{}
+	for _, param := range exportParams.Parameters {
+		types[param.Name] = param.Schema.Type + param.Schema.Items.Type
+	}
+
+	expectedTypes := map[string]string{
+		"from":     "integer",
+		"user":     "string",
+		"method":   "string",
+		"statuses": "arrayinteger",
+	}
+	for name, expected := range expectedTypes {
+		if types[name] != expected {
+			t.Errorf("expected query parameter %s of type %s got %s", name, expected, types[name])
+		}
+	}
+
+	// Get the docs page.
+	req, _ = http.NewRequest(http.MethodGet, "/docs", nil)
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusOK || !strings.Contains(writer.Body.String(), "/openapi.json") {
+		t.Fatalf("expected a docs page rendering the specification")
+	}
+
+	if strings.Contains(writer.Body.String(), "https://") {
+		t.Fatalf("expected a docs page loading only assets served by the api")
+	}
+
+	// Get the assets of the docs page.
+	for asset, contentType := range map[string]string{
+		"/docs/swagger-ui.css":       "text/css",
+		"/docs/swagger-ui-bundle.js": "text/javascript",
+	} {
+		req, _ = http.NewRequest(http.MethodGet, asset, nil)
+		writer = httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+
+		if writer.Code != http.StatusOK || writer.Body.Len() == 0 {
+			t.Fatalf("expected %d got %d for %s", http.StatusOK, writer.Code, asset)
+		}
+
+		if !strings.HasPrefix(writer.Header().Get("Content-Type"), contentType) {
+			t.Errorf("expected content type %s got %s for %s", contentType, writer.Header().Get("Content-Type"), asset)
+		}
+	}
+}
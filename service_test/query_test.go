@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestQuery tests filtering and sorting list endpoints with query parameters.
+func TestQuery(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+	service.CreateUserRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Store invites created a day apart.
+	now := time.Now()
+	invites := []entity.Invite{
+		{Uuid: "query-invite-a", Email: "query-a@einheit.co", Role: util.Finance, Status: entity.Pending},
+		{Uuid: "query-invite-b", Email: "query-b@einheit.co", Role: util.Management, Status: entity.Pending},
+		{Uuid: "query-invite-c", Email: "query-c@einheit.co", Role: util.Finance, Status: entity.Accepted},
+	}
+	for idx := range invites {
+		invites[idx].InvitedBy = session.User
+		invites[idx].CreatedOn = now.AddDate(0, 0, idx-3).Unix()
+		err = invites[idx].Update(service.App.Bolt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer service.App.Delete(util.InviteBucket, []byte(invites[idx].Uuid))
+	}
+
+	list := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	type inviteList struct {
+		Results []entity.Invite `json:"results"`
+	}
+
+	// Filters combine, repeated values of a field match any of them.
+	writer = list("/invites?q=query-&role=finance&sort=-createdOn")
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	response := inviteList{}
+	err = json.Unmarshal(writer.Body.Bytes(), &response)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(response.Results) != 2 {
+		t.Fatalf("expected %d invites got %d", 2, len(response.Results))
+	}
+
+	if response.Results[0].Uuid != "query-invite-c" || response.Results[1].Uuid != "query-invite-a" {
+		t.Errorf("expected invites sorted by descending creation time, got %s, %s",
+			response.Results[0].Uuid, response.Results[1].Uuid)
+	}
+
+	after := now.AddDate(0, 0, -2).Add(-time.Hour).Format(time.RFC3339)
+	writer = list(fmt.Sprintf("/invites?q=query-&role=finance&role=management&status=pending&createdAfter=%s", after))
+	response = inviteList{}
+	err = json.Unmarshal(writer.Body.Bytes(), &response)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(response.Results) != 1 || response.Results[0].Uuid != "query-invite-b" {
+		t.Fatalf("expected invite %s only, got %d invites", "query-invite-b", len(response.Results))
+	}
+
+	// Sorts read the sort index, descending sorts in reverse.
+	for path, expected := range map[string][]string{
+		"/invites?q=query-&sort=email":           {"query-invite-a", "query-invite-b", "query-invite-c"},
+		"/invites?q=query-&sort=-email&offset=0": {"query-invite-c", "query-invite-b", "query-invite-a"},
+	} {
+		response = inviteList{}
+		writer = list(path)
+		json.Unmarshal(writer.Body.Bytes(), &response)
+		if len(response.Results) != len(expected) {
+			t.Fatalf("expected %d invites got %d for %s", len(expected), len(response.Results), path)
+		}
+
+		for idx, invite := range response.Results {
+			if invite.Uuid != expected[idx] {
+				t.Errorf("expected invite %s at %d got %s for %s", expected[idx], idx, invite.Uuid, path)
+			}
+		}
+	}
+
+	// Offsets past the last addressable page are rejected.
+	writer = list("/invites?offset=4294967295")
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+
+	// Every invalid parameter is reported.
+	writer = list("/invites?role=owner&createdAfter=yesterday&sort=status&colour=red")
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+
+	problem := struct {
+		Fields []util.FieldError `json:"fields"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &problem)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(problem.Fields) != 4 {
+		t.Fatalf("expected %d field errors got %d", 4, len(problem.Fields))
+	}
+
+	// The admin is only listed when users are searched or filtered.
+	type userList struct {
+		Results []entity.User `json:"results"`
+	}
+
+	for path, listed := range map[string]bool{"/users": false, "/users?role=admin": true} {
+		writer = list(path)
+		if writer.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+		}
+
+		users := userList{}
+		err = json.Unmarshal(writer.Body.Bytes(), &users)
+		if err != nil {
+			t.Error(err)
+		}
+
+		found := false
+		for _, user := range users.Results {
+			if user.Uuid == session.User {
+				found = true
+			}
+
+			if user.Password != "" {
+				t.Errorf("expected listed users to be sanitized")
+			}
+		}
+
+		if found != listed {
+			t.Errorf("expected admin listed %v for %s, got %v", listed, path, found)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestRateLimit tests requests are rate limited per client and route.
+func TestRateLimit(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateFeedbackRoutes(service.App.Router)
+
+	limits := service.App.Cfg.RateLimits
+	limiter := service.App.RateLimiter
+	service.App.Cfg.RateLimits = map[string]util.RateLimit{
+		"POST /feedback": {Rate: 1, Burst: 2, By: util.RateLimitByIP},
+	}
+	service.App.RateLimiter = util.NewRateLimiter()
+	defer func() {
+		service.App.Cfg.RateLimits = limits
+		service.App.RateLimiter = limiter
+	}()
+
+	submit := func(addr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/feedback", strings.NewReader("{}"))
+		req.RemoteAddr = addr
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	// Exhaust the burst, invalid payloads are rejected after taking a token.
+	for idx := 0; idx < 2; idx++ {
+		writer := submit("10.0.0.1:4000")
+		if writer.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+		}
+
+		if writer.Header().Get("RateLimit-Remaining") != fmt.Sprint(1-idx) {
+			t.Fatalf("expected %d remaining got %s", 1-idx, writer.Header().Get("RateLimit-Remaining"))
+		}
+	}
+
+	writer := submit("10.0.0.1:4001")
+
+	fmt.Println("rate limited response: ", writer.Body.String())
+
+	if writer.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d got %d", http.StatusTooManyRequests, writer.Code)
+	}
+
+	if writer.Header().Get("Retry-After") != "60" || writer.Header().Get("RateLimit-Limit") != "2" {
+		t.Fatalf("expected a retry after %d seconds of a limit of %d", 60, 2)
+	}
+
+	// Another client is not limited.
+	writer = submit("10.0.0.2:4000")
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+
+	// Behind a trusted proxy clients are identified by the address the
+	// proxy appended, addresses sent by the client are ignored.
+	trustProxy := service.App.Cfg.TrustProxy
+	service.App.Cfg.TrustProxy = true
+	defer func() { service.App.Cfg.TrustProxy = trustProxy }()
+
+	forward := func(forwarded string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/feedback", strings.NewReader("{}"))
+		req.RemoteAddr = "10.0.0.9:4000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	for idx := 0; idx < 2; idx++ {
+		writer = forward(fmt.Sprint("192.0.2.", idx, ", 10.0.0.3"))
+		if writer.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+		}
+	}
+
+	writer = forward("192.0.2.9, 10.0.0.3")
+	if writer.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d got %d", http.StatusTooManyRequests, writer.Code)
+	}
+
+	service.App.Cfg.TrustProxy = trustProxy
+
+	// Rate limits persist across restarts.
+	err = service.App.SaveRateLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service.App.RateLimiter = util.NewRateLimiter()
+	err = service.App.LoadRateLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer = submit("10.0.0.1:4002")
+	if writer.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d got %d", http.StatusTooManyRequests, writer.Code)
+	}
+}
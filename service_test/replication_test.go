@@ -0,0 +1,310 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/scheduler"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestReplication tests all replication api endpoints served by a primary.
+func TestReplication(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateReplicationRoutes(service.App.Router)
+
+	token := service.App.Cfg.ReplicationToken
+	service.App.Cfg.ReplicationToken = "replication-test-token"
+	defer func() { service.App.Cfg.ReplicationToken = token }()
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Get replication status.
+	req, _ = http.NewRequest(http.MethodGet, "/replication/status", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("replication status response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	status := map[string]interface{}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status["role"] != "primary" {
+		t.Fatalf("expected role %s got %v", "primary", status["role"])
+	}
+
+	// Fetch changes with a session token.
+	req, _ = http.NewRequest(http.MethodGet, "/replication/changes", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusUnauthorized {
+		t.Fatalf("expected %d got %d", http.StatusUnauthorized, writer.Code)
+	}
+
+	// Fetch changes with the replication token.
+	req, _ = http.NewRequest(http.MethodGet, "/replication/changes?after=0&limit=1", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", service.App.Cfg.ReplicationToken))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("replication changes response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	feed := struct {
+		Meta struct {
+			Head uint64 `json:"head"`
+		} `json:"meta"`
+		Results []entity.Change `json:"results"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(feed.Results) != 1 || feed.Meta.Head < feed.Results[0].Sequence {
+		t.Fatalf("expected a change at or before head %d", feed.Meta.Head)
+	}
+
+	// Fetch a snapshot.
+	req, _ = http.NewRequest(http.MethodGet, "/replication/snapshot", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", service.App.Cfg.ReplicationToken))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	if writer.Header().Get("X-Replication-Sequence") == "" || writer.Body.Len() == 0 {
+		t.Fatalf("expected a snapshot with its sequence")
+	}
+
+	// Promote the primary.
+	req, _ = http.NewRequest(http.MethodPost, "/replication/promote", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusConflict {
+		t.Fatalf("expected %d got %d", http.StatusConflict, writer.Code)
+	}
+}
+
+// TestReplicationCompaction tests compacting the change feed, against a
+// separate database so the changes of other tests are kept.
+func TestReplicationCompaction(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+
+	token := service.App.Cfg.ReplicationToken
+	service.App.Cfg.ReplicationToken = "replication-test-token"
+	defer func() { service.App.Cfg.ReplicationToken = token }()
+
+	dir, err := ioutil.TempDir("", "compaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(filepath.Join(dir, "compaction.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{util.ChangeBucket, util.CacheBucket, util.IndexBucket} {
+			_, err := tx.CreateBucket(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := service.App.Bolt
+	service.App.Bolt = &entity.DB{DB: db}
+	defer func() { service.App.Bolt = store }()
+
+	// Record changes older than the retention period and a recent one.
+	expired := util.GetPastTime(time.Now(), 10, 0, 0, 0).Unix()
+	changes := []entity.Change{}
+	for seq := uint64(1); seq <= 4; seq++ {
+		change := entity.Change{
+			Sequence:  seq,
+			Entity:    "compaction",
+			EntityId:  fmt.Sprint("entity-", seq),
+			Operation: entity.PutOperation,
+			Timestamp: expired,
+			Data:      json.RawMessage(`{}`),
+		}
+		if seq == 4 {
+			change.Timestamp = time.Now().Unix()
+		}
+		changes = append(changes, change)
+	}
+
+	err = entity.ApplyChanges(service.App.Bolt, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = scheduler.CompactChanges(service.App)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compacted, err := entity.CompactedSequence(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if compacted != 3 {
+		t.Fatalf("expected changes compacted through %d got %d", 3, compacted)
+	}
+
+	// Resuming from before the compacted changes is refused.
+	req, _ := http.NewRequest(http.MethodGet, "/replication/changes?after=1", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", service.App.Cfg.ReplicationToken))
+	writer := httptest.NewRecorder()
+	service.App.ReplicationChanges(writer, req)
+
+	if writer.Code != http.StatusGone {
+		t.Fatalf("expected %d got %d", http.StatusGone, writer.Code)
+	}
+
+	problem := util.Problem{}
+	err = json.Unmarshal(writer.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problem.Code != util.ErrChangesCompacted.Code {
+		t.Errorf("expected error code %s got %s", util.ErrChangesCompacted.Code, problem.Code)
+	}
+
+	// Resuming from within the kept changes continues the feed.
+	req, _ = http.NewRequest(http.MethodGet, "/replication/changes?after=3", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", service.App.Cfg.ReplicationToken))
+	writer = httptest.NewRecorder()
+	service.App.ReplicationChanges(writer, req)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	feed := struct {
+		Meta struct {
+			Head uint64 `json:"head"`
+		} `json:"meta"`
+		Results []entity.Change `json:"results"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(feed.Results) != 1 || feed.Results[0].Sequence != 4 || feed.Meta.Head != 4 {
+		t.Fatalf("expected the kept change at head %d, got %d changes at head %d",
+			4, len(feed.Results), feed.Meta.Head)
+	}
+}
+
+// TestReplicationBootstrapTLS tests a replica verifies the certificate of
+// its primary, trusting self-signed primaries only through replicationca.
+func TestReplicationBootstrapTLS(t *testing.T) {
+	primary := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	dir, err := ioutil.TempDir("", "bootstrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &util.Config{
+		Storage:          filepath.Join(dir, "replica.db"),
+		ReplicaOf:        primary.URL,
+		ReplicationToken: "replication-test-token",
+	}
+
+	// The self-signed certificate of the primary is not trusted.
+	_, err = service.NewServiceFromConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected a certificate verification error, got %v", err)
+	}
+
+	// The certificate is trusted once its ca is configured, the primary is
+	// reached and its response read.
+	caPath := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: primary.Certificate().Raw})
+	err = ioutil.WriteFile(caPath, ca, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.ReplicationCA = caPath
+	_, err = service.NewServiceFromConfig(cfg)
+	problem, ok := err.(*util.Error)
+	if !ok || problem.Code != "delivery_rejected" {
+		t.Fatalf("expected the primary to reject the snapshot, got %v", err)
+	}
+}
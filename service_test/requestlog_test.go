@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestRequestLog tests searching and exporting the request log.
+func TestRequestLog(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	from := time.Now().Unix()
+	payloadJSON, err := json.Marshal(map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Make a request that succeeds and one that fails.
+	for _, id := range []string{session.User, "unknown"} {
+		req, _ = http.NewRequest(http.MethodGet, "/v1/users/"+id, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		req.Header.Set("X-Request-Id", "=1+1-"+id)
+		writer = httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+	}
+
+	// Make a request holding credentials.
+	req, _ = http.NewRequest(http.MethodPut, "/v1/users/unknown?resetToken=abc",
+		bytes.NewBufferString(`{"newPassword": "hunter2", "details": {"apiSecret": "s3cr3t"}}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	list := func(query map[string]interface{}) (int, []entity.RequestLog) {
+		body, _ := json.Marshal(query)
+		req, _ := http.NewRequest(http.MethodPost, "/v1/logs/list", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+
+		response := struct {
+			Results []entity.RequestLog `json:"results"`
+		}{}
+		json.Unmarshal(writer.Body.Bytes(), &response)
+		return writer.Code, response.Results
+	}
+
+	// Search by user, route template and method.
+	status, results := list(map[string]interface{}{
+		"from":   from,
+		"user":   session.User,
+		"route":  "/v1/users/{id}",
+		"method": "get",
+		"offset": 0,
+	})
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(results))
+	}
+
+	if results[0].Route != "/v1/users/"+session.User || results[1].Route != "/v1/users/unknown" {
+		t.Errorf("expected the requests in the order they were made in, got %s and %s",
+			results[0].Route, results[1].Route)
+	}
+
+	if results[0].Status != http.StatusOK || results[1].Status == http.StatusOK {
+		t.Errorf("expected the response statuses, got %d and %d", results[0].Status, results[1].Status)
+	}
+
+	if results[0].Requestor != session.User || results[0].RequestID == "" || results[0].Latency <= 0 {
+		t.Errorf("expected the requestor, request id and latency, got %+v", results[0])
+	}
+
+	// Credentials are redacted.
+	_, results = list(map[string]interface{}{
+		"from":   from,
+		"route":  "/v1/users/unknown",
+		"method": http.MethodPut,
+		"offset": 0,
+	})
+	if len(results) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(results))
+	}
+
+	details, _ := results[0].Payload["details"].(map[string]interface{})
+	if results[0].Payload["newPassword"] != "[redacted]" || details["apiSecret"] != "[redacted]" ||
+		results[0].QueryParams != "resetToken=%5Bredacted%5D" {
+		t.Errorf("expected the credentials to be redacted, got %v %s", results[0].Payload, results[0].QueryParams)
+	}
+
+	// Search by status and requestor email.
+	_, results = list(map[string]interface{}{
+		"from":     from,
+		"email":    service.App.Cfg.AdminEmail,
+		"route":    "/v1/users/{id}",
+		"statuses": []int{http.StatusOK},
+		"offset":   0,
+	})
+	if len(results) != 1 || results[0].Status != http.StatusOK {
+		t.Errorf("expected the successful request only, got %+v", results)
+	}
+
+	// Requests before the range are not matched.
+	_, results = list(map[string]interface{}{
+		"from":   time.Now().Unix() + 60,
+		"to":     time.Now().Unix() + 120,
+		"offset": 0,
+	})
+	if len(results) != 0 {
+		t.Errorf("expected no requests, got %d", len(results))
+	}
+
+	// Offsets past the last addressable page are rejected.
+	status, _ = list(map[string]interface{}{
+		"from":   from,
+		"offset": uint32(math.MaxUint32),
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, status)
+	}
+
+	// Ranges must end after they start.
+	status, _ = list(map[string]interface{}{
+		"from":   from,
+		"to":     from,
+		"offset": 0,
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, status)
+	}
+
+	// Export the requests as csv.
+	req, _ = http.NewRequest(http.MethodGet,
+		fmt.Sprintf("/v1/logs/export?from=%d&user=%s&route=/v1/users/{id}&statuses=200,404", from, session.User), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, writer.Code)
+	}
+
+	records, err := csv.NewReader(writer.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) < 2 || records[0][0] != "id" || records[0][9] != "status" {
+		t.Fatalf("expected a header and rows, got %v", records)
+	}
+
+	if records[1][3] != session.User || records[1][6] != "/v1/users/"+session.User || records[1][9] != "200" {
+		t.Errorf("expected the successful request first, got %v", records[1])
+	}
+
+	if records[1][2] != "'=1+1-"+session.User {
+		t.Errorf("expected formulas to be escaped, got %s", records[1][2])
+	}
+
+	// Exports require the start of the range.
+	req, _ = http.NewRequest(http.MethodGet, "/v1/logs/export", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+}
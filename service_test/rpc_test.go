@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"einheit/boltkit/rpc"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestRPC tests calling the api over grpc with the generated clients.
+func TestRPC(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go service.App.RPC.Serve(listener)
+	defer service.App.RPC.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sessions := rpc.NewSessionServiceClient(conn)
+	users := rpc.NewUserServiceClient(conn)
+	invites := rpc.NewInviteServiceClient(conn)
+
+	// authorized returns a context carrying the session token.
+	authorized := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", fmt.Sprintf("Token %s", token))
+	}
+
+	// Create Session.
+	admin, err := sessions.CreateSession(context.Background(), &rpc.CreateSessionRequest{
+		Email:    service.App.Cfg.AdminEmail,
+		Password: service.App.Cfg.AdminPass,
+	})
+	if err != nil {
+		t.Fatalf("expected a session, got %v", err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(admin.Token))
+
+	if admin.Token == "" || admin.Access != util.Admin {
+		t.Errorf("expected an admin session, got %v", admin)
+	}
+
+	// Get the signed in user.
+	user, err := users.GetUser(authorized(admin.Token), &rpc.IdRequest{Id: admin.User})
+	if err != nil {
+		t.Errorf("expected the user, got %v", err)
+	}
+
+	if user.GetEmail() != service.App.Cfg.AdminEmail {
+		t.Errorf("expected email %s, got %s", service.App.Cfg.AdminEmail, user.GetEmail())
+	}
+
+	// Calls require the session token.
+	_, err = users.GetUser(context.Background(), &rpc.IdRequest{Id: admin.User})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected status %s, got %v", codes.Unauthenticated, err)
+	}
+
+	// Missing entities fail as not found, with the error code trailer.
+	trailer := metadata.MD{}
+	_, err = invites.GetInvite(authorized(admin.Token), &rpc.IdRequest{Id: "missing"}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected status %s, got %v", codes.NotFound, err)
+	}
+
+	if code := trailer.Get("error-code"); len(code) != 1 || code[0] != "not_found" {
+		t.Errorf("expected error code not_found, got %v", code)
+	}
+
+	// List users with filters.
+	list, err := users.ListUsers(authorized(admin.Token), &rpc.ListRequest{
+		Filters: []*rpc.Filter{{Name: "role", Values: []string{util.Admin}}},
+	})
+	if err != nil {
+		t.Fatalf("expected the users, got %v", err)
+	}
+
+	if len(list.Results) != 1 || list.Results[0].Role != util.Admin {
+		t.Errorf("expected the admin, got %d users", len(list.Results))
+	}
+
+	if list.Meta == nil || list.Meta.Count != 1 {
+		t.Errorf("expected a page of one user, got %v", list.Meta)
+	}
+
+	// Payloads are validated as the api validates them.
+	_, err = invites.CreateInvite(authorized(admin.Token), &rpc.CreateInviteRequest{Email: "rpc@einheit.co"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected status %s, got %v", codes.InvalidArgument, err)
+	}
+
+	// Methods the server does not implement are unimplemented.
+	err = conn.Invoke(authorized(admin.Token), "/boltkit.v1.UserService/Unknown", &rpc.IdRequest{}, &rpc.Empty{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("expected status %s, got %v", codes.Unimplemented, err)
+	}
+}
@@ -30,6 +30,11 @@ func TestSession(t *testing.T) {
 		t.Error(err)
 	}
 
+	head, err := entity.HeadSequence(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Create session.
 	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
 	writer := httptest.NewRecorder()
@@ -47,6 +52,25 @@ func TestSession(t *testing.T) {
 
 	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
 
+	// Logins are recorded without a change to the user.
+	user, err := entity.GetUser([]byte(session.User), service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.LastLogin < session.CreatedOn {
+		t.Errorf("expected the login at %d to be recorded, got %d", session.CreatedOn, user.LastLogin)
+	}
+
+	loginHead, err := entity.HeadSequence(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loginHead != head {
+		t.Errorf("expected no change recorded for a login, head moved from %d to %d", head, loginHead)
+	}
+
 	// Get Session.
 	sessionGet := fmt.Sprint("/sessions/", session.Token)
 	req, _ = http.NewRequest(http.MethodGet, sessionGet, nil)
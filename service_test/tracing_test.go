@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/trace"
+	"einheit/boltkit/util"
+)
+
+// TestTracing tests continuing the trace of a request and exporting its
+// spans.
+func TestTracing(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	exported := new(bytes.Buffer)
+	previous := trace.Default
+	trace.Default = trace.NewTracer("boltkit", trace.NewWriterExporter(exported), 1)
+	defer func() { trace.Default = previous }()
+
+	payloadJSON, err := json.Marshal(map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID := "00f067aa0ba902b7"
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Traceparent", "00-"+traceID+"-"+parentID+"-01")
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, writer.Code)
+	}
+
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// List users with their invites, the page and its relations are read in
+	// transactions of the request.
+	req, _ = http.NewRequest(http.MethodGet, "/v1/users?include=invite", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	if writer.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, writer.Code)
+	}
+
+	trace.Default.Shutdown()
+
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Kind         int    `json:"kind"`
+	}
+
+	spans := map[string]span{}
+	children := map[string][]string{}
+	for _, line := range bytes.Split(bytes.TrimSpace(exported.Bytes()), []byte("\n")) {
+		request := struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}{}
+		err = json.Unmarshal(line, &request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, resource := range request.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				for _, exportedSpan := range scope.Spans {
+					spans[exportedSpan.Name] = exportedSpan
+					children[exportedSpan.ParentSpanID] = append(children[exportedSpan.ParentSpanID], exportedSpan.Name)
+				}
+			}
+		}
+	}
+
+	server, ok := spans["POST /v1/sessions"]
+	if !ok {
+		t.Fatalf("expected a server span, got %v", spans)
+	}
+
+	if server.TraceID != traceID || server.ParentSpanID != parentID || server.Kind != trace.KindServer {
+		t.Errorf("expected the server span to continue the trace, got %+v", server)
+	}
+
+	// hasChild asserts a span has a child span of the provided name.
+	hasChild := func(parent span, name string) bool {
+		for _, child := range children[parent.SpanID] {
+			if child == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, name := range []string{"validate request", "bolt.view", "bolt.update"} {
+		if !hasChild(server, name) {
+			t.Errorf("expected a %s span child of the server span, got %v", name, children[server.SpanID])
+		}
+	}
+
+	list, ok := spans["GET /v1/users"]
+	if !ok {
+		t.Fatalf("expected a server span of the user list, got %v", spans)
+	}
+
+	reads := 0
+	for _, child := range children[list.SpanID] {
+		if child == "bolt.view" {
+			reads++
+		}
+	}
+
+	if reads != 2 {
+		t.Errorf("expected bolt.view spans of the user list and its relations, got %v", children[list.SpanID])
+	}
+}
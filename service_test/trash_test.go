@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/scheduler"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestTrash tests all trash api endpoints.
+func TestTrash(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+	service.CreateTrashRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	v, err := service.App.CacheGet(util.AdminKey)
+	if err != nil {
+		t.Error(err)
+	}
+
+	payload = map[string]interface{}{
+		"email":     "trash@einheit.co",
+		"role":      util.Management,
+		"invitedBy": string(v),
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Create invite.
+	req, _ = http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	invite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	// Delete invite.
+	payload = map[string]interface{}{
+		"deleted": true,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	deleteInvite := fmt.Sprint("/invites/", invite.Uuid)
+	req, _ = http.NewRequest(http.MethodDelete, deleteInvite, bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNoContent {
+		t.Fatalf("expected %d got %d", http.StatusNoContent, writer.Code)
+	}
+
+	// List deleted invites.
+	payload = map[string]interface{}{
+		"offset": 0,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/trash/invites", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("list deleted invites response size: ", writer.Body.Len())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	// Offsets past the deleted invites list nothing.
+	req, _ = http.NewRequest(http.MethodPost, "/trash/invites", bytes.NewBufferString(`{"offset": 5}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	// Restore invite.
+	restoreInvite := fmt.Sprint("/trash/invites/", invite.Uuid)
+	req, _ = http.NewRequest(http.MethodPut, restoreInvite, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("restore deleted invite response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	// Purging a restored invite should fail.
+	purgeInvite := fmt.Sprint("/trash/invites/", invite.Uuid)
+	req, _ = http.NewRequest(http.MethodDelete, purgeInvite, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusConflict {
+		t.Fatalf("expected %d got %d", http.StatusConflict, writer.Code)
+	}
+
+	// Delete and purge invite.
+	req, _ = http.NewRequest(http.MethodDelete, deleteInvite, bytes.NewBuffer([]byte(`{"deleted":true}`)))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNoContent {
+		t.Fatalf("expected %d got %d", http.StatusNoContent, writer.Code)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, purgeInvite, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNoContent {
+		t.Fatalf("expected %d got %d", http.StatusNoContent, writer.Code)
+	}
+
+	_, err = entity.GetInvite([]byte(invite.Uuid), service.App.Bolt)
+	if err == nil {
+		t.Fatalf("expected purged invite to be removed from storage")
+	}
+}
+
+// TestPurgeDeleted tests purging invites deleted for longer than the trash
+// retention.
+func TestPurgeDeleted(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+
+	now := time.Now().Unix()
+	recent := &entity.Invite{Uuid: "purge-recent", Email: "recent@einheit.co", Deleted: true,
+		LastModified: now}
+	expired := &entity.Invite{Uuid: "purge-expired", Email: "expired@einheit.co", Deleted: true,
+		LastModified: now, DeletedOn: util.GetPastTime(time.Now(), 31, 0, 0, 0).Unix()}
+	for _, invite := range []*entity.Invite{recent, expired} {
+		err = invite.Update(service.App.Bolt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer invite.Purge(service.App.Bolt)
+	}
+
+	err = scheduler.PurgeDeleted(service.App)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Invites without a deletion time are aged by their last modification.
+	_, err = entity.GetInvite([]byte(recent.Uuid), service.App.Bolt)
+	if err != nil {
+		t.Errorf("expected the recently deleted invite to be kept, got %v", err)
+	}
+
+	_, err = entity.GetInvite([]byte(expired.Uuid), service.App.Bolt)
+	if err == nil {
+		t.Error("expected the expired invite to be purged")
+	}
+}
@@ -107,6 +107,15 @@ func TestUser(t *testing.T) {
 		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
 	}
 
+	// Registering again with the accepted invite should fail.
+	req, _ = http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(payloadJSON))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusConflict {
+		t.Fatalf("expected %d got %d", http.StatusConflict, writer.Code)
+	}
+
 	// Get user.
 	getUser := fmt.Sprint("/users/", user.Uuid)
 	req, _ = http.NewRequest(http.MethodGet, getUser, nil)
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestValidation tests request payload decoding and validation.
+func TestValidation(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Create an invalid invite.
+	payload = map[string]interface{}{
+		"email": "not an email",
+		"role":  "owner",
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("create invalid invite response: ", writer.Body.String())
+
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+
+	response := struct {
+		Fields []util.FieldError `json:"fields"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The email, role and invitedBy fields are all reported at once.
+	if len(response.Fields) != 3 {
+		t.Fatalf("expected %d field errors got %d", 3, len(response.Fields))
+	}
+
+	// Create an invite.
+	v, err := service.App.CacheGet(util.AdminKey)
+	if err != nil {
+		t.Error(err)
+	}
+
+	payload = map[string]interface{}{
+		"email":     "validation@einheit.co",
+		"role":      util.Management,
+		"invitedBy": string(v),
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/invites", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	invite := new(entity.Invite)
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	// Update the invite status.
+	payload = map[string]interface{}{
+		"status": entity.Cancelled,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	updateInvite := fmt.Sprint("/invites/", invite.Uuid)
+	req, _ = http.NewRequest(http.MethodPut, updateInvite, bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("update invite status response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	err = json.Unmarshal(writer.Body.Bytes(), invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if invite.Status != entity.Cancelled || invite.Email != "validation@einheit.co" {
+		t.Fatalf("expected status %s and an unchanged email", entity.Cancelled)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestVersion tests versioned routes and their deprecated root aliases.
+func TestVersion(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.App.SetupRoutes()
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	if writer.Header().Get("Deprecation") != "" {
+		t.Fatalf("expected a versioned route not to be deprecated")
+	}
+
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Get a non-existent invite from its versioned route.
+	req, _ = http.NewRequest(http.MethodGet, "/v1/invites/missing", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNotFound {
+		t.Fatalf("expected %d got %d", http.StatusNotFound, writer.Code)
+	}
+
+	// Get a non-existent invite from its root alias.
+	req, _ = http.NewRequest(http.MethodGet, "/invites/missing", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	if writer.Code != http.StatusNotFound {
+		t.Fatalf("expected %d got %d", http.StatusNotFound, writer.Code)
+	}
+
+	if writer.Header().Get("Deprecation") != "true" ||
+		writer.Header().Get("Link") != "</v1/invites/missing>; rel=\"successor-version\"" {
+		t.Fatalf("expected a deprecated root alias linking to its successor")
+	}
+
+	// Retire the root aliases.
+	sunset := service.App.LegacySunset
+	service.App.LegacySunset = time.Now().Add(-time.Hour)
+	defer func() { service.App.LegacySunset = sunset }()
+
+	req, _ = http.NewRequest(http.MethodGet, "/invites/missing", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("retired root alias response: ", writer.Body.String())
+
+	if writer.Code != http.StatusGone {
+		t.Fatalf("expected %d got %d", http.StatusGone, writer.Code)
+	}
+
+	if writer.Header().Get("Sunset") == "" {
+		t.Fatalf("expected the sunset of a retired root alias")
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+)
+
+// TestView tests trimming response fields and embedding related entities.
+func TestView(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateInviteRoutes(service.App.Router)
+	service.CreateUserRoutes(service.App.Router)
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	invite := entity.Invite{
+		Uuid:      "view-invite",
+		Email:     "view@einheit.co",
+		Role:      util.Finance,
+		Status:    entity.Pending,
+		InvitedBy: session.User,
+		CreatedOn: time.Now().Unix(),
+	}
+	err = invite.Update(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.App.Delete(util.InviteBucket, []byte(invite.Uuid))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+		writer := httptest.NewRecorder()
+		service.App.Router.ServeHTTP(writer, req)
+		return writer
+	}
+
+	type inviteView struct {
+		Email    string `json:"email"`
+		Role     string `json:"role"`
+		Embedded struct {
+			InvitedBy *entity.User `json:"invitedBy"`
+		} `json:"embedded"`
+	}
+
+	// The inviter is embedded in every listed invite.
+	writer = get("/invites?q=view@einheit.co&fields=email,invitedBy&include=invitedBy")
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	response := struct {
+		Results []inviteView `json:"results"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &response)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(response.Results) != 1 {
+		t.Fatalf("expected %d invites got %d", 1, len(response.Results))
+	}
+
+	listed := response.Results[0]
+	if listed.Email != invite.Email || listed.Role != "" {
+		t.Errorf("expected invite trimmed to its email, got email %s role %s", listed.Email, listed.Role)
+	}
+
+	inviter := listed.Embedded.InvitedBy
+	if inviter == nil || inviter.Email != service.App.Cfg.AdminEmail {
+		t.Fatalf("expected the inviter embedded")
+	}
+
+	if inviter.Password != "" {
+		t.Errorf("expected the embedded inviter to be sanitized")
+	}
+
+	// A single invite is shaped the same way.
+	writer = get(fmt.Sprintf("/invites/%s?fields=role", invite.Uuid))
+	fetched := map[string]interface{}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &fetched)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(fetched) != 1 || fetched["role"] != invite.Role {
+		t.Errorf("expected the invite trimmed to its role, got %v", fetched)
+	}
+
+	// Unknown fields and relations are rejected.
+	writer = get(fmt.Sprintf("/invites/%s?fields=colour&include=owner", invite.Uuid))
+	if writer.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d got %d", http.StatusUnprocessableEntity, writer.Code)
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"einheit/boltkit/entity"
+	"einheit/boltkit/service"
+	"einheit/boltkit/util"
+
+	"github.com/boltdb/bolt"
+)
+
+// TestWebhook tests all webhook api endpoints and event delivery.
+func TestWebhook(t *testing.T) {
+	err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	service.CreateSessionRoutes(service.App.Router)
+	service.CreateFeedbackRoutes(service.App.Router)
+	service.CreateWebhookRoutes(service.App.Router)
+
+	// Start the webhook receiver, recording signature verification results.
+	verified := make(chan bool, 2)
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		timestamp := req.Header.Get("X-Webhook-Timestamp")
+		expected := fmt.Sprint("sha256=",
+			util.HMACSignature(secret, append([]byte(timestamp+"."), body...)))
+		verified <- req.Header.Get("X-Webhook-Signature") == expected
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	// Create Session.
+	payload := map[string]interface{}{
+		"email":    service.App.Cfg.AdminEmail,
+		"password": service.App.Cfg.AdminPass,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/sessions", bytes.NewBuffer(payloadJSON))
+	writer := httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	session := new(entity.Session)
+	err = json.Unmarshal(writer.Body.Bytes(), session)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.SessionBucket, []byte(session.Token))
+
+	// Create webhook.
+	payload = map[string]interface{}{
+		"url":    receiver.URL,
+		"events": []string{entity.FeedbackCreatedEvent},
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("create webhook response: ", writer.Body.String())
+
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, writer.Code)
+	}
+
+	webhook := new(entity.Webhook)
+	err = json.Unmarshal(writer.Body.Bytes(), webhook)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.WebhookBucket, []byte(webhook.Uuid))
+
+	secret = webhook.Secret
+
+	// Create feedback.
+	payload = map[string]interface{}{
+		"user":    "webhook@einheit.co",
+		"details": "webhook test",
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/feedback", bytes.NewBuffer(payloadJSON))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+	feedback := new(entity.Feedback)
+	err = json.Unmarshal(writer.Body.Bytes(), feedback)
+	if err != nil {
+		t.Error(err)
+	}
+
+	defer service.App.Delete(util.FeedbackBucket, []byte(feedback.Uuid))
+
+	// List webhook deliveries.
+	payload = map[string]interface{}{
+		"offset": 0,
+	}
+
+	payloadJSON, err = json.Marshal(payload)
+	if err != nil {
+		t.Error(err)
+	}
+
+	listDeliveries := fmt.Sprint("/webhooks/", webhook.Uuid, "/deliveries")
+	req, _ = http.NewRequest(http.MethodPost, listDeliveries, bytes.NewBuffer(payloadJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("list deliveries response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	response := struct {
+		Results []entity.Delivery `json:"results"`
+	}{}
+	err = json.Unmarshal(writer.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Results) != 1 {
+		t.Fatalf("expected %d deliveries got %d", 1, len(response.Results))
+	}
+
+	// Attempt the queued delivery.
+	delivery := response.Results[0]
+	err = service.App.Deliver(&delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !<-verified {
+		t.Fatalf("expected a valid delivery signature")
+	}
+
+	if delivery.Status != entity.DeliveryDelivered {
+		t.Fatalf("expected status %s got %s", entity.DeliveryDelivered, delivery.Status)
+	}
+
+	// Redeliver.
+	redeliver := fmt.Sprint("/webhooks/", webhook.Uuid, "/deliveries/", delivery.Uuid, "/redeliver")
+	req, _ = http.NewRequest(http.MethodPost, redeliver, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", session.Token))
+	writer = httptest.NewRecorder()
+	service.App.Router.ServeHTTP(writer, req)
+
+	fmt.Println("redeliver response: ", writer.Body.String())
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, writer.Code)
+	}
+
+	if !<-verified {
+		t.Fatalf("expected a valid redelivery signature")
+	}
+
+	// Deliveries to receivers with untrusted certificates fail without the
+	// payload being received.
+	untrusted := httptest.NewTLSServer(receiver.Config.Handler)
+	defer untrusted.Close()
+
+	stored, err := entity.GetWebhook([]byte(webhook.Uuid), service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored.URL = untrusted.URL
+	err = stored.Update(service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	intercepted := delivery
+	intercepted.Status = entity.DeliveryPending
+	err = service.App.Deliver(&intercepted)
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected a certificate verification error, got %v", err)
+	}
+
+	if intercepted.Status != entity.DeliveryPending || intercepted.ResponseCode != 0 {
+		t.Fatalf("expected a pending retry got %s with status %d", intercepted.Status, intercepted.ResponseCode)
+	}
+
+	if len(verified) != 0 {
+		t.Fatalf("expected no delivery to an untrusted receiver")
+	}
+
+	// Deliveries to deleted webhooks fail without being attempted.
+	stored, err = entity.GetWebhook([]byte(webhook.Uuid), service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stored.Delete(true, service.App.Bolt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purged := delivery
+	purged.Webhook = "purged"
+	defer service.App.Bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(util.DeliveryBucket).DeleteBucket([]byte(purged.Webhook))
+	})
+
+	for _, pending := range []entity.Delivery{delivery, purged} {
+		pending.Status = entity.DeliveryPending
+		err = service.App.Deliver(&pending)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pending.Status != entity.DeliveryFailed || pending.LastError != util.ErrWebhookDeleted.Error() {
+			t.Fatalf("expected status %s got %s: %s", entity.DeliveryFailed, pending.Status, pending.LastError)
+		}
+	}
+
+	if len(verified) != 0 {
+		t.Fatalf("expected no delivery to a deleted webhook")
+	}
+}
@@ -0,0 +1,284 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// queueSize is the number of finished spans held for export, spans
+	// finished while the queue is full are dropped.
+	queueSize = 2048
+
+	// batchSize is the largest number of spans exported at once.
+	batchSize = 512
+
+	// exportInterval is how often queued spans are exported.
+	exportInterval = 5 * time.Second
+)
+
+// Exporter sends finished spans to a tracing backend, encoded as otlp json
+// export requests.
+type Exporter interface {
+	Export(ctx context.Context, request []byte) error
+}
+
+// NewTracer creates a tracer exporting the spans of the provided service in
+// batches, with the provided ratio of traces sampled.
+func NewTracer(service string, exporter Exporter, sampleRate float64) *Tracer {
+	tracer := &Tracer{
+		Service:    service,
+		SampleRate: sampleRate,
+		exporter:   exporter,
+		spans:      make(chan *Span, queueSize),
+		flush:      make(chan chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go tracer.run()
+	return tracer
+}
+
+// queue queues a finished span for export.
+func (tracer *Tracer) queue(span *Span) {
+	select {
+	case tracer.spans <- span:
+	default:
+	}
+}
+
+// run exports queued spans in batches, on every interval or once a batch
+// is full.
+func (tracer *Tracer) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := []*Span{}
+	for {
+		select {
+		case span := <-tracer.spans:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				tracer.export(batch)
+				batch = []*Span{}
+			}
+		case <-ticker.C:
+			tracer.export(batch)
+			batch = []*Span{}
+		case flushed := <-tracer.flush:
+			for len(tracer.spans) > 0 {
+				batch = append(batch, <-tracer.spans)
+			}
+			tracer.export(batch)
+			batch = []*Span{}
+			close(flushed)
+		case <-tracer.done:
+			return
+		}
+	}
+}
+
+// export sends a batch of spans, failures are dropped as tracing must not
+// affect the traced service.
+func (tracer *Tracer) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	request, err := json.Marshal(tracer.encode(batch))
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+	defer cancel()
+	tracer.exporter.Export(ctx, request)
+}
+
+// Shutdown exports the queued spans and stops the tracer.
+func (tracer *Tracer) Shutdown() {
+	if tracer.exporter == nil {
+		return
+	}
+
+	flushed := make(chan struct{})
+	tracer.flush <- flushed
+	<-flushed
+	close(tracer.done)
+
+	if closer, ok := tracer.exporter.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// writerExporter writes export requests as json lines.
+type writerExporter struct {
+	mtx    sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterExporter creates an exporter writing every export request as a
+// json line, readable by the otlp json file receiver of the collector. The
+// writer is closed on shutdown when it is an io.Closer.
+func NewWriterExporter(writer io.Writer) Exporter {
+	return &writerExporter{writer: writer}
+}
+
+// Export writes an export request.
+func (exporter *writerExporter) Export(ctx context.Context, request []byte) error {
+	exporter.mtx.Lock()
+	defer exporter.mtx.Unlock()
+	_, err := exporter.writer.Write(append(request, '\n'))
+	return err
+}
+
+// Close closes the writer of the exporter.
+func (exporter *writerExporter) Close() error {
+	if closer, ok := exporter.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// otlpExporter posts export requests to an otlp/http endpoint.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewOTLPExporter creates an exporter posting export requests to an otlp/http
+// traces endpoint, such as http://localhost:4318/v1/traces.
+func NewOTLPExporter(endpoint string, client *http.Client) Exporter {
+	return &otlpExporter{endpoint: endpoint, client: client}
+}
+
+// Export posts an export request.
+func (exporter *otlpExporter) Export(ctx context.Context, request []byte) error {
+	req, err := http.NewRequest(http.MethodPost, exporter.endpoint, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := exporter.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp export rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The otlp json encoding of export requests.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanData `json:"spans"`
+	}
+
+	scope struct {
+		Name string `json:"name"`
+	}
+
+	spanData struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            status     `json:"status"`
+	}
+
+	status struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	keyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+// statusError is the otlp status code of failed spans.
+const statusError = 2
+
+// encode encodes a batch of spans as an otlp export request.
+func (tracer *Tracer) encode(batch []*Span) exportRequest {
+	spans := make([]spanData, 0, len(batch))
+	for _, span := range batch {
+		data := spanData{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+		if span.Parent != (SpanID{}) {
+			data.ParentSpanID = span.Parent.String()
+		}
+		if span.Failed {
+			data.Status = status{Code: statusError, Message: span.Message}
+		}
+
+		span.mtx.Lock()
+		for key, value := range span.Attributes {
+			data.Attributes = append(data.Attributes, keyValue{Key: key, Value: encodeValue(value)})
+		}
+		span.mtx.Unlock()
+		spans = append(spans, data)
+	}
+
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: []keyValue{
+			{Key: "service.name", Value: encodeValue(tracer.Service)},
+		}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "einheit/boltkit/trace"}, Spans: spans}},
+	}}}
+}
+
+// encodeValue encodes an attribute value as an otlp any value.
+func encodeValue(value interface{}) map[string]interface{} {
+	switch typed := value.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": typed}
+	case bool:
+		return map[string]interface{}{"boolValue": typed}
+	case int:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(typed), 10)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+	case uint32:
+		return map[string]interface{}{"intValue": strconv.FormatUint(uint64(typed), 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": typed}
+	}
+	return map[string]interface{}{"stringValue": fmt.Sprint(value)}
+}
@@ -0,0 +1,262 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as numbered by opentelemetry.
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// traceparentHeader carries the w3c trace context of a request.
+const traceparentHeader = "Traceparent"
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span of a trace.
+type SpanID [8]byte
+
+// String returns the hex encoding of the trace id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String returns the hex encoding of the span id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Valid asserts the ids of the span context are set.
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a w3c traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a w3c traceparent header value.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	// Version 00 has exactly four fields, later versions may add fields.
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+
+	_, err := hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	if err != nil {
+		return sc, false
+	}
+
+	_, err = hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	if err != nil {
+		return sc, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.Valid()
+}
+
+// Span is a timed operation of a trace. Methods of a nil span, the span of
+// an unsampled or untraced operation, do nothing.
+type Span struct {
+	Name       string
+	Kind       int
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Failed     bool
+	Message    string
+
+	mtx    sync.Mutex
+	tracer *Tracer
+	ended  bool
+}
+
+// SetAttribute sets an attribute of the span, strings, bools, integers and
+// floats are supported.
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+
+	span.mtx.Lock()
+	defer span.mtx.Unlock()
+	span.Attributes[key] = value
+}
+
+// SetError marks the span failed with the provided error, nil errors are
+// ignored.
+func (span *Span) SetError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+
+	span.mtx.Lock()
+	defer span.mtx.Unlock()
+	span.Failed = true
+	span.Message = err.Error()
+}
+
+// Finish ends the span and queues it for export, spans are only exported
+// once.
+func (span *Span) Finish() {
+	if span == nil {
+		return
+	}
+
+	span.mtx.Lock()
+	if span.ended {
+		span.mtx.Unlock()
+		return
+	}
+	span.ended = true
+	span.End = time.Now()
+	span.mtx.Unlock()
+
+	span.tracer.queue(span)
+}
+
+// Tracer creates the spans of a process and exports them.
+type Tracer struct {
+	// Service is the name of the traced service.
+	Service string
+	// SampleRate is the ratio of traces started by the process which are
+	// sampled, traces continued from a remote parent follow its decision.
+	SampleRate float64
+
+	exporter Exporter
+	spans    chan *Span
+	flush    chan chan struct{}
+	done     chan struct{}
+}
+
+// Default is the tracer spans are started with, it traces nothing until
+// replaced with a tracer exporting spans.
+var Default = &Tracer{}
+
+// spanKey is the context key of the current span.
+type spanKey struct{}
+
+// remoteKey is the context key of the span context of a remote parent.
+type remoteKey struct{}
+
+// Start starts a span with the default tracer, as a child of the span of
+// the context if any. The returned context carries the started span.
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	return Default.Start(ctx, name, kind)
+}
+
+// Start starts a span as a child of the span of the context if any.
+func (tracer *Tracer) Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if tracer.exporter == nil {
+		return ctx, nil
+	}
+
+	var parent SpanContext
+	var sampled bool
+	if current := FromContext(ctx); current != nil {
+		parent, sampled = current.Context, true
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent, sampled = remote, remote.Sampled
+	} else {
+		sampled = tracer.sample()
+	}
+
+	if !sampled {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Context:    SpanContext{TraceID: parent.TraceID, Sampled: true},
+		Parent:     parent.SpanID,
+		Start:      time.Now(),
+		Attributes: map[string]interface{}{},
+		tracer:     tracer,
+	}
+	if !parent.Valid() {
+		rand.Read(span.Context.TraceID[:])
+	}
+	rand.Read(span.Context.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample decides whether a trace started by the process is sampled.
+func (tracer *Tracer) sample() bool {
+	if tracer.SampleRate >= 1 {
+		return true
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return float64(binary.BigEndian.Uint64(buf)>>11)/(1<<53) < tracer.SampleRate
+}
+
+// FromContext returns the current span of a context, nil when untraced.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Extract returns a context continuing the trace of the traceparent header
+// of an incoming request, if any.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, ok := ParseTraceparent(header.Get(traceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Inject sets the traceparent header of an outgoing request to the current
+// span of the context, or to the remote parent the context continues.
+func Inject(ctx context.Context, header http.Header) {
+	if span := FromContext(ctx); span != nil {
+		header.Set(traceparentHeader, span.Context.Traceparent())
+		return
+	}
+
+	if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		header.Set(traceparentHeader, remote.Traceparent())
+	}
+}
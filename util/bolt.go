@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/binary"
 	"fmt"
 	"time"
 
@@ -30,3 +31,16 @@ func CreateBucket(db *bolt.DB, bucketName string) error {
 	})
 	return err
 }
+
+// EncodeSequence encodes a bolt sequence number as a big endian key, this
+// keeps sequenced keys sorted when iterated with a cursor.
+func EncodeSequence(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// DecodeSequence decodes a sequenced key into its sequence number.
+func DecodeSequence(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key)
+}
@@ -4,25 +4,53 @@ import "encoding/json"
 
 // Config represents the server configuration file.
 type Config struct {
-	Port                string `json:"port"`
-	Debug               bool   `json:"debug"`
-	Server              string `json:"server"`
-	HTTPS               bool   `json:"https"`
-	Storage             string `json:"storage"`
-	AdminEmail          string `json:"adminemail"`
-	AdminPass           string `json:"adminpass"`
-	ResetEmail          string `json:"resetemail"`
-	InviteEmail         string `json:"inviteemail"`
-	FeedbackEmail       string `json:"feedbackemail"`
-	MailgunAPIKey       string `json:"mailgunapikey"`
-	MailgunDomain       string `json:"mailgundomain"`
-	MailgunPublicAPIKey string `json:"mailgunpublicapikey"`
-	PageLimit           uint32 `json:"pagelimit"`
-	Frontend            string `json:"frontend"`
-	AWSAccessKey        string `json:"awsaccesskey"`
-	AWSSecretKey        string `json:"awssecretkey"`
-	AWSRegion           string `json:"awsregion"`
-	AWSBucket           string `json:"awsbucket"`
+	Port                 string               `json:"port"`
+	GRPCPort             string               `json:"grpcport"`
+	Debug                bool                 `json:"debug"`
+	Server               string               `json:"server"`
+	HTTPS                bool                 `json:"https"`
+	Storage              string               `json:"storage"`
+	AdminEmail           string               `json:"adminemail"`
+	AdminPass            string               `json:"adminpass"`
+	ResetEmail           string               `json:"resetemail"`
+	InviteEmail          string               `json:"inviteemail"`
+	FeedbackEmail        string               `json:"feedbackemail"`
+	MailgunAPIKey        string               `json:"mailgunapikey"`
+	MailgunDomain        string               `json:"mailgundomain"`
+	MailgunPublicAPIKey  string               `json:"mailgunpublicapikey"`
+	PageLimit            uint32               `json:"pagelimit"`
+	MaxBodySize          int64                `json:"maxbodysize"`
+	StrictPayloads       bool                 `json:"strictpayloads"`
+	TrashRetention       uint32               `json:"trashretention"`
+	WebhookMaxAttempts   uint32               `json:"webhookmaxattempts"`
+	WebhookRetryDelay    uint32               `json:"webhookretrydelay"`
+	ReplicaOf            string               `json:"replicaof"`
+	ReplicationToken     string               `json:"replicationtoken"`
+	ReplicationCA        string               `json:"replicationca"`
+	ChangeRetention      uint32               `json:"changeretention"`
+	LegacyRouteSunset    string               `json:"legacyroutesunset"`
+	RateLimits           map[string]RateLimit `json:"ratelimits"`
+	PersistRateLimits    bool                 `json:"persistratelimits"`
+	IdempotencyTTL       uint32               `json:"idempotencyttl"`
+	TrustProxy           bool                 `json:"trustproxy"`
+	TrustedProxies       uint32               `json:"trustedproxies"`
+	GraphQLMaxDepth      uint32               `json:"graphqlmaxdepth"`
+	GraphQLMaxComplexity uint32               `json:"graphqlmaxcomplexity"`
+	EventHistory         uint32               `json:"eventhistory"`
+	EventHeartbeat       uint32               `json:"eventheartbeat"`
+	TraceExporter        string               `json:"traceexporter"`
+	TraceEndpoint        string               `json:"traceendpoint"`
+	TraceSampleRate      float64              `json:"tracesamplerate"`
+	LogFormat            string               `json:"logformat"`
+	LogLevel             string               `json:"loglevel"`
+	LogLevelRevert       uint32               `json:"loglevelrevert"`
+	LogFileSize          int64                `json:"logfilesize"`
+	LogFiles             int                  `json:"logfiles"`
+	Frontend             string               `json:"frontend"`
+	AWSAccessKey         string               `json:"awsaccesskey"`
+	AWSSecretKey         string               `json:"awssecretkey"`
+	AWSRegion            string               `json:"awsregion"`
+	AWSBucket            string               `json:"awsbucket"`
 	// MinioEndpoint       string `json:"minioendpoint"`
 	// MinioAccessKey      string `json:"minioaccesskey"`
 	// MinioSecretKey      string `json:"miniosecretkey"`
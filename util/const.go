@@ -2,18 +2,34 @@ package util
 
 // Bucket names.
 var (
-	SessionBucket   = []byte("session")
-	UserBucket      = []byte("user")
-	InviteBucket    = []byte("invite")
-	CacheBucket     = []byte("cache")
-	PassResetBucket = []byte("passreset")
-	FeedbackBucket  = []byte("feedback")
-	LogBucket       = []byte("log")
+	SessionBucket     = []byte("session")
+	UserBucket        = []byte("user")
+	InviteBucket      = []byte("invite")
+	CacheBucket       = []byte("cache")
+	PassResetBucket   = []byte("passreset")
+	FeedbackBucket    = []byte("feedback")
+	LogBucket         = []byte("log")
+	HistoryBucket     = []byte("history")
+	ChangeBucket      = []byte("changes")
+	WebhookBucket     = []byte("webhook")
+	DeliveryBucket    = []byte("delivery")
+	RateLimitBucket   = []byte("ratelimit")
+	IdempotencyBucket = []byte("idempotency")
+	IndexBucket       = []byte("index")
 )
 
 // Cache keys.
 var (
-	AdminKey = []byte("admin")
+	AdminKey       = []byte("admin")
+	ReplicationKey = []byte("replication")
+	PromotedKey    = []byte("promoted")
+	CompactedKey   = []byte("compacted")
+)
+
+// Actors.
+var (
+	// System identifies writes made by the service itself rather than a user.
+	System = "system"
 )
 
 // Access Privilige types.
@@ -25,6 +41,10 @@ var (
 
 // Scheduled Job types.
 var (
-	InviteJob    = "invite"
-	PassResetJob = "passreset"
+	InviteJob      = "invite"
+	PassResetJob   = "passreset"
+	PurgeJob       = "purge"
+	WebhookJob     = "webhook"
+	IdempotencyJob = "idempotency"
+	CompactionJob  = "compaction"
 )
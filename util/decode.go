@@ -0,0 +1,313 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"einheit/boltkit/trace"
+)
+
+const (
+	// DefaultMaxBodySize is the largest request body, in bytes, accepted by
+	// a decoder with no configured limit.
+	DefaultMaxBodySize = 1 << 20
+)
+
+// FieldError describes a single invalid field of a request payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned when a request payload has invalid fields, it
+// holds every field error found.
+type ValidationErrors []FieldError
+
+// Error implements the error interface.
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for idx, fieldErr := range errs {
+		messages[idx] = fmt.Sprintf("'%s' %s", fieldErr.Field, fieldErr.Message)
+	}
+	return fmt.Sprint("invalid payload: ", strings.Join(messages, ", "))
+}
+
+// Decoder parses request payloads into typed request structs.
+//
+// Request struct fields are named by their json tags and validated by their
+// validate tags, a comma separated list of rules:
+//
+//	required   the field must be present and not null.
+//	nonempty   a string or list field must not be empty.
+//	email      a string field must be an email address.
+//	url        a string field must be an absolute http(s) url.
+//	min=n      a number must be at least n, a string or list at least n long.
+//	max=n      a number must be at most n, a string or list at most n long.
+//	oneof=a b  a string field must be one of the space separated options.
+//
+// Unknown fields are ignored so clients can send back entities they fetched,
+// unless the decoder disallows them.
+type Decoder struct {
+	MaxBodySize           int64
+	DisallowUnknownFields bool
+}
+
+// NewDecoder creates a request decoder from the server configuration.
+func NewDecoder(cfg *Config) *Decoder {
+	decoder := &Decoder{
+		MaxBodySize:           cfg.MaxBodySize,
+		DisallowUnknownFields: cfg.StrictPayloads,
+	}
+	if decoder.MaxBodySize <= 0 {
+		decoder.MaxBodySize = DefaultMaxBodySize
+	}
+	return decoder
+}
+
+// Decode reads the body of a request into the provided request struct
+// pointer and validates it. An empty body decodes as an empty object. Type,
+// unknown field and validation failures are all collected and returned
+// together as ValidationErrors.
+func (decoder *Decoder) Decode(req *http.Request, dst interface{}) error {
+	_, span := trace.Start(req.Context(), "validate request", trace.KindInternal)
+	defer span.Finish()
+
+	err := decoder.decode(req, dst)
+	span.SetError(err)
+	return err
+}
+
+// decode reads and validates the body of a request.
+func (decoder *Decoder) decode(req *http.Request, dst interface{}) error {
+	target := reflect.ValueOf(dst)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return ErrNotApplicable(target.Type().String())
+	}
+
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(req.Body, decoder.MaxBodySize+1))
+		if err != nil {
+			// A body limited by the server fails once the limit is read.
+			if int64(len(body)) >= decoder.MaxBodySize {
+				return ErrPayloadTooLarge(decoder.MaxBodySize)
+			}
+			return ErrReadBody
+		}
+	}
+
+	if int64(len(body)) > decoder.MaxBodySize {
+		return ErrPayloadTooLarge(decoder.MaxBodySize)
+	}
+
+	payload := map[string]json.RawMessage{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		err := json.Unmarshal(body, &payload)
+		if err != nil {
+			return ErrMalformedJSON
+		}
+	}
+
+	errs := ValidationErrors{}
+	known := map[string]bool{}
+	structValue := target.Elem()
+	structType := structValue.Type()
+	for idx := 0; idx < structType.NumField(); idx++ {
+		field := structType.Field(idx)
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		known[name] = true
+
+		raw, present := payload[name]
+		present = present && string(raw) != "null"
+		if present {
+			err := json.Unmarshal(raw, structValue.Field(idx).Addr().Interface())
+			if err != nil {
+				errs = append(errs, FieldError{name, fmt.Sprint("must be of type ", typeName(field.Type))})
+				continue
+			}
+		}
+
+		errs = append(errs, validateField(name, field.Tag.Get("validate"), structValue.Field(idx), present)...)
+	}
+
+	if decoder.DisallowUnknownFields {
+		unknown := []string{}
+		for key := range payload {
+			if !known[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			errs = append(errs, FieldError{key, "is not a known field"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// fieldName returns the payload key of a request struct field, an empty
+// string if the field is not decoded.
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return tag
+}
+
+// typeName describes the json type expected for a request struct field.
+func typeName(fieldType reflect.Type) string {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprint("list of ", typeName(fieldType.Elem()))
+	}
+	return "object"
+}
+
+// validateField applies the validate tag rules of a decoded field.
+func validateField(name string, tag string, value reflect.Value, present bool) []FieldError {
+	errs := []FieldError{}
+	if tag == "" {
+		return errs
+	}
+
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if rule == "required" && !present {
+			return append(errs, FieldError{name, "is required"})
+		}
+	}
+
+	if !present {
+		return errs
+	}
+
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	for _, rule := range rules {
+		option := ""
+		if pos := strings.Index(rule, "="); pos != -1 {
+			rule, option = rule[:pos], rule[pos+1:]
+		}
+
+		message := ""
+		switch rule {
+		case "required":
+		case "nonempty":
+			if length(value) == 0 {
+				message = "must not be empty"
+			}
+		case "email":
+			address, err := mail.ParseAddress(value.String())
+			if err != nil || address.Address != value.String() {
+				message = "must be an email address"
+			}
+		case "url":
+			parsed, err := url.Parse(value.String())
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+				parsed.Host == "" {
+				message = "must be an http or https url"
+			}
+		case "min", "max":
+			bound, err := strconv.ParseFloat(option, 64)
+			if err != nil {
+				message = fmt.Sprintf("has an invalid %s rule", rule)
+				break
+			}
+
+			measure, noun := size(value)
+			if rule == "min" && measure < bound {
+				message = fmt.Sprintf("must be at least %v%s", bound, noun)
+			}
+			if rule == "max" && measure > bound {
+				message = fmt.Sprintf("must be at most %v%s", bound, noun)
+			}
+		case "oneof":
+			options := strings.Fields(option)
+			match := false
+			for _, opt := range options {
+				if value.String() == opt {
+					match = true
+					break
+				}
+			}
+			if !match {
+				message = fmt.Sprintf("must be one of '%s'", strings.Join(options, ", "))
+			}
+		default:
+			message = fmt.Sprintf("has an unknown rule '%s'", rule)
+		}
+
+		if message != "" {
+			errs = append(errs, FieldError{name, message})
+		}
+	}
+
+	return errs
+}
+
+// length returns the length of string and list values, zero otherwise.
+func length(value reflect.Value) int {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return value.Len()
+	}
+	return 0
+}
+
+// size measures a value for min and max rules, along with the unit measured.
+func size(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len(value.String())), " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items long"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
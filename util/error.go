@@ -1,8 +1,8 @@
 package util
 
 import (
-	"errors"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"strings"
 )
@@ -10,81 +10,220 @@ import (
 var (
 	// ErrUnauthorizedAccess is returned when a request does not have the
 	// needed clearance to call an endpoint.
-	ErrUnauthorizedAccess = errors.New("unauthorized access")
+	ErrUnauthorizedAccess = NewError("unauthorized", http.StatusUnauthorized, "unauthorized access")
 
 	// ErrExpiredSession is returned when a request has an expired session.
-	ErrExpiredSession = errors.New("expired session")
+	ErrExpiredSession = NewError("expired_session", http.StatusUnauthorized, "expired session")
 
 	// ErrExpiredReset is returned when the supplied password resethas
 	// already expired.
-	ErrExpiredReset = errors.New("password reset has already expired")
+	ErrExpiredReset = NewError("expired_reset", http.StatusGone, "password reset has already expired")
 
 	// ErrMalformedRequest is returned when a request has a malformed request
 	// body.
-	ErrMalformedRequest = errors.New("malformed request")
+	ErrMalformedRequest = NewError("malformed_request", http.StatusBadRequest, "malformed request")
 
 	// ErrMalformedPayload is returned when a request has a malformed request
 	// body.
-	ErrMalformedPayload = errors.New("malformed payload")
+	ErrMalformedPayload = NewError("malformed_payload", http.StatusBadRequest, "malformed payload")
 
-	// ErrMalformedJSON is returned when a json marshal fails for an entity.
-	ErrMalformedJSON = errors.New("malformed json")
+	// ErrMalformedJSON is returned when a request has a body that is not
+	// valid json.
+	ErrMalformedJSON = NewError("malformed_json", http.StatusBadRequest, "malformed json")
+
+	// ErrStorage is returned when a stored record can not be encoded or
+	// decoded.
+	ErrStorage = NewError("storage_error", http.StatusInternalServerError, "failed to read or write a stored record")
 
 	// ErrEntityJSON is returned when an update for an entity fails.
-	ErrEntityUpdate = errors.New("failed to update entity")
+	ErrEntityUpdate = NewError("entity_update_failed", http.StatusInternalServerError, "failed to update entity")
 
 	// ErrReadBody is returned when the body of a request cannot be read.
-	ErrReadBody = errors.New("failed to read request body")
+	ErrReadBody = NewError("unreadable_body", http.StatusBadRequest, "failed to read request body")
 
 	// ErrBcryptHash is returned when the a hash computation fails.
-	ErrBcryptHash = errors.New("failed to hash user password")
+	ErrBcryptHash = NewError("hash_failed", http.StatusInternalServerError, "failed to hash user password")
 
 	// ErrUnexpectedAuthorization is returned when a request has an unexpected
 	// authorization type.
-	ErrUnexpectedAuthorization = errors.New("unexpected authorization type")
+	ErrUnexpectedAuthorization = NewError("unexpected_authorization", http.StatusUnauthorized, "unexpected authorization type")
 
 	// ErrAuthorizationNotFound is returned when a request has no authorization
 	// header.
-	ErrAuthorizationNotFound = errors.New("authorization header not found")
+	ErrAuthorizationNotFound = NewError("missing_authorization", http.StatusUnauthorized, "authorization header not found")
 
 	// ErrPasswordMismatch is returned when the provided password does not
 	// match the stored password for a user.
-	ErrPasswordMismatch = errors.New("passwords do not match")
+	ErrPasswordMismatch = NewError("password_mismatch", http.StatusUnauthorized, "passwords do not match")
 
 	// ErrResetUsed is returned when the provided password reset
 	// has already been used.
-	ErrResetUsed = errors.New("password reset has already been used")
+	ErrResetUsed = NewError("reset_used", http.StatusConflict, "password reset has already been used")
 
 	// ErrNoUpdate is returned when an update call does not have updates
 	// to any of the update keys of an entity.
-	ErrNoUpdate = errors.New("supplied keys do not update entity")
+	ErrNoUpdate = NewError("no_update", http.StatusBadRequest, "supplied keys do not update entity")
+
+	// ErrNoPriorState is returned when restoring a revision that created
+	// its entity, there is no earlier state to restore.
+	ErrNoPriorState = NewError("no_prior_state", http.StatusConflict, "revision has no prior state to restore")
+
+	// ErrNotDeleted is returned when a trash operation targets an entity
+	// that has not been deleted.
+	ErrNotDeleted = NewError("not_deleted", http.StatusConflict, "entity has not been deleted")
+
+	// ErrStreamingUnsupported is returned when a streaming response is
+	// requested over a connection that cannot be flushed.
+	ErrStreamingUnsupported = NewError("streaming_unsupported", http.StatusNotImplemented, "streaming unsupported")
+
+	// ErrReadOnlyReplica is returned when a write is requested from a
+	// replica following a primary.
+	ErrReadOnlyReplica = NewError("read_only_replica", http.StatusForbidden, "writes are not accepted by a read-only replica")
+
+	// ErrNotReplica is returned when a replica operation is requested from
+	// a primary.
+	ErrNotReplica = NewError("not_replica", http.StatusConflict, "service is not a replica")
+
+	// ErrChangesCompacted is returned when changes are requested after a
+	// sequence older than the changes kept by the change feed.
+	ErrChangesCompacted = NewError("changes_compacted", http.StatusGone, "changes after the requested sequence have been compacted")
+
+	// ErrInternal is returned when a request fails unexpectedly.
+	ErrInternal = NewError("internal_error", http.StatusInternalServerError, "the request could not be completed")
+
+	// ErrForbidden is returned when a request has a valid session without
+	// the role an endpoint requires.
+	ErrForbidden = NewError("forbidden", http.StatusForbidden, "insufficient privileges")
+
+	// ErrRouteNotFound is returned when no endpoint matches a request.
+	ErrRouteNotFound = NewError("route_not_found", http.StatusNotFound, "no endpoint matches the request")
+
+	// ErrInviteExists is returned when an invite is created for an email
+	// that already has one.
+	ErrInviteExists = NewError("invite_exists", http.StatusConflict, "invite already exists for provided email")
+
+	// ErrInviteMismatch is returned when a user registers with an invite
+	// issued to a different email.
+	ErrInviteMismatch = NewError("invite_mismatch", http.StatusBadRequest, "invite not associated with user being created")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused
+	// for a different request.
+	ErrIdempotencyKeyReused = NewError("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was used for a different request")
+
+	// ErrIdempotencyInProgress is returned when a request is retried with
+	// an idempotency key while the original request is being processed.
+	ErrIdempotencyInProgress = NewError("idempotency_in_progress", http.StatusConflict, "a request with the idempotency key is being processed")
+
+	// ErrExpiredInvite is returned when a user registers with an expired
+	// invite.
+	ErrExpiredInvite = NewError("expired_invite", http.StatusGone, "invite expired")
+
+	// ErrInviteUnavailable is returned when a user registers with an invite
+	// that is no longer pending.
+	ErrInviteUnavailable = NewError("invite_unavailable", http.StatusConflict, "invite has already been accepted or cancelled")
+
+	// ErrWebhookDeleted is recorded as the error of deliveries to a webhook
+	// deleted before they were made.
+	ErrWebhookDeleted = NewError("webhook_deleted", http.StatusGone, "webhook has been deleted")
 )
 
+// Error is an error carrying a stable machine readable code and the http
+// status it is responded with. Details hold any further context a client
+// can act on.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Details map[string]interface{}
+}
+
+// NewError creates an error with the provided code, http status and message.
+func NewError(code string, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+// Error implements the error interface.
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// withDetails returns a copy of the error with the provided details.
+func (err *Error) withDetails(details map[string]interface{}) *Error {
+	detailed := *err
+	detailed.Details = details
+	return &detailed
+}
+
 // ErrKeyNotFound is returned when a query returns nothing for the key supplied.
 func ErrKeyNotFound(key string) error {
-	return fmt.Errorf("associated value for '%s' not found", key)
+	return NewError("not_found", http.StatusNotFound,
+		fmt.Sprintf("associated value for '%s' not found", key)).
+		withDetails(map[string]interface{}{"key": key})
 }
 
 // ErrInvalidParam is returned when a an unexpected parameter type is
 // encountered.
 func ErrInvalidParameter(key string) error {
-	return fmt.Errorf("invalid parameter type for '%s'", key)
+	return NewError("invalid_parameter", http.StatusBadRequest,
+		fmt.Sprintf("invalid parameter type for '%s'", key)).
+		withDetails(map[string]interface{}{"parameter": key})
 }
 
 // ErrInvalidParameterOption is returned when a an unexpected parameter option
 // is encountered in a context making it invalid.
 func ErrInvalidParameterOption(key string, value interface{}, expectation interface{}) error {
-	return fmt.Errorf("invalid option for parameter type '%s' with value '%v', expected '%v'", key, value, expectation)
+	return NewError("invalid_parameter_option", http.StatusBadRequest,
+		fmt.Sprintf("invalid option for parameter type '%s' with value '%v', expected '%v'", key, value, expectation)).
+		withDetails(map[string]interface{}{"parameter": key, "value": value, "expected": expectation})
 }
 
 // ErrNotApplicable is returned when functionality is not applicable for an entity.
 func ErrNotApplicable(entity string) error {
-	return fmt.Errorf("functionality not applicable to entity '%s'", entity)
+	return NewError("not_applicable", http.StatusBadRequest,
+		fmt.Sprintf("functionality not applicable to entity '%s'", entity)).
+		withDetails(map[string]interface{}{"entity": entity})
+}
+
+// ErrDeliveryRejected is returned when a webhook endpoint responds to a
+// delivery with a non-success status.
+func ErrDeliveryRejected(status int) error {
+	return NewError("delivery_rejected", http.StatusBadGateway,
+		fmt.Sprintf("delivery rejected with status %d", status)).
+		withDetails(map[string]interface{}{"upstreamStatus": status})
+}
+
+// ErrPayloadTooLarge is returned when a request body exceeds the accepted
+// size.
+func ErrPayloadTooLarge(limit int64) error {
+	return NewError("payload_too_large", http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("request payload exceeds %d bytes", limit)).
+		withDetails(map[string]interface{}{"limit": limit})
+}
+
+// ErrRouteRetired is returned when a deprecated route is requested past its
+// sunset.
+func ErrRouteRetired(successor string) error {
+	return NewError("route_retired", http.StatusGone, "route has been retired").
+		withDetails(map[string]interface{}{"successor": successor})
+}
+
+// ErrRateLimited is returned when a client exceeds the rate limit of a route,
+// it may retry after the provided number of seconds.
+func ErrRateLimited(retryAfter int64) error {
+	return NewError("rate_limited", http.StatusTooManyRequests,
+		fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter)).
+		withDetails(map[string]interface{}{"retryAfter": retryAfter})
 }
 
 // ErrParameterGroup is returned a set of parameters are expected together but
 // are not in a request.
 func ErrParameterGroup(parameters []string) error {
-	return fmt.Errorf("parameters '%s' are expected together in a request",
-		strings.Join(parameters, string(filepath.Separator)))
+	return NewError("parameter_group", http.StatusBadRequest,
+		fmt.Sprintf("parameters '%s' are expected together in a request",
+			strings.Join(parameters, string(filepath.Separator)))).
+		withDetails(map[string]interface{}{"parameters": parameters})
 }
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a message published on an event bus. Roles are the session roles
+// allowed to receive it, the admin receives every event.
+type Event struct {
+	ID    uint64
+	Name  string
+	Data  interface{}
+	Roles []string
+}
+
+// EventBus delivers published events to its subscribers in memory, keeping
+// the latest events so subscribers can resume after reconnecting.
+type EventBus struct {
+	mtx         sync.Mutex
+	lastID      uint64
+	history     []Event
+	size        int
+	closed      bool
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published on a bus after it subscribed.
+// Events is closed when the subscription is closed, or dropped for falling
+// behind the events published.
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	bus    *EventBus
+}
+
+// NewEventBus creates an event bus keeping up to the provided number of past
+// events. Event ids start from the creation time, so ids of a bus created
+// after a restart follow those of the previous bus.
+func NewEventBus(size int) *EventBus {
+	return &EventBus{
+		lastID:      uint64(time.Now().UnixNano()),
+		history:     make([]Event, 0, size),
+		size:        size,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish sends an event to every subscriber, subscribers whose buffer is
+// full are dropped rather than blocking the publisher.
+func (bus *EventBus) Publish(name string, data interface{}, roles ...string) Event {
+	bus.mtx.Lock()
+	defer bus.mtx.Unlock()
+
+	bus.lastID++
+	event := Event{ID: bus.lastID, Name: name, Data: data, Roles: roles}
+	if len(bus.history) == bus.size && bus.size > 0 {
+		copy(bus.history, bus.history[1:])
+		bus.history = bus.history[:bus.size-1]
+	}
+	if bus.size > 0 {
+		bus.history = append(bus.history, event)
+	}
+
+	for subscription := range bus.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			bus.drop(subscription)
+		}
+	}
+	return event
+}
+
+// Subscribe subscribes to the events published from now on, along with the
+// kept events published after the provided event id. All kept events are
+// replayed for ids older than the kept events, a zero id replays none.
+func (bus *EventBus) Subscribe(lastID uint64) *Subscription {
+	bus.mtx.Lock()
+	defer bus.mtx.Unlock()
+
+	missed := []Event{}
+	if lastID != 0 {
+		for _, event := range bus.history {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	events := make(chan Event, len(missed)+bus.size+1)
+	for _, event := range missed {
+		events <- event
+	}
+
+	subscription := &Subscription{Events: events, events: events, bus: bus}
+	if bus.closed {
+		close(events)
+		return subscription
+	}
+
+	bus.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+// Close closes every subscription, ending the streams fed by the bus so
+// servers can shut down. Later subscriptions are closed once replayed.
+func (bus *EventBus) Close() {
+	bus.mtx.Lock()
+	defer bus.mtx.Unlock()
+
+	bus.closed = true
+	for subscription := range bus.subscribers {
+		bus.drop(subscription)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (bus *EventBus) Subscribers() int {
+	bus.mtx.Lock()
+	defer bus.mtx.Unlock()
+	return len(bus.subscribers)
+}
+
+// Close closes the subscription, it is safe to close a dropped subscription.
+func (subscription *Subscription) Close() {
+	subscription.bus.mtx.Lock()
+	defer subscription.bus.mtx.Unlock()
+	subscription.bus.drop(subscription)
+}
+
+// drop removes a subscriber and closes its events, the bus must be locked.
+func (bus *EventBus) drop(subscription *Subscription) {
+	if _, ok := bus.subscribers[subscription]; ok {
+		delete(bus.subscribers, subscription)
+		close(subscription.events)
+	}
+}
@@ -59,9 +59,52 @@ func (entity *JSONList) Scan(src interface{}) error {
 	return err
 }
 
-// RespondWithError writes a JSON error message to a request.
-func RespondWithError(w http.ResponseWriter, code int, err error) {
-	RespondWithJSON(w, code, map[string]string{"error": err.Error()})
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type    string                 `json:"type"`
+	Title   string                 `json:"title"`
+	Status  int                    `json:"status"`
+	Detail  string                 `json:"detail"`
+	Code    string                 `json:"code"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Fields  []FieldError           `json:"fields,omitempty"`
+}
+
+// NewProblem describes an error as problem details. Errors without a code
+// are treated as internal failures, their messages are logged rather than
+// sent to the client.
+func NewProblem(err error) *Problem {
+	problem := &Problem{Type: "about:blank"}
+	switch typed := err.(type) {
+	case *Error:
+		problem.Status = typed.Status
+		problem.Detail = typed.Message
+		problem.Code = typed.Code
+		problem.Details = typed.Details
+	case ValidationErrors:
+		problem.Status = http.StatusUnprocessableEntity
+		problem.Detail = typed.Error()
+		problem.Code = "validation_failed"
+		problem.Fields = typed
+	default:
+		log.Errorf("internal error: %v", err)
+		problem.Status = ErrInternal.Status
+		problem.Detail = ErrInternal.Message
+		problem.Code = ErrInternal.Code
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+	return problem
+}
+
+// RespondWithError writes an error to a request as RFC 7807 problem details,
+// with the http status of the error.
+func RespondWithError(writer http.ResponseWriter, err error) {
+	problem := NewProblem(err)
+	response, _ := json.Marshal(problem)
+	writer.Header().Set("Content-Type", "application/problem+json")
+	writer.WriteHeader(problem.Status)
+	writer.Write(response)
 }
 
 // RespondWithJSON writes a JSON payload to a request.
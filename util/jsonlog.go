@@ -0,0 +1,181 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// levelNames are the names of the log levels in json log lines.
+var levelNames = map[btclog.Level]string{
+	btclog.LevelTrace:    "trace",
+	btclog.LevelDebug:    "debug",
+	btclog.LevelInfo:     "info",
+	btclog.LevelWarn:     "warn",
+	btclog.LevelError:    "error",
+	btclog.LevelCritical: "critical",
+}
+
+// JSONBackend writes the messages of its subsystem loggers as json lines, it
+// is the structured counterpart of btclog.Backend.
+type JSONBackend struct {
+	mtx    sync.Mutex
+	writer io.Writer
+}
+
+// NewJSONBackend creates a json logging backend writing to the provided
+// writer.
+func NewJSONBackend(writer io.Writer) *JSONBackend {
+	return &JSONBackend{writer: writer}
+}
+
+// Logger creates a logger of the provided subsystem, logging at the info
+// level.
+func (backend *JSONBackend) Logger(subsystem string) btclog.Logger {
+	level := uint32(btclog.LevelInfo)
+	return &jsonLogger{backend: backend, subsystem: subsystem, level: &level}
+}
+
+// jsonLine is a json log line.
+type jsonLine struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+	UserID    string `json:"userId,omitempty"`
+	Route     string `json:"route,omitempty"`
+}
+
+// write writes a message as a json line.
+func (backend *JSONBackend) write(level btclog.Level, subsystem string, fields LogFields, message string) {
+	line, err := json.Marshal(jsonLine{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Level:     levelNames[level],
+		Subsystem: subsystem,
+		Message:   message,
+		RequestID: fields.RequestID,
+		UserID:    fields.UserID,
+		Route:     fields.Route,
+	})
+	if err != nil {
+		return
+	}
+
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+	backend.writer.Write(append(line, '\n'))
+}
+
+// jsonLogger is a subsystem logger of a json backend. Loggers derived with
+// request fields share the level of the subsystem logger.
+type jsonLogger struct {
+	backend   *JSONBackend
+	subsystem string
+	level     *uint32
+	fields    LogFields
+}
+
+// WithFields returns a logger adding the provided request fields to every
+// line.
+func (logger *jsonLogger) WithFields(fields LogFields) btclog.Logger {
+	return &jsonLogger{backend: logger.backend, subsystem: logger.subsystem, level: logger.level, fields: fields}
+}
+
+// log writes a message logged at the provided level if enabled.
+func (logger *jsonLogger) log(level btclog.Level, message func() string) {
+	if level < logger.Level() {
+		return
+	}
+	logger.backend.write(level, logger.subsystem, logger.fields, message())
+}
+
+// sprint formats a message as btclog does, with operands separated by spaces.
+func sprint(args []interface{}) func() string {
+	return func() string {
+		return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	}
+}
+
+// sprintf formats a message from a format string.
+func sprintf(format string, args []interface{}) func() string {
+	return func() string {
+		return fmt.Sprintf(format, args...)
+	}
+}
+
+// Trace logs at the trace level.
+func (logger *jsonLogger) Trace(args ...interface{}) {
+	logger.log(btclog.LevelTrace, sprint(args))
+}
+
+// Tracef logs a formatted message at the trace level.
+func (logger *jsonLogger) Tracef(format string, args ...interface{}) {
+	logger.log(btclog.LevelTrace, sprintf(format, args))
+}
+
+// Debug logs at the debug level.
+func (logger *jsonLogger) Debug(args ...interface{}) {
+	logger.log(btclog.LevelDebug, sprint(args))
+}
+
+// Debugf logs a formatted message at the debug level.
+func (logger *jsonLogger) Debugf(format string, args ...interface{}) {
+	logger.log(btclog.LevelDebug, sprintf(format, args))
+}
+
+// Info logs at the info level.
+func (logger *jsonLogger) Info(args ...interface{}) {
+	logger.log(btclog.LevelInfo, sprint(args))
+}
+
+// Infof logs a formatted message at the info level.
+func (logger *jsonLogger) Infof(format string, args ...interface{}) {
+	logger.log(btclog.LevelInfo, sprintf(format, args))
+}
+
+// Warn logs at the warn level.
+func (logger *jsonLogger) Warn(args ...interface{}) {
+	logger.log(btclog.LevelWarn, sprint(args))
+}
+
+// Warnf logs a formatted message at the warn level.
+func (logger *jsonLogger) Warnf(format string, args ...interface{}) {
+	logger.log(btclog.LevelWarn, sprintf(format, args))
+}
+
+// Error logs at the error level.
+func (logger *jsonLogger) Error(args ...interface{}) {
+	logger.log(btclog.LevelError, sprint(args))
+}
+
+// Errorf logs a formatted message at the error level.
+func (logger *jsonLogger) Errorf(format string, args ...interface{}) {
+	logger.log(btclog.LevelError, sprintf(format, args))
+}
+
+// Critical logs at the critical level.
+func (logger *jsonLogger) Critical(args ...interface{}) {
+	logger.log(btclog.LevelCritical, sprint(args))
+}
+
+// Criticalf logs a formatted message at the critical level.
+func (logger *jsonLogger) Criticalf(format string, args ...interface{}) {
+	logger.log(btclog.LevelCritical, sprintf(format, args))
+}
+
+// Level returns the level of the logger.
+func (logger *jsonLogger) Level() btclog.Level {
+	return btclog.Level(atomic.LoadUint32(logger.level))
+}
+
+// SetLevel sets the level of the logger.
+func (logger *jsonLogger) SetLevel(level btclog.Level) {
+	atomic.StoreUint32(logger.level, uint32(level))
+}
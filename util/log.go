@@ -5,7 +5,11 @@
 
 package util
 
-import "github.com/btcsuite/btclog"
+import (
+	"context"
+
+	"github.com/btcsuite/btclog"
+)
 
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
@@ -29,3 +33,42 @@ func DisableLog() {
 func UseLogger(logger btclog.Logger) {
 	log = logger
 }
+
+// LogFields are the fields of the request a message is logged for.
+type LogFields struct {
+	RequestID string
+	UserID    string
+	Route     string
+}
+
+// logFieldsKey is the context key of the log fields of a request.
+type logFieldsKey struct{}
+
+// WithLogFields returns a context carrying the log fields of a request.
+func WithLogFields(ctx context.Context, fields LogFields) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LogFieldsFrom returns the log fields carried by a context.
+func LogFieldsFrom(ctx context.Context) (LogFields, bool) {
+	fields, ok := ctx.Value(logFieldsKey{}).(LogFields)
+	return fields, ok
+}
+
+// ContextLogger returns a logger adding the log fields of the context to
+// every message. Loggers which can not add fields, such as those of the
+// text backend, are returned as is.
+func ContextLogger(logger btclog.Logger, ctx context.Context) btclog.Logger {
+	fields, ok := LogFieldsFrom(ctx)
+	if !ok {
+		return logger
+	}
+
+	structured, ok := logger.(interface {
+		WithFields(fields LogFields) btclog.Logger
+	})
+	if !ok {
+		return logger
+	}
+	return structured.WithFields(fields)
+}
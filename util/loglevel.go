@@ -0,0 +1,139 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// logLevelNames are the levels subsystem loggers can be set to.
+var logLevelNames = []string{"trace", "debug", "info", "warn", "error", "critical", "off"}
+
+// LogLevels sets the levels of the subsystem loggers at runtime. Changes may
+// be temporary, reverting to the levels they replaced once their duration
+// elapses.
+type LogLevels struct {
+	mtx      sync.Mutex
+	loggers  map[string]btclog.Logger
+	previous map[string]btclog.Level
+	revert   *time.Timer
+	revertAt time.Time
+}
+
+// NewLogLevels creates the level control of the provided subsystem loggers,
+// keyed by subsystem.
+func NewLogLevels(loggers map[string]btclog.Logger) *LogLevels {
+	return &LogLevels{loggers: loggers}
+}
+
+// Subsystems returns the sorted subsystems.
+func (levels *LogLevels) Subsystems() []string {
+	subsystems := make([]string, 0, len(levels.loggers))
+	for subsystem := range levels.loggers {
+		subsystems = append(subsystems, subsystem)
+	}
+	sort.Strings(subsystems)
+	return subsystems
+}
+
+// Levels returns the level of every subsystem, along with the time pending
+// temporary changes revert at, zero when there are none.
+func (levels *LogLevels) Levels() (map[string]string, time.Time) {
+	levels.mtx.Lock()
+	defer levels.mtx.Unlock()
+
+	current := map[string]string{}
+	for subsystem, logger := range levels.loggers {
+		current[subsystem] = levelName(logger.Level())
+	}
+	return current, levels.revertAt
+}
+
+// Set sets the level of a subsystem, or of every subsystem when none is
+// provided. A change with a positive duration reverts once it elapses,
+// extending the pending revert if any. A change without a duration is kept
+// when pending changes revert.
+func (levels *LogLevels) Set(subsystem string, level string, revertAfter time.Duration) error {
+	parsed, ok := btclog.LevelFromString(level)
+	if !ok {
+		return ErrInvalidParameterOption("level", level, strings.Join(logLevelNames, ", "))
+	}
+
+	subsystems := levels.Subsystems()
+	if subsystem != "" {
+		if _, ok := levels.loggers[subsystem]; !ok {
+			return ErrInvalidParameterOption("subsystem", subsystem, strings.Join(subsystems, ", "))
+		}
+		subsystems = []string{subsystem}
+	}
+
+	levels.mtx.Lock()
+	defer levels.mtx.Unlock()
+
+	if revertAfter > 0 {
+		if levels.previous == nil {
+			levels.previous = map[string]btclog.Level{}
+			for name, logger := range levels.loggers {
+				levels.previous[name] = logger.Level()
+			}
+		}
+
+		if levels.revert != nil {
+			levels.revert.Stop()
+		}
+
+		// A stopped timer may already be firing, only the pending timer
+		// reverts.
+		var timer *time.Timer
+		timer = time.AfterFunc(revertAfter, func() {
+			levels.mtx.Lock()
+			defer levels.mtx.Unlock()
+			if levels.revert == timer {
+				levels.restore()
+			}
+		})
+		levels.revert = timer
+		levels.revertAt = time.Now().Add(revertAfter)
+	}
+
+	for _, name := range subsystems {
+		levels.loggers[name].SetLevel(parsed)
+		if revertAfter <= 0 && levels.previous != nil {
+			levels.previous[name] = parsed
+		}
+	}
+	return nil
+}
+
+// Revert restores the levels replaced by temporary changes.
+func (levels *LogLevels) Revert() {
+	levels.mtx.Lock()
+	defer levels.mtx.Unlock()
+	levels.restore()
+}
+
+// restore restores the levels replaced by temporary changes, the levels must
+// be locked.
+func (levels *LogLevels) restore() {
+	if levels.revert != nil {
+		levels.revert.Stop()
+	}
+	for name, level := range levels.previous {
+		levels.loggers[name].SetLevel(level)
+	}
+
+	levels.previous = nil
+	levels.revert = nil
+	levels.revertAt = time.Time{}
+}
+
+// levelName returns the name of a log level.
+func levelName(level btclog.Level) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return "off"
+}
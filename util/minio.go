@@ -2,8 +2,11 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 
+	"einheit/boltkit/trace"
+
 	minio "github.com/minio/minio-go"
 )
 
@@ -50,10 +53,17 @@ func (mc *Minio) CreateBucket(bucketName string) error {
 }
 
 // Upload uploads data to the specified bucket.
-func (mc *Minio) Upload(objectPath string, object *[]byte) (*map[string]string, error) {
+func (mc *Minio) Upload(ctx context.Context, objectPath string, object *[]byte) (*map[string]string, error) {
+	ctx, span := trace.Start(ctx, "minio.upload", trace.KindClient)
+	defer span.Finish()
+	span.SetAttribute("storage.bucket", mc.Bucket)
+	span.SetAttribute("storage.key", objectPath)
+	span.SetAttribute("storage.size", len(*object))
+
 	mime := GetMime(object)
-	_, err := mc.Client.PutObject(mc.Bucket, objectPath, bytes.NewReader(*object), int64(len(*object)), minio.PutObjectOptions{ContentType: mime})
+	_, err := mc.Client.PutObjectWithContext(ctx, mc.Bucket, objectPath, bytes.NewReader(*object), int64(len(*object)), minio.PutObjectOptions{ContentType: mime})
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
@@ -1,15 +1,25 @@
 package util
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"net/http"
 	"strings"
 
+	"einheit/boltkit/metrics"
+	"einheit/boltkit/trace"
+
 	mailgun "github.com/mailgun/mailgun-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// emailsSent counts the emails sent, by outcome.
+var emailsSent = metrics.NewCounter("boltkit_emails_total", "Emails sent, by outcome.", "outcome")
+
 // Round rounding function.
 func Round(f float64, places uint) float64 {
 	shift := math.Pow(10, float64(places))
@@ -31,11 +41,30 @@ func BetweenInclusive(value string, a string, b string) string {
 }
 
 // SendEmail sends an email.
-func SendEmail(mailGun mailgun.Mailgun, fromName string, fromEmail string, subject string, body string, to string) error {
+func SendEmail(ctx context.Context, mailGun mailgun.Mailgun, fromName string, fromEmail string, subject string, body string, to string) error {
+	_, span := trace.Start(ctx, "send email", trace.KindClient)
+	defer span.Finish()
+	span.SetAttribute("email.subject", subject)
+
 	fromFormat := fmt.Sprintf("%s <%s>", fromName, fromEmail)
 	message := mailGun.NewMessage(fromFormat, subject, "message", to)
 	message.SetHtml(body)
 	_, _, err := mailGun.Send(message)
+	if err != nil {
+		span.SetError(err)
+		emailsSent.Inc("failure")
+		ContextLogger(log, ctx).Errorf("failed to send email %q: %v", subject, err)
+		return err
+	}
+
+	emailsSent.Inc("success")
+	return nil
+}
+
+// PingMailer asserts the sending domain is reachable with the credentials of
+// the mailer.
+func PingMailer(mailGun mailgun.Mailgun, domain string) error {
+	_, _, _, err := mailGun.GetSingleDomain(domain)
 	return err
 }
 
@@ -48,6 +77,14 @@ func BcryptHash(plaintext string) (string, error) {
 	return string(hashedPassword), nil
 }
 
+// HMACSignature computes the hex encoded HMAC-SHA256 of a payload with the
+// supplied secret.
+func HMACSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // GetSessionToken retrieves the session token from a request. The Authorization
 // format expected is: 'Token sessiontoken'.
 func GetSessionToken(request *http.Request) (string, error) {
@@ -0,0 +1,357 @@
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query field types.
+const (
+	// StringField is filtered by exact match, against any of the values
+	// provided for it.
+	StringField = "string"
+	// BoolField is filtered by exact match.
+	BoolField = "bool"
+	// TimeField holds unix time, it is filtered by range with the After and
+	// Before suffixed parameters.
+	TimeField = "time"
+)
+
+// Reserved list query parameters.
+const (
+	searchParam = "q"
+	sortParam   = "sort"
+	offsetParam = "offset"
+)
+
+// QueryField describes an entity field list queries can filter by.
+type QueryField struct {
+	// Name is the json name of the field.
+	Name string
+	// Param is the query parameter of the field, defaults to its name.
+	Param    string
+	Type     string
+	Options  []string
+	Sortable bool
+}
+
+// QuerySchema describes the fields a list query of an entity can filter,
+// search and sort by.
+type QuerySchema struct {
+	Fields []QueryField
+	// Search are the fields the search term is matched against.
+	Search []string
+}
+
+// QueryParam describes a query parameter of a list query.
+type QueryParam struct {
+	Name        string
+	Type        string
+	Options     []string
+	Description string
+	// Repeated parameters match any of their values.
+	Repeated bool
+}
+
+// Filter is a condition on a field of a queried entity.
+type Filter struct {
+	Field  string
+	Type   string
+	Values []string
+	Bool   bool
+	After  int64
+	Before int64
+}
+
+// Query is a validated list query. Filters are combined, an entity matches
+// when it matches all filters and contains the search term in any of the
+// searched fields.
+type Query struct {
+	Term    string
+	Search  []string
+	Filters []Filter
+	Sort    string
+	Desc    bool
+	Offset  uint32
+}
+
+// param returns the query parameter of a field.
+func (field QueryField) param() string {
+	if field.Param != "" {
+		return field.Param
+	}
+	return field.Name
+}
+
+// NewQuery creates a query searching for the provided term, at the provided
+// offset.
+func (schema *QuerySchema) NewQuery(term string, offset uint32) *Query {
+	return &Query{Term: term, Search: schema.Search, Offset: offset}
+}
+
+// Parse reads and validates a list query from url query parameters. Every
+// invalid parameter is reported.
+func (schema *QuerySchema) Parse(values url.Values) (*Query, error) {
+	query := schema.NewQuery(values.Get(searchParam), 0)
+	errs := ValidationErrors{}
+	known := map[string]bool{searchParam: true, sortParam: true, offsetParam: true,
+		fieldsParam: true, includeParam: true}
+
+	if values.Get(offsetParam) != "" {
+		offset, err := strconv.ParseUint(values.Get(offsetParam), 10, 32)
+		if err != nil {
+			errs = append(errs, FieldError{offsetParam, "must be of type integer"})
+		}
+		query.Offset = uint32(offset)
+	}
+
+	for _, field := range schema.Fields {
+		param := field.param()
+		switch field.Type {
+		case TimeField:
+			filter := Filter{Field: field.Name, Type: field.Type}
+			for _, bound := range []string{"After", "Before"} {
+				name := param + bound
+				known[name] = true
+				if values.Get(name) == "" {
+					continue
+				}
+
+				unix, err := parseTime(values.Get(name))
+				if err != nil {
+					errs = append(errs, FieldError{name, "must be a unix time or an RFC 3339 time"})
+					continue
+				}
+
+				if bound == "After" {
+					filter.After = unix
+				} else {
+					filter.Before = unix
+				}
+			}
+
+			if filter.After != 0 || filter.Before != 0 {
+				query.Filters = append(query.Filters, filter)
+			}
+		case BoolField:
+			known[param] = true
+			if values.Get(param) == "" {
+				continue
+			}
+
+			state, err := strconv.ParseBool(values.Get(param))
+			if err != nil {
+				errs = append(errs, FieldError{param, "must be of type boolean"})
+				continue
+			}
+			query.Filters = append(query.Filters, Filter{Field: field.Name, Type: field.Type, Bool: state})
+		default:
+			known[param] = true
+			options := values[param]
+			if len(options) == 0 {
+				continue
+			}
+
+			if len(field.Options) > 0 {
+				for _, option := range options {
+					if !contains(field.Options, option) {
+						errs = append(errs, FieldError{param,
+							fmt.Sprintf("must be one of '%s'", strings.Join(field.Options, ", "))})
+						break
+					}
+				}
+			}
+			query.Filters = append(query.Filters, Filter{Field: field.Name, Type: field.Type, Values: options})
+		}
+	}
+
+	if sortBy := values.Get(sortParam); sortBy != "" {
+		query.Desc = strings.HasPrefix(sortBy, "-")
+		query.Sort = strings.TrimPrefix(sortBy, "-")
+		if !contains(schema.sortable(), query.Sort) {
+			errs = append(errs, FieldError{sortParam,
+				fmt.Sprintf("must be one of '%s', prefixed with '-' to sort descending",
+					strings.Join(schema.sortable(), ", "))})
+		}
+	}
+
+	unknown := []string{}
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, FieldError{key, "is not a known filter"})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return query, nil
+}
+
+// Params describes the query parameters accepted by the schema.
+func (schema *QuerySchema) Params() []QueryParam {
+	params := []QueryParam{
+		{Name: searchParam, Type: StringField,
+			Description: fmt.Sprintf("matched against '%s'", strings.Join(schema.Search, ", "))},
+	}
+
+	for _, field := range schema.Fields {
+		param := field.param()
+		switch field.Type {
+		case TimeField:
+			params = append(params,
+				QueryParam{Name: param + "After", Type: field.Type, Description: fmt.Sprint("only later ", field.Name)},
+				QueryParam{Name: param + "Before", Type: field.Type, Description: fmt.Sprint("only earlier ", field.Name)})
+		case BoolField:
+			params = append(params, QueryParam{Name: param, Type: field.Type})
+		default:
+			params = append(params, QueryParam{Name: param, Type: field.Type, Options: field.Options,
+				Description: "repeat to match any of the values", Repeated: true})
+		}
+	}
+
+	sortable := []string{}
+	for _, name := range schema.sortable() {
+		sortable = append(sortable, name, "-"+name)
+	}
+	return append(params, QueryParam{Name: sortParam, Type: StringField, Options: sortable,
+		Description: "the field to sort by, prefixed with '-' to sort descending"})
+}
+
+// sortable returns the names of the fields a query can sort by.
+func (schema *QuerySchema) sortable() []string {
+	names := []string{}
+	for _, field := range schema.Fields {
+		if field.Sortable {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// Unfiltered asserts the query neither searches nor filters.
+func (query *Query) Unfiltered() bool {
+	return query.Term == "" && len(query.Filters) == 0
+}
+
+// Match asserts an entity, a struct or pointer to one, satisfies the query.
+func (query *Query) Match(entity interface{}) bool {
+	value := reflect.Indirect(reflect.ValueOf(entity))
+	for _, filter := range query.Filters {
+		field := jsonField(value, filter.Field)
+		if !field.IsValid() {
+			return false
+		}
+
+		switch filter.Type {
+		case TimeField:
+			unix := field.Int()
+			if (filter.After != 0 && unix <= filter.After) ||
+				(filter.Before != 0 && unix >= filter.Before) {
+				return false
+			}
+		case BoolField:
+			if field.Bool() != filter.Bool {
+				return false
+			}
+		default:
+			if !contains(filter.Values, field.String()) {
+				return false
+			}
+		}
+	}
+
+	if query.Term == "" {
+		return true
+	}
+
+	term := strings.ToLower(query.Term)
+	for _, name := range query.Search {
+		field := jsonField(value, name)
+		if field.IsValid() && strings.Contains(strings.ToLower(field.String()), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// SortSlice orders a slice of entities by the sort field of the query, the
+// order of entities with equal fields is kept, and reversed when sorting
+// descending.
+func (query *Query) SortSlice(entities interface{}) {
+	if query.Sort == "" {
+		return
+	}
+
+	slice := reflect.ValueOf(entities)
+	sort.SliceStable(entities, func(i, j int) bool {
+		left := jsonField(slice.Index(i), query.Sort)
+		right := jsonField(slice.Index(j), query.Sort)
+		switch left.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return left.Int() < right.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return left.Uint() < right.Uint()
+		case reflect.Bool:
+			return !left.Bool() && right.Bool()
+		}
+		return strings.ToLower(left.String()) < strings.ToLower(right.String())
+	})
+
+	if query.Desc {
+		swap := reflect.Swapper(entities)
+		for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+}
+
+// jsonField returns the field of a struct value with the provided json name.
+func jsonField(value reflect.Value, name string) reflect.Value {
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	structType := value.Type()
+	for idx := 0; idx < structType.NumField(); idx++ {
+		if fieldName(structType.Field(idx)) == name {
+			return value.Field(idx)
+		}
+	}
+	return reflect.Value{}
+}
+
+// parseTime reads a unix time or an RFC 3339 time.
+func parseTime(value string) (int64, error) {
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return unix, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Unix(), nil
+}
+
+// contains asserts a value is one of the provided options.
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// RateLimitByIP keys a rate limit by the address of the client.
+	RateLimitByIP = "ip"
+	// RateLimitByUser keys a rate limit by the user of the session, or by
+	// the address of the client for requests without a session.
+	RateLimitByUser = "user"
+
+	// rateSweepInterval is how often buckets refilled to capacity are
+	// dropped, they are indistinguishable from new buckets.
+	rateSweepInterval = time.Minute
+)
+
+// RateLimit configures a token bucket rate limit. Buckets hold up to burst
+// tokens and are refilled at rate tokens per minute, every request takes a
+// token. A rate of zero disables the limit.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst uint32  `json:"burst"`
+	By    string  `json:"by"`
+}
+
+// TokenBucket is the rate limit state of a client.
+type TokenBucket struct {
+	Tokens  float64 `json:"tokens"`
+	Updated int64   `json:"updated"`
+	Full    int64   `json:"full"`
+}
+
+// RateStatus is the outcome of taking a token from a bucket.
+type RateStatus struct {
+	Allowed    bool
+	Limit      uint32
+	Remaining  uint32
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimiter tracks the token buckets of rate limited clients in memory.
+type RateLimiter struct {
+	mtx     sync.Mutex
+	buckets map[string]*TokenBucket
+	swept   time.Time
+}
+
+// NewRateLimiter creates an empty rate limiter.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: map[string]*TokenBucket{},
+		swept:   time.Now(),
+	}
+}
+
+// Take takes a token from the bucket associated with the provided key,
+// refilling it under the provided limit first.
+func (limiter *RateLimiter) Take(key string, limit RateLimit, now time.Time) RateStatus {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	perNano := limit.Rate / float64(time.Minute)
+
+	limiter.mtx.Lock()
+	defer limiter.mtx.Unlock()
+
+	if now.Sub(limiter.swept) > rateSweepInterval {
+		limiter.sweep(now)
+	}
+
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		bucket = &TokenBucket{Tokens: burst, Updated: now.UnixNano()}
+		limiter.buckets[key] = bucket
+	}
+
+	elapsed := float64(now.UnixNano() - bucket.Updated)
+	if elapsed > 0 {
+		bucket.Tokens = math.Min(burst, bucket.Tokens+elapsed*perNano)
+		bucket.Updated = now.UnixNano()
+	}
+
+	status := RateStatus{Limit: uint32(burst)}
+	if bucket.Tokens >= 1 {
+		bucket.Tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = time.Duration((1 - bucket.Tokens) / perNano)
+	}
+
+	status.Remaining = uint32(bucket.Tokens)
+	status.Reset = time.Duration((burst - bucket.Tokens) / perNano)
+	bucket.Full = now.Add(status.Reset).UnixNano()
+	return status
+}
+
+// sweep drops the buckets refilled to capacity.
+func (limiter *RateLimiter) sweep(now time.Time) {
+	for key, bucket := range limiter.buckets {
+		if bucket.Full <= now.UnixNano() {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.swept = now
+}
+
+// Buckets returns a copy of the tracked token buckets.
+func (limiter *RateLimiter) Buckets() map[string]TokenBucket {
+	limiter.mtx.Lock()
+	defer limiter.mtx.Unlock()
+
+	buckets := make(map[string]TokenBucket, len(limiter.buckets))
+	for key, bucket := range limiter.buckets {
+		buckets[key] = *bucket
+	}
+	return buckets
+}
+
+// Restore tracks a token bucket associated with the provided key.
+func (limiter *RateLimiter) Restore(key string, bucket TokenBucket) {
+	limiter.mtx.Lock()
+	limiter.buckets[key] = &bucket
+	limiter.mtx.Unlock()
+}
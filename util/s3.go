@@ -2,9 +2,12 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 
+	"einheit/boltkit/trace"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -65,8 +68,23 @@ func (connection *S3Connection) CreateBucket(bucketName string) error {
 	return err
 }
 
+// Ping asserts a bucket is reachable with the credentials of the connection.
+func (connection *S3Connection) Ping(ctx context.Context, bucketName string) error {
+	params := &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	}
+	_, err := connection.AWSInstance.HeadBucketWithContext(ctx, params)
+	return err
+}
+
 // Upload uploads data to an S3 bucket.
-func (connection *S3Connection) Upload(bucketName string, objectPath string, object []byte) (map[string]string, error) {
+func (connection *S3Connection) Upload(ctx context.Context, bucketName string, objectPath string, object []byte) (map[string]string, error) {
+	ctx, span := trace.Start(ctx, "s3.upload", trace.KindClient)
+	defer span.Finish()
+	span.SetAttribute("storage.bucket", bucketName)
+	span.SetAttribute("storage.key", objectPath)
+	span.SetAttribute("storage.size", len(object))
+
 	fileBytes := bytes.NewReader(object)
 	contentType := http.DetectContentType(object)
 	contentLength := int64(len(object))
@@ -78,8 +96,9 @@ func (connection *S3Connection) Upload(bucketName string, objectPath string, obj
 		ContentLength: aws.Int64(contentLength),
 		ContentType:   aws.String(contentType),
 	}
-	_, err := connection.AWSInstance.PutObject(params)
+	_, err := connection.AWSInstance.PutObjectWithContext(ctx, params)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
@@ -0,0 +1,152 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Schemas collects the OpenAPI schema objects of named struct types, keyed
+// by the name they are referenced by.
+type Schemas map[string]interface{}
+
+// SchemaOf describes the json encoding of a value's type as an OpenAPI
+// schema object. Named struct types are collected and referenced, fields are
+// named by their json tags and constrained by their validate tags.
+func (schemas Schemas) SchemaOf(value interface{}) map[string]interface{} {
+	return schemas.schemaOf(reflect.TypeOf(value))
+}
+
+// schemaOf describes the json encoding of a type.
+func (schemas Schemas) schemaOf(valueType reflect.Type) map[string]interface{} {
+	if valueType == nil {
+		return map[string]interface{}{}
+	}
+
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	if valueType == rawMessageType {
+		return map[string]interface{}{}
+	}
+
+	switch valueType.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemas.schemaOf(valueType.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemas.schemaOf(valueType.Elem()),
+		}
+	case reflect.Struct:
+		if valueType.Name() == "" {
+			return schemas.structSchema(valueType)
+		}
+
+		name := schemaName(valueType)
+		if _, ok := schemas[name]; !ok {
+			// Reserve the name ahead of describing the fields so self
+			// referencing types terminate.
+			schemas[name] = nil
+			schemas[name] = schemas.structSchema(valueType)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	return map[string]interface{}{}
+}
+
+// structSchema describes the fields of a struct type as an object schema.
+func (schemas Schemas) structSchema(structType reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for idx := 0; idx < structType.NumField(); idx++ {
+		field := structType.Field(idx)
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+
+		property := schemas.schemaOf(field.Type)
+		if constrain(property, field.Tag.Get("validate")) {
+			required = append(required, name)
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// constrain applies the validate tag rules of a field to its schema, it
+// reports whether the field is required.
+func constrain(schema map[string]interface{}, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		option := ""
+		if pos := strings.Index(rule, "="); pos != -1 {
+			rule, option = rule[:pos], rule[pos+1:]
+		}
+
+		switch rule {
+		case "required":
+			required = true
+		case "nonempty":
+			constrainSize(schema, "min", "1")
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "min", "max":
+			constrainSize(schema, rule, option)
+		case "oneof":
+			schema["enum"] = strings.Fields(option)
+		}
+	}
+
+	return required
+}
+
+// constrainSize applies a min or max rule to a schema, bounding the value of
+// numbers and the length of strings and lists.
+func constrainSize(schema map[string]interface{}, rule string, option string) {
+	bound, err := strconv.ParseFloat(option, 64)
+	if err != nil {
+		return
+	}
+
+	switch schema["type"] {
+	case "string":
+		schema[rule+"Length"] = bound
+	case "array":
+		schema[rule+"Items"] = bound
+	default:
+		schema[rule+"imum"] = bound
+	}
+}
+
+// schemaName returns the component name of a named struct type.
+func schemaName(structType reflect.Type) string {
+	name := []rune(structType.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Response view query parameters.
+const (
+	fieldsParam  = "fields"
+	includeParam = "include"
+
+	// embeddedField holds the related entities embedded in an entity.
+	embeddedField = "embedded"
+)
+
+// View selects the fields of the entities of a response and the related
+// entities embedded in them.
+type View struct {
+	Fields  []string
+	Include []string
+}
+
+// ParseView reads and validates the view of a response from the comma
+// separated fields and include query parameters. Fields are the json names
+// of the fields of entity, included relations one of the provided names.
+func ParseView(values url.Values, entity interface{}, relations []string) (*View, error) {
+	view := &View{Fields: splitParam(values, fieldsParam), Include: splitParam(values, includeParam)}
+	errs := ValidationErrors{}
+
+	entityValue := reflect.Indirect(reflect.ValueOf(entity))
+	for _, field := range view.Fields {
+		if !jsonField(entityValue, field).IsValid() {
+			errs = append(errs, FieldError{fieldsParam, fmt.Sprintf("'%s' is not a field", field)})
+		}
+	}
+
+	for _, relation := range view.Include {
+		if !contains(relations, relation) {
+			errs = append(errs, FieldError{includeParam,
+				fmt.Sprintf("must be one of '%s'", strings.Join(relations, ", "))})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return view, nil
+}
+
+// Empty asserts the view neither trims fields nor embeds relations.
+func (view *View) Empty() bool {
+	return view == nil || (len(view.Fields) == 0 && len(view.Include) == 0)
+}
+
+// Render trims an entity to the fields of the view and embeds the provided
+// related entities, keyed by relation.
+func (view *View) Render(entity interface{}, related map[string]interface{}) (interface{}, error) {
+	if view.Empty() {
+		return entity, nil
+	}
+
+	entityBytes, err := json.Marshal(entity)
+	if err != nil {
+		return nil, ErrInternal
+	}
+
+	rendered := map[string]interface{}{}
+	err = json.Unmarshal(entityBytes, &rendered)
+	if err != nil {
+		return nil, ErrInternal
+	}
+
+	if len(view.Fields) > 0 {
+		for key := range rendered {
+			if !contains(view.Fields, key) {
+				delete(rendered, key)
+			}
+		}
+	}
+
+	if len(view.Include) > 0 {
+		rendered[embeddedField] = related
+	}
+
+	return rendered, nil
+}
+
+// splitParam returns the values of a query parameter, repeated or comma
+// separated.
+func splitParam(values url.Values, param string) []string {
+	split := []string{}
+	for _, value := range values[param] {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" && !contains(split, item) {
+				split = append(split, item)
+			}
+		}
+	}
+	return split
+}